@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// isAdmin reports whether userID is listed in ADMIN_USER_IDS.
+func (gb *GrammarBot) isAdmin(userID int64) bool {
+	return containsID(gb.cfg.AdminIDs, userID)
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// isIgnored reports whether updates from userID in chatID should get no
+// response at all, because the user is blocked or the chat isn't allowed.
+func (gb *GrammarBot) isIgnored(userID, chatID int64) bool {
+	if gb.isAdmin(userID) {
+		return false
+	}
+	if gb.isBlocked(userID) {
+		return true
+	}
+	return len(gb.cfg.AllowedChatIDs) > 0 && !containsID(gb.cfg.AllowedChatIDs, chatID)
+}
+
+// isBlocked reports whether userID is blocked by BLOCKLIST_USER_IDS or /block.
+func (gb *GrammarBot) isBlocked(userID int64) bool {
+	return containsID(gb.cfg.BlockedUserIDs, userID) || gb.store.IsBlocked(userID)
+}
+
+// updateOrigin returns the user and chat an update came from.
+func updateOrigin(update tgbotapi.Update) (userID, chatID int64, ok bool) {
+	switch {
+	case update.Message != nil:
+		return senderID(update.Message), update.Message.Chat.ID, true
+	case update.EditedMessage != nil:
+		return senderID(update.EditedMessage), update.EditedMessage.Chat.ID, true
+	case update.CallbackQuery != nil && update.CallbackQuery.Message != nil:
+		return update.CallbackQuery.From.ID, update.CallbackQuery.Message.Chat.ID, true
+	case update.InlineQuery != nil:
+		// Inline queries have no chat; the allowlist judges them by the
+		// user's private chat
+		return update.InlineQuery.From.ID, update.InlineQuery.From.ID, true
+	case update.ChannelPost != nil:
+		return update.ChannelPost.Chat.ID, update.ChannelPost.Chat.ID, true
+	}
+	return 0, 0, false
+}
+
+// targetUserID returns the user an admin command is about, given by ID in
+// args or by replying to one of their messages.
+func targetUserID(message *tgbotapi.Message, args string) (int64, bool) {
+	if args != "" {
+		id, err := strconv.ParseInt(args, 10, 64)
+		return id, err == nil
+	}
+	if message.ReplyToMessage != nil && message.ReplyToMessage.From != nil {
+		return message.ReplyToMessage.From.ID, true
+	}
+	return 0, false
+}
+
+// handleBlockCommand blocks or unblocks a user given by ID or by replying
+// to one of their messages.
+func (gb *GrammarBot) handleBlockCommand(message *tgbotapi.Message, args string, blocked bool) {
+	userID, ok := targetUserID(message, args)
+	if !ok {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Usage: /%s <user ID>, or reply to one of their messages.", message.Command())))
+		return
+	}
+
+	if blocked && gb.isAdmin(userID) {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Admins can't be blocked."))
+		return
+	}
+
+	if err := gb.store.SetBlocked(userID, blocked); err != nil {
+		log.Printf("Error saving blocklist: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save the blocklist. Please try again later."))
+		return
+	}
+
+	reply := fmt.Sprintf("User %d is blocked.", userID)
+	if !blocked {
+		reply = fmt.Sprintf("User %d is unblocked.", userID)
+		if containsID(gb.cfg.BlockedUserIDs, userID) {
+			reply += " They are still listed in BLOCKLIST_USER_IDS, which takes precedence."
+		}
+	}
+	log.Printf("Admin %d: %s", message.From.ID, reply)
+	gb.send(tgbotapi.NewMessage(message.Chat.ID, reply))
+}
+
+// handleQueueStatusCommand reports queue backpressure to admins.
+func (gb *GrammarBot) handleQueueStatusCommand(message *tgbotapi.Message, args string) {
+	stats := gb.Snapshot()
+	status := fmt.Sprintf(`📊 Queue status
+Queue depth: %d/%d
+Active workers: %d/%d
+In-flight AI calls: %d
+Recent error rate: %.1f%% (last %d calls)
+Latency p50/p90/p99: %s/%s/%s
+Total checks: %d (%d failed, %d blocked by safety filters)
+Recovered panics: %d
+Polling reconnects: %d
+Truncated replies: %d
+Telegram flood waits: %d
+Structured reprompts: %d of %d (%.1f%%)`,
+		len(gb.queue), cap(gb.queue),
+		stats.ActiveWorkers, gb.cfg.Workers,
+		stats.InFlight,
+		stats.RecentErrorRate*100, stats.RecentSamples,
+		stats.Latency.P50.Round(time.Millisecond), stats.Latency.P90.Round(time.Millisecond), stats.Latency.P99.Round(time.Millisecond),
+		stats.Checks, stats.CheckErrors, stats.ErrorsByType[errorSafety],
+		stats.Panics,
+		stats.Reconnects,
+		stats.Truncations,
+		stats.FloodWaits,
+		stats.StructuredReprompts, stats.StructuredCorrections, stats.StructuredRepromptRate*100,
+	)
+
+	split := stats.Models
+	models := make([]string, 0, len(split))
+	for model := range split {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	for _, model := range models {
+		status += fmt.Sprintf("\nModel %s: %d requests", model, split[model])
+	}
+
+	gb.send(tgbotapi.NewMessage(message.Chat.ID, status))
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxAutoDelete is the longest auto-delete delay. Telegram only lets bots
+// delete their messages for 48 hours; half that is a safety margin, as a
+// deletion can be held up by a flood wait.
+const maxAutoDelete = 24 * time.Hour
+
+// deleteScheduler runs delayed deletions. Pending deletions are cancelled
+// by stop, so no timer outlives the bot.
+type deleteScheduler struct {
+	mu      sync.Mutex
+	timers  map[*time.Timer]struct{}
+	stopped bool
+}
+
+// schedule runs fn after delay unless the scheduler is stopped first.
+func (d *deleteScheduler) schedule(delay time.Duration, fn func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stopped {
+		return
+	}
+	if d.timers == nil {
+		d.timers = make(map[*time.Timer]struct{})
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		d.mu.Lock()
+		_, pending := d.timers[timer]
+		delete(d.timers, timer)
+		d.mu.Unlock()
+
+		if pending {
+			fn()
+		}
+	})
+	d.timers[timer] = struct{}{}
+}
+
+// stop cancels every pending deletion and rejects new ones.
+func (d *deleteScheduler) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.stopped = true
+	for timer := range d.timers {
+		timer.Stop()
+	}
+	if n := len(d.timers); n > 0 {
+		log.Printf("Cancelled %d pending auto-deletions", n)
+	}
+	d.timers = nil
+}
+
+// scheduleAutoDelete deletes the bot's message after the chat's auto-delete
+// delay, if one is set.
+func (gb *GrammarBot) scheduleAutoDelete(chatID int64, messageID int) {
+	delay := gb.store.GetChatSettings(chatID).AutoDelete()
+	if delay <= 0 {
+		return
+	}
+
+	gb.deletions.schedule(delay, func() {
+		_, err := gb.request(tgbotapi.NewDeleteMessage(chatID, messageID))
+		if err != nil && !isMessageGone(err) {
+			log.Printf("Error auto-deleting message %d in chat %d: %v", messageID, chatID, err)
+		}
+	})
+}
+
+// isMessageGone reports whether err means the message was already deleted,
+// for example by a user.
+func isMessageGone(err error) bool {
+	return strings.Contains(err.Error(), "message to delete not found")
+}
+
+// canManageChat reports whether the sender of message may change the chat's
+// settings: anyone in a private chat, otherwise chat admins and bot admins.
+func (gb *GrammarBot) canManageChat(message *tgbotapi.Message) bool {
+	if message.Chat.IsPrivate() || gb.isAdmin(senderID(message)) {
+		return true
+	}
+	// Anonymous group admins post on behalf of the group itself
+	if message.SenderChat != nil && message.SenderChat.ID == message.Chat.ID {
+		return true
+	}
+	if message.From == nil {
+		return false
+	}
+
+	var member tgbotapi.ChatMember
+	err := gb.outbound(func() (err error) {
+		member, err = gb.bot.GetChatMember(tgbotapi.GetChatMemberConfig{
+			ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: message.Chat.ID, UserID: message.From.ID},
+		})
+		return err
+	})
+	if err != nil {
+		log.Printf("Error checking chat admin: %v", err)
+		return false
+	}
+	return member.IsCreator() || member.IsAdministrator()
+}
+
+// handleAutoDeleteCommand shows or sets how long corrections in the chat stay
+// visible before the bot deletes them.
+func (gb *GrammarBot) handleAutoDeleteCommand(message *tgbotapi.Message, args string) {
+	chatID := message.Chat.ID
+	settings := gb.store.GetChatSettings(chatID)
+	arg := strings.ToLower(args)
+
+	if arg == "" {
+		reply := "Auto-delete is off. Use /autodelete <seconds> to delete my corrections after a delay."
+		if delay := settings.AutoDelete(); delay > 0 {
+			reply = fmt.Sprintf("My corrections are deleted after %s. Use /autodelete off to keep them.", delay)
+		}
+		gb.send(tgbotapi.NewMessage(chatID, reply))
+		return
+	}
+
+	if !gb.canManageChat(message) {
+		gb.send(tgbotapi.NewMessage(chatID, "Only chat admins can change auto-delete."))
+		return
+	}
+
+	seconds := 0
+	if arg != "off" {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 || time.Duration(n)*time.Second > maxAutoDelete {
+			gb.send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Usage: /autodelete <seconds|off>, with at most %d seconds.", int(maxAutoDelete.Seconds()))))
+			return
+		}
+		seconds = n
+	}
+	if err := gb.store.UpdateChatSettings(chatID, func(stored *ChatSettings) {
+		stored.AutoDeleteSeconds = seconds
+		settings = *stored
+	}); err != nil {
+		log.Printf("Error saving chat settings: %v", err)
+		gb.send(tgbotapi.NewMessage(chatID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	reply := "Auto-delete is off. My corrections will stay in the chat."
+	if seconds > 0 {
+		reply = fmt.Sprintf("My corrections will be deleted after %s.", settings.AutoDelete())
+	}
+	gb.send(tgbotapi.NewMessage(chatID, reply))
+}
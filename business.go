@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// businessConnection is a Telegram Business account the bot is connected
+// to. The Telegram library predates Business, so these types are decoded
+// here.
+type businessConnection struct {
+	ID         string         `json:"id"`
+	User       *tgbotapi.User `json:"user"`
+	UserChatID int64          `json:"user_chat_id"`
+	CanReply   bool           `json:"can_reply"`
+	Rights     *struct {
+		CanReply bool `json:"can_reply"`
+	} `json:"rights"`
+	IsEnabled bool `json:"is_enabled"`
+}
+
+// canReply reports whether the bot may send messages on the account's
+// behalf, under both the old and the current name of the right.
+func (c *businessConnection) canReply() bool {
+	return c.IsEnabled && (c.CanReply || c.Rights != nil && c.Rights.CanReply)
+}
+
+// businessMessage is a message received in a connected account's chat.
+type businessMessage struct {
+	message      *tgbotapi.Message
+	connectionID string
+}
+
+// businessConnections caches the connections the bot learned about.
+type businessConnections struct {
+	mu          sync.Mutex
+	connections map[string]*businessConnection
+}
+
+func (b *businessConnections) set(connection *businessConnection) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.connections == nil {
+		b.connections = make(map[string]*businessConnection)
+	}
+	b.connections[connection.ID] = connection
+}
+
+func (b *businessConnections) get(id string) (*businessConnection, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	connection, ok := b.connections[id]
+	return connection, ok
+}
+
+// businessConnection returns a connection, asking Telegram about ones made
+// before the bot started.
+func (gb *GrammarBot) businessConnection(id string) (*businessConnection, error) {
+	if connection, ok := gb.business.get(id); ok {
+		return connection, nil
+	}
+
+	params := tgbotapi.Params{}
+	params["business_connection_id"] = id
+	resp, err := gb.makeRequest("getBusinessConnection", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get business connection: %w", err)
+	}
+	var connection businessConnection
+	if err := json.Unmarshal(resp.Result, &connection); err != nil {
+		return nil, fmt.Errorf("failed to decode business connection: %w", err)
+	}
+	gb.business.set(&connection)
+	return &connection, nil
+}
+
+// handleBusinessMessage corrects a message a customer sent to a connected
+// Business account, replying on the account's behalf. The account owner's
+// own messages are left alone, and the owner's settings (language, style and
+// so on) apply. Nothing is sent when the message has no mistakes or the
+// check fails, so customers only ever see corrections.
+func (gb *GrammarBot) handleBusinessMessage(business businessMessage) {
+	message := business.message
+	if message.Text == "" || strings.HasPrefix(message.Text, "/") || countWords(message.Text) < gb.cfg.MinWords {
+		return
+	}
+
+	connection, err := gb.businessConnection(business.connectionID)
+	if err != nil {
+		log.Printf("Error handling business message: %v", err)
+		return
+	}
+	if !connection.canReply() || connection.User == nil || senderID(message) == connection.User.ID {
+		return
+	}
+	// handleUpdate judged the sender; the owner is only known from the
+	// connection
+	ownerID := connection.User.ID
+	if gb.isIgnored(ownerID, message.Chat.ID) {
+		return
+	}
+
+	opts := gb.optionsForUser(ownerID, connection.User)
+	correctedText, err := gb.checkGrammar(message.Text, opts)
+	if err != nil {
+		gb.logError("Error checking grammar: %v", err)
+		return
+	}
+	if !hasCorrections(message.Text, correctedText) {
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, renderCorrection(correctedText, opts, gb.userStyle(ownerID), gb.cfg.MaxHighlights))
+	msg.ReplyToMessageID = message.MessageID
+	msg.ParseMode = "MarkdownV2"
+	if err := gb.sendBusinessMessage(business.connectionID, msg); err != nil {
+		log.Printf("Error sending business reply: %v", err)
+	}
+}
+
+// sendBusinessMessage sends msg on behalf of a Business account. Only its
+// text, parse mode and reply target are used. The text is prepared like
+// that of send, but too long text is truncated rather than split.
+func (gb *GrammarBot) sendBusinessMessage(connectionID string, msg tgbotapi.MessageConfig) error {
+	msg.Text, msg.ParseMode = gb.outgoingText(msg.Text, msg.ParseMode)
+	msg.Text, msg.ParseMode = gb.truncateOversized(msg.Text, msg.ParseMode)
+
+	params := tgbotapi.Params{}
+	params["business_connection_id"] = connectionID
+	params.AddNonZero64("chat_id", msg.ChatID)
+	params["text"] = msg.Text
+	params.AddNonEmpty("parse_mode", msg.ParseMode)
+	if msg.ReplyToMessageID != 0 {
+		reply := map[string]any{"message_id": msg.ReplyToMessageID, "allow_sending_without_reply": true}
+		if err := params.AddInterface("reply_parameters", reply); err != nil {
+			return fmt.Errorf("failed to encode reply: %w", err)
+		}
+	}
+
+	if _, err := gb.makeRequest("sendMessage", params); err != nil {
+		return fmt.Errorf("failed to send business message: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// cacheKey identifies a correction: the same text checked with the same
+// options and model gets the same answer.
+type cacheKey struct {
+	text string
+	opts CorrectOptions
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	value   string
+	expires time.Time
+}
+
+// correctionCache is a size-bounded LRU cache of corrections whose entries
+// expire after a TTL. A zero capacity disables it.
+type correctionCache struct {
+	capacity int
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element
+	lru     *list.List
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+func newCorrectionCache(capacity int, ttl time.Duration) *correctionCache {
+	return &correctionCache{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[cacheKey]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// get returns the cached correction for key and records a hit or miss.
+func (c *correctionCache) get(key cacheKey) (string, bool) {
+	value, ok := c.peek(key)
+	if c.capacity > 0 {
+		if ok {
+			c.hits.Add(1)
+		} else {
+			c.misses.Add(1)
+		}
+	}
+	return value, ok
+}
+
+// peek is get without recording statistics.
+func (c *correctionCache) peek(key cacheKey) (string, bool) {
+	if c.capacity == 0 {
+		return "", false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return "", false
+	}
+	c.lru.MoveToFront(el)
+	return entry.value, true
+}
+
+// put stores the correction for key, evicting the least recently used entry
+// when the cache is full.
+func (c *correctionCache) put(key cacheKey, value string) {
+	if c.capacity == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*cacheEntry)
+		entry.value, entry.expires = value, time.Now().Add(c.ttl)
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, value: value, expires: time.Now().Add(c.ttl)})
+	if c.lru.Len() > c.capacity {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// size returns how many corrections are cached, including expired ones not
+// yet evicted.
+func (c *correctionCache) size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// correctionCategories are the kinds of mistake corrections can be limited
+// to with /categories, in the order they are listed. They are a report's
+// issue categories, with word choice counted as style.
+var correctionCategories = reportCategories
+
+// categoriesPrompt limits corrections to some categories. %s lists them.
+const categoriesPrompt = `
+
+Correct only mistakes of these kinds: %s. Style includes word choice. Leave every other mistake exactly as written.`
+
+// structuredCategoryPrompt asks structured corrections to name each edit's
+// category, so edits of other categories can be dropped.
+const structuredCategoryPrompt = ` Give each edit a "category" field: one of grammar, spelling, punctuation or style.`
+
+// parseCategories parses a list of categories separated by commas or spaces
+// into correctionCategories order. Choosing every category, or "all",
+// returns nil, meaning corrections aren't limited.
+func parseCategories(arg string) ([]string, error) {
+	chosen := make(map[string]bool)
+	for _, name := range strings.FieldsFunc(strings.ToLower(arg), func(r rune) bool { return r == ',' || r == ' ' }) {
+		if name == "all" {
+			return nil, nil
+		}
+		if !containsString(correctionCategories, name) {
+			return nil, fmt.Errorf("unknown category %q", name)
+		}
+		chosen[name] = true
+	}
+
+	var categories []string
+	for _, category := range correctionCategories {
+		if chosen[category] {
+			categories = append(categories, category)
+		}
+	}
+	if len(categories) == len(correctionCategories) {
+		return nil, nil
+	}
+	return categories, nil
+}
+
+// categoriesInstructions returns the prompt limiting corrections to the
+// categories of opts, if any.
+func categoriesInstructions(opts CorrectOptions) string {
+	if opts.Categories == "" {
+		return ""
+	}
+	return fmt.Sprintf(categoriesPrompt, strings.ReplaceAll(opts.Categories, ",", ", "))
+}
+
+// allowsCategory reports whether an edit of category may be shown with
+// opts. Edits the model didn't put in a known category are dropped when
+// corrections are limited.
+func allowsCategory(opts CorrectOptions, category string) bool {
+	if opts.Categories == "" {
+		return true
+	}
+	category = strings.ToLower(strings.TrimSpace(category))
+	return containsString(strings.Split(opts.Categories, ","), category)
+}
+
+// handleCategoriesCommand shows or sets the kinds of mistake the user's
+// corrections are limited to: "/categories punctuation,spelling", or
+// "/categories all" to correct everything again.
+func (gb *GrammarBot) handleCategoriesCommand(message *tgbotapi.Message, arg string) {
+	userID := senderID(message)
+	settings := gb.store.GetUserSettings(userID)
+
+	if arg == "" {
+		current := "all categories"
+		if len(settings.Categories) > 0 {
+			current = strings.Join(settings.Categories, ", ")
+		}
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("I correct %s. Use /categories with a list of %s to correct only those, or /categories all.",
+			current, strings.Join(correctionCategories, ", "))))
+		return
+	}
+
+	categories, err := parseCategories(arg)
+	if err != nil {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("%s. Choose from %s, or all.", capitalize(err.Error()), strings.Join(correctionCategories, ", "))))
+		return
+	}
+
+	if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) { stored.Categories = categories }); err != nil {
+		log.Printf("Error saving settings: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	reply := "I'll correct mistakes of every category again."
+	if len(categories) > 0 {
+		reply = fmt.Sprintf("I'll correct only these mistakes and leave the rest as written: %s.", strings.Join(categories, ", "))
+	}
+	gb.replyWithSettings(message, reply)
+}
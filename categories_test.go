@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCategories(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    []string
+		wantErr bool
+	}{
+		{"punctuation,spelling", []string{"spelling", "punctuation"}, false},
+		{"Punctuation  style", []string{"punctuation", "style"}, false},
+		{"grammar, grammar", []string{"grammar"}, false},
+		{"all", nil, false},
+		{"grammar,spelling,punctuation,style", nil, false},
+		{"grammar,vibes", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseCategories(tt.arg)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseCategories(%q) = %q, %v, want %q, error %v", tt.arg, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// TestStructuredEditsFilteredByCategory checks that edits of categories the
+// user didn't choose are left out of the correction.
+func TestStructuredEditsFilteredByCategory(t *testing.T) {
+	const raw = `{"edits": [
+		{"original": "go", "corrected": "goes", "category": "grammar"},
+		{"original": "hom", "corrected": "home", "category": "spelling"},
+		{"original": "today", "corrected": "today,", "category": "Punctuation"}
+	]}`
+	tests := map[string]string{
+		"":                     "She ~go~ **goes** ~hom~ **home** ~today~ **today,** ok\\.",
+		"punctuation":          "She go hom ~today~ **today,** ok\\.",
+		"spelling,punctuation": "She go ~hom~ **home** ~today~ **today,** ok\\.",
+		"style":                "She go hom today ok\\.",
+	}
+	for categories, want := range tests {
+		got, err := parseStructured("She go hom today ok.", raw, CorrectOptions{Categories: categories})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("categories %q: got %q, want %q", categories, got, want)
+		}
+	}
+}
+
+// TestCategoriesInPrompt checks that the model is told which categories to
+// correct, and asked to name them in structured corrections.
+func TestCategoriesInPrompt(t *testing.T) {
+	opts := CorrectOptions{Language: defaultLanguage, Categories: "spelling,punctuation"}
+
+	engine := recordPrompts(nil)
+	if _, err := correctWith(context.Background(), engine, "She go home.", opts); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(engine.prompt.Instructions, "Correct only mistakes of these kinds: spelling, punctuation.") {
+		t.Error("text prompt doesn't limit the categories")
+	}
+	if len(engine.prompt.Examples) != 0 {
+		t.Error("examples correcting every category were sent with limited categories")
+	}
+
+	if instructions := structuredInstructions(opts); !strings.Contains(instructions, `"category" field`) {
+		t.Error("structured prompt doesn't ask for each edit's category")
+	}
+}
+
+func TestCategoriesCommand(t *testing.T) {
+	gb, tg := newTestBot(t, testConfig(t, nil), nil)
+
+	gb.handleCategoriesCommand(command(7, "/categories spelling punctuation"), "spelling punctuation")
+	if got := gb.store.GetUserSettings(7).Categories; !reflect.DeepEqual(got, []string{"spelling", "punctuation"}) {
+		t.Errorf("saved categories %q, want spelling and punctuation", got)
+	}
+
+	gb.handleCategoriesCommand(command(7, "/categories vibes"), "vibes")
+	if got := gb.store.GetUserSettings(7).Categories; len(got) != 2 {
+		t.Errorf("an unknown category changed the saved categories to %q", got)
+	}
+	if replies := tg.callsTo("sendMessage"); !strings.Contains(replies[len(replies)-1].Get("text"), "Choose from") {
+		t.Errorf("reply to an unknown category = %q", replies[len(replies)-1].Get("text"))
+	}
+
+	gb.handleCategoriesCommand(command(7, "/categories all"), "all")
+	if got := gb.store.GetUserSettings(7).Categories; got != nil {
+		t.Errorf("saved categories %q after /categories all, want none", got)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	_ "embed"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+//go:embed CHANGELOG.md
+var changelogMarkdown string
+
+// whatsNewEntries is how many releases /whatsnew shows.
+const whatsNewEntries = 3
+
+// changelogEntry is one release in CHANGELOG.md.
+type changelogEntry struct {
+	Version string
+	Items   []string
+}
+
+// changelog holds the parsed releases, newest first.
+var changelog = parseChangelog(changelogMarkdown)
+
+// parseChangelog reads "## <version>" headings followed by "- " items.
+func parseChangelog(markdown string) []changelogEntry {
+	var entries []changelogEntry
+	for _, line := range strings.Split(markdown, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "## "):
+			entries = append(entries, changelogEntry{Version: strings.TrimPrefix(line, "## ")})
+		case strings.HasPrefix(line, "- ") && len(entries) > 0:
+			last := &entries[len(entries)-1]
+			last.Items = append(last.Items, strings.TrimPrefix(line, "- "))
+		}
+	}
+	return entries
+}
+
+// currentVersion is the newest release in the changelog.
+func currentVersion() string {
+	if len(changelog) == 0 {
+		return ""
+	}
+	return changelog[0].Version
+}
+
+// renderChangelog formats the latest n releases as MarkdownV2.
+func renderChangelog(n int) string {
+	var b strings.Builder
+	b.WriteString(escapeMarkdownV2("🆕 What's new:"))
+	for i, entry := range changelog {
+		if i == n {
+			break
+		}
+		b.WriteString("\n\n*" + escapeMarkdownV2(entry.Version) + "*")
+		for _, item := range entry.Items {
+			b.WriteString("\n" + escapeMarkdownV2("• "+item))
+		}
+	}
+	return b.String()
+}
+
+// handleWhatsNewCommand shows recent releases, or toggles update notices
+// with "/whatsnew notify on|off".
+func (gb *GrammarBot) handleWhatsNewCommand(message *tgbotapi.Message, args string) {
+	words := strings.Fields(strings.ToLower(args))
+	if len(words) > 0 && words[0] == "notify" {
+		gb.setUpdateNotices(message, words[1:])
+		return
+	}
+
+	gb.sendChangelog(message.Chat.ID)
+
+	userID := senderID(message)
+	settings := gb.store.GetUserSettings(userID)
+	if settings.LastSeenVersion != currentVersion() {
+		settings.LastSeenVersion = currentVersion()
+		if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) { stored.LastSeenVersion = currentVersion() }); err != nil {
+			log.Printf("Error saving settings: %v", err)
+		}
+	}
+}
+
+func (gb *GrammarBot) setUpdateNotices(message *tgbotapi.Message, args []string) {
+	if len(args) != 1 || (args[0] != "on" && args[0] != "off") {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /whatsnew notify on|off"))
+		return
+	}
+
+	userID := senderID(message)
+	notify := args[0] == "on"
+	if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) {
+		stored.NotifyUpdates = notify
+		stored.LastSeenVersion = currentVersion()
+	}); err != nil {
+		log.Printf("Error saving settings: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	reply := "I won't tell you about new versions. Use /whatsnew any time to check."
+	if notify {
+		reply = "I'll tell you about new features the first time you message me after an update."
+	}
+	gb.send(tgbotapi.NewMessage(message.Chat.ID, reply))
+}
+
+// notifyUpdates sends the changelog to opted-in users on their first private
+// interaction after a new version.
+func (gb *GrammarBot) notifyUpdates(message *tgbotapi.Message) {
+	if message.From == nil || !message.Chat.IsPrivate() || message.Command() == "whatsnew" {
+		return
+	}
+
+	settings := gb.store.GetUserSettings(message.From.ID)
+	if !settings.NotifyUpdates || settings.LastSeenVersion == currentVersion() {
+		return
+	}
+
+	if err := gb.store.UpdateUserSettings(message.From.ID, func(stored *UserSettings) { stored.LastSeenVersion = currentVersion() }); err != nil {
+		log.Printf("Error saving settings: %v", err)
+		return
+	}
+	gb.sendChangelog(message.Chat.ID)
+}
+
+func (gb *GrammarBot) sendChangelog(chatID int64) {
+	msg := tgbotapi.NewMessage(chatID, renderChangelog(whatsNewEntries))
+	msg.ParseMode = "MarkdownV2"
+	if _, err := gb.send(msg); err != nil {
+		log.Printf("Error sending changelog: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"log"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// linkedChats caches the discussion group linked to each channel.
+type linkedChats struct {
+	mu    sync.Mutex
+	chats map[int64]int64
+}
+
+// size returns how many channels have a cached discussion group.
+func (l *linkedChats) size() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.chats)
+}
+
+// handleChannelPost checks that a channel post can be corrected. The
+// correction itself is posted when the post is automatically forwarded into
+// the channel's linked discussion group, where replies appear as comments.
+func (gb *GrammarBot) handleChannelPost(post *tgbotapi.Message) {
+	if !gb.cfg.ChannelCorrections || messageText(post) == "" {
+		return
+	}
+
+	if linked := gb.linkedChatID(post.Chat.ID); linked == 0 {
+		log.Printf("Channel %d has no linked discussion group, so its posts can't be corrected", post.Chat.ID)
+	}
+}
+
+// handleAutomaticForward corrects a channel post forwarded into its linked
+// discussion group by replying to it there.
+func (gb *GrammarBot) handleAutomaticForward(message *tgbotapi.Message) {
+	if !gb.cfg.ChannelCorrections || message.ForwardFromChat == nil {
+		return
+	}
+
+	// Only comment on posts coming from the channel this group belongs to
+	if gb.linkedChatID(message.ForwardFromChat.ID) != message.Chat.ID {
+		return
+	}
+
+	if text := messageText(message); text != "" {
+		gb.checkAndReply(message, text)
+	}
+}
+
+// linkedChatID returns the discussion group linked to channelID, or 0.
+func (gb *GrammarBot) linkedChatID(channelID int64) int64 {
+	gb.linked.mu.Lock()
+	defer gb.linked.mu.Unlock()
+
+	if id, ok := gb.linked.chats[channelID]; ok {
+		return id
+	}
+
+	var chat tgbotapi.Chat
+	err := gb.outbound(func() (err error) {
+		chat, err = gb.bot.GetChat(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: channelID}})
+		return err
+	})
+	if err != nil {
+		log.Printf("Error resolving linked chat of %d: %v", channelID, err)
+		return 0
+	}
+
+	if gb.linked.chats == nil {
+		gb.linked.chats = make(map[int64]int64)
+	}
+	gb.linked.chats[channelID] = chat.LinkedChatID
+	return chat.LinkedChatID
+}
+
+// messageText returns the text of a message, or its caption for media posts.
+func messageText(message *tgbotapi.Message) string {
+	if message.Text != "" {
+		return message.Text
+	}
+	return message.Caption
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Chat cooldown modes, selected with CHAT_COOLDOWN_MODE.
+const (
+	// cooldownDrop skips messages that arrive while the chat cools down.
+	cooldownDrop = "drop"
+	// cooldownQueue checks them once the cooldown allows, one per cooldown.
+	cooldownQueue = "queue"
+)
+
+// maxQueuedPerChat bounds how many checks wait for a chat's cooldown in
+// queue mode; further messages are skipped.
+const maxQueuedPerChat = 5
+
+type chatCooldown struct {
+	next   time.Time
+	queued int
+}
+
+// chatCooldowns spaces out the automatic checks of each group chat, so the
+// bot doesn't dominate a busy conversation.
+type chatCooldowns struct {
+	mu    sync.Mutex
+	chats map[int64]*chatCooldown
+}
+
+// reserve takes the next check slot of chatID and returns how long the
+// check has to wait for it. It returns false, taking no slot, when the
+// check would have to wait but maxQueued checks already do.
+func (c *chatCooldowns) reserve(chatID int64, now time.Time, cooldown time.Duration, maxQueued int) (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.chats == nil {
+		c.chats = make(map[int64]*chatCooldown)
+	}
+	chat, ok := c.chats[chatID]
+	if !ok {
+		chat = &chatCooldown{}
+		c.chats[chatID] = chat
+	}
+
+	if !now.Before(chat.next) {
+		chat.next = now.Add(cooldown)
+		c.dropIdleLocked(now)
+		return 0, true
+	}
+	if chat.queued >= maxQueued {
+		return 0, false
+	}
+	wait := chat.next.Sub(now)
+	chat.next = chat.next.Add(cooldown)
+	chat.queued++
+	return wait, true
+}
+
+// dequeue records that a queued check of chatID is running.
+func (c *chatCooldowns) dequeue(chatID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if chat, ok := c.chats[chatID]; ok && chat.queued > 0 {
+		chat.queued--
+	}
+}
+
+// dropIdleLocked forgets chats whose cooldown is over, so the map doesn't
+// grow with every chat ever seen.
+func (c *chatCooldowns) dropIdleLocked(now time.Time) {
+	if len(c.chats) <= 1000 {
+		return
+	}
+	for chatID, chat := range c.chats {
+		if chat.queued == 0 && !now.Before(chat.next) {
+			delete(c.chats, chatID)
+		}
+	}
+}
+
+// autoCheck checks text of a message nobody asked to check explicitly. In
+// groups, checks are spaced cfg.ChatCooldown apart: messages arriving
+// sooner are skipped or, in queue mode, checked later.
+func (gb *GrammarBot) autoCheck(message *tgbotapi.Message, text string) {
+	if message.Chat.IsPrivate() || gb.cfg.ChatCooldown == 0 {
+		gb.checkAndReply(message, text)
+		return
+	}
+
+	maxQueued := 0
+	if gb.cfg.ChatCooldownMode == cooldownQueue {
+		maxQueued = maxQueuedPerChat
+	}
+	chatID := message.Chat.ID
+	wait, ok := gb.chatCooldowns.reserve(chatID, time.Now(), gb.cfg.ChatCooldown, maxQueued)
+	switch {
+	case !ok:
+		return
+	case wait == 0:
+		gb.checkAndReply(message, text)
+		return
+	}
+
+	// Queued checks run outside the worker pool, so a restart forgets them
+	time.AfterFunc(wait, func() {
+		defer func() {
+			if r := recover(); r != nil {
+				gb.metrics.panics.Add(1)
+				log.Printf("Recovered panic checking queued message %d in chat %d: %v\n%s", message.MessageID, chatID, r, debug.Stack())
+			}
+		}()
+		gb.chatCooldowns.dequeue(chatID)
+		if gb.ctx.Err() != nil {
+			return
+		}
+		gb.checkAndReply(message, text)
+	})
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// chatLanguage returns the language a group's corrections are locked to, or
+// "" when members' own languages apply. Private chats are never locked.
+func (gb *GrammarBot) chatLanguage(chat *tgbotapi.Chat) string {
+	if chat == nil || chat.IsPrivate() {
+		return ""
+	}
+	return gb.store.GetChatSettings(chat.ID).Language
+}
+
+// handleChatLanguageCommand shows or sets the group's language lock. Only
+// chat admins may change it.
+func (gb *GrammarBot) handleChatLanguageCommand(message *tgbotapi.Message, arg string) {
+	chatID := message.Chat.ID
+	if message.Chat.IsPrivate() {
+		gb.send(tgbotapi.NewMessage(chatID, "/chatlanguage works in groups. Here, use /language to choose your language."))
+		return
+	}
+
+	settings := gb.store.GetChatSettings(chatID)
+	if arg == "" {
+		reply := "Everyone here is corrected in their own language. Use /chatlanguage <name>, for example /chatlanguage German, to correct everyone in one language."
+		if settings.Language != "" {
+			reply = fmt.Sprintf("Everyone here is corrected in %s. Use /chatlanguage off to let members choose again.", settings.Language)
+		}
+		gb.send(tgbotapi.NewMessage(chatID, reply))
+		return
+	}
+
+	if !gb.canManageChat(message) {
+		gb.send(tgbotapi.NewMessage(chatID, "Only chat admins can change the chat language."))
+		return
+	}
+
+	language := ""
+	if !strings.EqualFold(arg, "off") {
+		var ok bool
+		language, ok = normalizeLanguage(arg)
+		if !ok {
+			gb.send(tgbotapi.NewMessage(chatID, "Please give a language name like German or Brazilian Portuguese, or off."))
+			return
+		}
+	}
+	if err := gb.store.UpdateChatSettings(chatID, func(stored *ChatSettings) { stored.Language = language }); err != nil {
+		log.Printf("Error saving chat settings: %v", err)
+		gb.send(tgbotapi.NewMessage(chatID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	reply := "The chat language is off. Members are corrected in their own language again."
+	if language != "" {
+		reply = fmt.Sprintf("Got it, I'll correct everyone in this chat in %s.", language)
+	}
+	gb.send(tgbotapi.NewMessage(chatID, reply))
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// splitMessage splits text into chunks of at most limit UTF-16 code units,
+// the unit Telegram measures messages in. MarkdownV2 text is only cut
+// between entities and escapes, preferably at a line break or a space. With
+// markers, each chunk starts with a "(part k/n)" line that counts towards
+// the limit.
+func splitMessage(text string, limit int, markdown, markers bool) []string {
+	if utf16Len(text) <= limit {
+		return []string{text}
+	}
+	if !markers {
+		return splitAtSafePoints(text, limit, markdown)
+	}
+
+	// The marker's length depends on the number of chunks, so split again
+	// until the count is stable
+	total := 2
+	for {
+		chunks := splitAtSafePoints(text, limit-utf16Len(chunkMarker(total, total, markdown)), markdown)
+		if len(chunks) <= total || len(fmt.Sprint(len(chunks))) == len(fmt.Sprint(total)) {
+			for i := range chunks {
+				chunks[i] = chunkMarker(i+1, len(chunks), markdown) + chunks[i]
+			}
+			return chunks
+		}
+		total = len(chunks)
+	}
+}
+
+// chunkMarker is the prefix of chunk k of n.
+func chunkMarker(k, n int, markdown bool) string {
+	marker := fmt.Sprintf("(part %d/%d)\n", k, n)
+	if markdown {
+		return escapeMarkdownV2(marker)
+	}
+	return marker
+}
+
+// splitAtSafePoints cuts text greedily into chunks of at most limit UTF-16
+// code units.
+func splitAtSafePoints(text string, limit int, markdown bool) []string {
+	unsafe := map[int]bool{}
+	if markdown {
+		unsafe = unsafeCuts(text)
+	}
+
+	var chunks []string
+	start := 0
+	for utf16Len(text[start:]) > limit {
+		rest := text[start:]
+		cut, lastSafe, lastSpace, lastLine, end := 0, 0, 0, 0, 0
+		units := 0
+		for i, r := range rest {
+			end = i
+			if i > 0 && !unsafe[start+i] {
+				lastSafe = i
+				switch rest[i-1] {
+				case ' ':
+					lastSpace = i
+				case '\n':
+					lastLine = i
+				}
+			}
+			units += utf16RuneLen(r)
+			if units > limit {
+				break
+			}
+		}
+		// Prefer a line break unless it would leave a chunk mostly empty
+		switch {
+		case lastLine > end/2:
+			cut = lastLine
+		case lastSpace > 0:
+			cut = lastSpace
+		case lastSafe > 0:
+			cut = lastSafe
+		default:
+			// A single entity is longer than the limit; cut it anyway and
+			// let send fall back to plain text for the broken chunks
+			cut = limitOffset(rest, limit)
+		}
+
+		chunks = append(chunks, strings.TrimRight(rest[:cut], " \n"))
+		start += cut
+		for start < len(text) && (text[start] == ' ' || text[start] == '\n') {
+			start++
+		}
+	}
+	if start < len(text) {
+		chunks = append(chunks, text[start:])
+	}
+	return chunks
+}
+
+// unsafeCuts returns the byte offsets of MarkdownV2 text where a cut would
+// break an entity or an escape. Invalid MarkdownV2 has no entities to keep
+// together, since it is sent as plain text anyway.
+func unsafeCuts(text string) map[int]bool {
+	unsafe := map[int]bool{}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' {
+			unsafe[i+1] = true
+			i++
+		}
+	}
+	spans, err := parseMarkdownV2(text)
+	if err != nil {
+		return unsafe
+	}
+	for _, s := range spans {
+		for i := s.Start + 1; i < s.End; i++ {
+			unsafe[i] = true
+		}
+	}
+	return unsafe
+}
+
+// limitOffset returns the byte offset of the longest prefix of text within
+// limit UTF-16 code units.
+func limitOffset(text string, limit int) int {
+	units := 0
+	for i, r := range text {
+		units += utf16RuneLen(r)
+		if units > limit {
+			return i
+		}
+	}
+	return len(text)
+}
+
+// utf16Len returns the length of text in UTF-16 code units.
+func utf16Len(text string) int {
+	n := 0
+	for _, r := range text {
+		n += utf16RuneLen(r)
+	}
+	return n
+}
+
+func utf16RuneLen(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
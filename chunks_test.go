@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSplitMessageMarkers(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		limit    int
+		markdown bool
+	}{
+		{"plain", strings.Repeat("She goes home every day. ", 400), maxMessageLength, false},
+		{"markdown", strings.Repeat("She ~go~ *goes* home\\. ", 400), maxMessageLength, true},
+		{"more than nine parts", strings.Repeat("They were late again. ", 40), 60, false},
+		{"wide characters", strings.Repeat("Sie geht heute nach Hause 😀. ", 300), maxMessageLength, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := splitMessage(tt.text, tt.limit, tt.markdown, true)
+			if len(chunks) < 2 {
+				t.Fatalf("got %d chunks, want the text split", len(chunks))
+			}
+
+			var rejoined strings.Builder
+			for i, chunk := range chunks {
+				if n := utf16Len(chunk); n > tt.limit {
+					t.Errorf("chunk %d is %d UTF-16 units with its marker, over the limit of %d", i+1, n, tt.limit)
+				}
+				marker := chunkMarker(i+1, len(chunks), tt.markdown)
+				body, ok := strings.CutPrefix(chunk, marker)
+				if !ok {
+					t.Fatalf("chunk %d doesn't start with %q: %q", i+1, marker, chunk[:min(len(chunk), 20)])
+				}
+				// The space a chunk was cut at may be dropped
+				rejoined.WriteString(body + " ")
+			}
+			if got, want := normalizeSpace(rejoined.String()), normalizeSpace(tt.text); got != want {
+				t.Error("chunks without their markers don't add up to the text")
+			}
+		})
+	}
+}
+
+func TestSplitMessageWithoutMarkers(t *testing.T) {
+	text := strings.Repeat("She goes home every day. ", 400)
+	for i, chunk := range splitMessage(text, maxMessageLength, false, false) {
+		if strings.HasPrefix(chunk, "(part ") {
+			t.Errorf("chunk %d has a marker with markers off", i+1)
+		}
+	}
+	if chunks := splitMessage("She goes home.", maxMessageLength, false, true); len(chunks) != 1 || chunks[0] != "She goes home." {
+		t.Errorf("short text split into %q, want it unchanged and unmarked", chunks)
+	}
+}
+
+func TestChunkMarker(t *testing.T) {
+	if got := chunkMarker(2, 3, false); got != "(part 2/3)\n" {
+		t.Errorf("plain marker = %q", got)
+	}
+	if got, want := chunkMarker(10, 12, true), fmt.Sprintf("\\(part %d/%d\\)\n", 10, 12); got != want {
+		t.Errorf("MarkdownV2 marker = %q, want %q", got, want)
+	}
+}
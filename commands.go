@@ -0,0 +1,339 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Command describes a bot command and how to handle it.
+type Command struct {
+	Name string
+	// Usage describes the arguments, e.g. "<text>".
+	Usage       string
+	Description string
+	// MenuDescription is the short text for Telegram's command menu, with
+	// Translations keyed by IETF language code. Commands without one are
+	// left out of the menu.
+	MenuDescription string
+	Translations    map[string]string
+	// AdminOnly commands are hidden from everyone not in ADMIN_USER_IDS.
+	AdminOnly bool
+	// Feature names the optional feature the command belongs to. While it
+	// is off the command behaves as if it didn't exist.
+	Feature string
+	Handler func(message *tgbotapi.Message, args string)
+}
+
+// commandRegistry maps command names to their definitions, keeping the
+// order they were registered in for /help and the command menu.
+type commandRegistry struct {
+	ordered []*Command
+	byName  map[string]*Command
+}
+
+func (r *commandRegistry) register(c Command) {
+	if r.byName == nil {
+		r.byName = make(map[string]*Command)
+	}
+	if _, exists := r.byName[c.Name]; exists {
+		panic(fmt.Sprintf("command /%s registered twice", c.Name))
+	}
+	r.ordered = append(r.ordered, &c)
+	r.byName[c.Name] = &c
+}
+
+func (r *commandRegistry) lookup(name string) (*Command, bool) {
+	c, ok := r.byName[name]
+	return c, ok
+}
+
+// registerCommandHandlers fills the command registry.
+func (gb *GrammarBot) registerCommandHandlers() {
+	r := &gb.commands
+
+	r.register(Command{Name: "start", Description: "Show the welcome message", Handler: gb.handleStartCommand})
+	r.register(Command{Name: "help", Description: "Show help information", Handler: gb.handleHelpCommand,
+		MenuDescription: "How to use the bot", Translations: map[string]string{
+			"de": "So benutzt du den Bot",
+			"es": "Cómo usar el bot",
+			"ru": "Как пользоваться ботом",
+		}})
+	r.register(Command{Name: "check", Usage: "<text>", Description: "Check text of any length (or reply to a message or photo with /check)", Handler: gb.handleCheckCommand,
+		MenuDescription: "Check the grammar of a text", Translations: map[string]string{
+			"de": "Grammatik eines Textes prüfen",
+			"es": "Revisar la gramática de un texto",
+			"ru": "Проверить грамматику текста",
+		}})
+	r.register(Command{Name: "summary", Usage: "<text>", Description: "Check a text and summarize it in one line (or reply to a message with /summary)", Handler: gb.handleSummaryCommand,
+		MenuDescription: "Check and summarize a text", Translations: map[string]string{
+			"de": "Text prüfen und zusammenfassen",
+			"es": "Revisar y resumir un texto",
+			"ru": "Проверить и кратко пересказать текст",
+		}})
+	r.register(Command{Name: "flag", Usage: "[on|off]", Description: "Toggle flag mode: mark mistakes without correcting them", Handler: gb.handleFlagCommand,
+		MenuDescription: "Mark mistakes without fixing them", Translations: map[string]string{
+			"de": "Fehler markieren, ohne sie zu korrigieren",
+			"es": "Marcar errores sin corregirlos",
+			"ru": "Отмечать ошибки без исправления",
+		}})
+	r.register(Command{Name: "explain", Usage: "[on|off]", Description: "Toggle short explanations of each correction", Handler: gb.handleExplainCommand,
+		MenuDescription: "Explain each correction", Translations: map[string]string{
+			"de": "Jede Korrektur erklären",
+			"es": "Explicar cada corrección",
+			"ru": "Объяснять исправления",
+		}})
+	r.register(Command{Name: "explainlang", Usage: "<language|native|same>", Description: "Set the language explanations are written in", Handler: gb.handleExplainLangCommand})
+	r.register(Command{Name: "mixed", Usage: "[on|off]", Description: "Toggle correcting each language of a mixed-language message on its own", Handler: gb.handleMixedCommand})
+	r.register(Command{Name: "report", Usage: "[on|off]", Description: "Toggle formal writing reports for /check", Feature: "reports", Handler: gb.handleReportCommand})
+	r.register(Command{Name: "practice", Usage: "[stop]", Description: "Get a short exercise: find and fix the mistake", Feature: "practice", Handler: gb.handlePracticeCommand,
+		MenuDescription: "Practice with an exercise", Translations: map[string]string{
+			"de": "Mit einer Übung trainieren",
+			"es": "Practicar con un ejercicio",
+			"ru": "Потренироваться на упражнении",
+		}})
+	r.register(Command{Name: "dailypractice", Usage: "[on [HH:MM]|off]", Description: "Get an exercise every day at a time of your choice, in your /timezone", Feature: "practice", Handler: gb.handleDailyPracticeCommand})
+	r.register(Command{Name: "synonyms", Usage: "<word or phrase>", Description: "List synonyms grouped by register, with how each differs", Handler: gb.handleSynonymsCommand})
+	r.register(Command{Name: "language", Usage: "<name|auto>", Description: "Set the language I correct your messages in, or have me detect it", Handler: gb.handleLanguageCommand,
+		MenuDescription: "Set the correction language", Translations: map[string]string{
+			"de": "Korrektursprache festlegen",
+			"es": "Elegir el idioma de corrección",
+			"ru": "Выбрать язык проверки",
+		}})
+	r.register(Command{Name: "style", Usage: "<inline|arrows|minimal>", Description: "Choose how corrections are shown", Handler: gb.handleStyleCommand,
+		MenuDescription: "Choose how corrections are shown", Translations: map[string]string{
+			"de": "Darstellung der Korrekturen wählen",
+			"es": "Elegir cómo se muestran las correcciones",
+			"ru": "Выбрать вид исправлений",
+		}})
+	r.register(Command{Name: "strictness", Usage: "<low|medium|high>", Description: "Choose whether I fix only errors (high) or also improve clarity and flow (low)", Handler: gb.handleStrictnessCommand})
+	r.register(Command{Name: "verbosity", Usage: "<minimal|normal|detailed>", Description: "Choose between just the corrected text, marked corrections, or corrections explained and counted", Handler: gb.handleVerbosityCommand})
+	r.register(Command{Name: "categories", Usage: "<grammar,spelling,punctuation,style|all>", Description: "Correct only some kinds of mistake, such as punctuation and spelling", Handler: gb.handleCategoriesCommand})
+	r.register(Command{Name: "autodelete", Usage: "<seconds|off>", Description: "Delete my corrections in this chat after a delay (chat admins)", Handler: gb.handleAutoDeleteCommand})
+	r.register(Command{Name: "chatlanguage", Usage: "[<name>|off]", Description: "Correct everyone in this group in one language (chat admins)", Handler: gb.handleChatLanguageCommand})
+	r.register(Command{Name: "mentiononly", Usage: "[on|off]", Description: "Check only messages that mention me in this group (chat admins)", Handler: gb.handleMentionOnlyCommand})
+	r.register(Command{Name: "preview", Usage: "[alert|dm|off]", Description: "Show /check corrections to their sender before the group (chat admins)", Handler: gb.handlePreviewCommand})
+	r.register(Command{Name: "polls", Usage: "[on|off]", Description: "Check the question and options of polls sent to this chat (chat admins)", Feature: "polls", Handler: gb.handlePollsCommand})
+	r.register(Command{Name: "greeting", Usage: "[on|off]", Description: "Introduce me to new members of this chat (chat admins)", Handler: gb.handleGreetingCommand})
+	r.register(Command{Name: "typing", Usage: "[on|off]", Description: "Show or hide the typing indicator in this chat (chat admins)", Handler: gb.handleTypingCommand})
+	r.register(Command{Name: "cleanreply", Usage: "<text|reaction>", Description: "Choose a reply or just a reaction for messages without mistakes", Handler: gb.handleCleanReplyCommand})
+	r.register(Command{Name: "usemodel", Usage: "<standard|pro>", Description: "Choose the AI model your messages are checked with (pro for entitled users)", Handler: gb.handleUseModelCommand})
+	r.register(Command{Name: "versions", Usage: "[on|off]", Description: "Also show what you changed when you resend a new version of a text", Handler: gb.handleVersionsCommand})
+	r.register(Command{Name: "feedback", Usage: "[thumbs|stars]", Description: "Rate my corrections with 👍/👎 or with 1 to 5 stars", Handler: gb.handleFeedbackCommand})
+	r.register(Command{Name: "digest", Usage: "[on|off]", Description: "Toggle getting my corrections together every few minutes instead of a reply to each message", Handler: gb.handleDigestCommand})
+	r.register(Command{Name: "tips", Usage: "[on|off]", Description: "Toggle short learning tips about common mistakes you keep making", Handler: gb.handleTipsCommand})
+	r.register(Command{Name: "streak", Usage: "[on|off]", Description: "Show how many days in a row you've checked a message, or turn the daily streak note on or off", Feature: "streaks", Handler: gb.handleStreakCommand})
+	r.register(Command{Name: "settings", Description: "Show all your settings", Handler: gb.handleSettingsCommand})
+	r.register(Command{Name: "reset", Description: "Reset your modes and preferences to the defaults, keeping your history", Handler: gb.handleResetCommand})
+	r.register(Command{Name: "history", Description: "Show your recent checks", Handler: gb.handleHistoryCommand,
+		MenuDescription: "Show your recent checks", Translations: map[string]string{
+			"de": "Letzte Prüfungen anzeigen",
+			"es": "Ver tus revisiones recientes",
+			"ru": "Последние проверки",
+		}})
+	r.register(Command{Name: "export", Description: "Download your settings and history as a file", Handler: gb.handleExportCommand,
+		MenuDescription: "Download your data", Translations: map[string]string{
+			"de": "Deine Daten herunterladen",
+			"es": "Descargar tus datos",
+			"ru": "Скачать свои данные",
+		}})
+	r.register(Command{Name: "timezone", Usage: "<name>", Description: "Set the timezone used for timestamps", Handler: gb.handleTimezoneCommand,
+		MenuDescription: "Set your timezone", Translations: map[string]string{
+			"de": "Zeitzone festlegen",
+			"es": "Configurar tu zona horaria",
+			"ru": "Указать часовой пояс",
+		}})
+	r.register(Command{Name: "whatsnew", Description: "See recent updates", Handler: gb.handleWhatsNewCommand,
+		MenuDescription: "See recent updates", Translations: map[string]string{
+			"de": "Neuigkeiten anzeigen",
+			"es": "Ver las novedades",
+			"ru": "Что нового",
+		}})
+
+	// Admin commands
+	r.register(Command{Name: "queuestatus", Description: "Show queue depth, workers and error rate", AdminOnly: true, Handler: gb.handleQueueStatusCommand})
+	r.register(Command{Name: "internals", Description: "Dump runtime state as JSON", AdminOnly: true, Handler: gb.handleInternalsCommand})
+	r.register(Command{Name: "maintenance", Usage: "[on|off]", Description: "Pause grammar checks for everyone but admins", AdminOnly: true, Handler: gb.handleMaintenanceCommand})
+	r.register(Command{Name: "selftest", Usage: "<text>", Description: "Show each stage of checking a text, to debug formatting", AdminOnly: true, Handler: gb.handleSelfTestCommand})
+	r.register(Command{Name: "comparemodels", Usage: "<text>", Description: "Correct a text with each model and show the results side by side with latencies", AdminOnly: true, Handler: gb.handleCompareModelsCommand})
+	r.register(Command{Name: "block", Usage: "<user ID>", Description: "Ignore a user", AdminOnly: true,
+		Handler: func(message *tgbotapi.Message, args string) { gb.handleBlockCommand(message, args, true) }})
+	r.register(Command{Name: "unblock", Usage: "<user ID>", Description: "Stop ignoring a user", AdminOnly: true,
+		Handler: func(message *tgbotapi.Message, args string) { gb.handleBlockCommand(message, args, false) }})
+	r.register(Command{Name: "grantpro", Usage: "<user ID>", Description: "Let a user choose the pro model", AdminOnly: true,
+		Handler: func(message *tgbotapi.Message, args string) { gb.handleGrantProCommand(message, args, true) }})
+	r.register(Command{Name: "revokepro", Usage: "<user ID>", Description: "Stop a user from choosing the pro model", AdminOnly: true,
+		Handler: func(message *tgbotapi.Message, args string) { gb.handleGrantProCommand(message, args, false) }})
+	r.register(Command{Name: "features", Description: "Show which optional features are on", AdminOnly: true, Handler: gb.handleFeaturesCommand})
+	r.register(Command{Name: "stats", Usage: "global", Description: "Show how users rate corrections by model and prompt version", AdminOnly: true, Handler: gb.handleStatsCommand})
+}
+
+// parsedCommand is a command message split into its parts.
+type parsedCommand struct {
+	// Name is the command without the slash, in lowercase.
+	Name string
+	// Bot is the username after @ in commands like /help@name, or empty.
+	Bot string
+	// Args is the text after the command without surrounding whitespace;
+	// line breaks within it are kept.
+	Args string
+}
+
+// parseCommand splits a command message into its parts. Only messages that
+// start with a command are commands, so "see /help" is not. The command
+// entity's length is in UTF-16 code units.
+func parseCommand(message *tgbotapi.Message) (parsedCommand, bool) {
+	if !message.IsCommand() {
+		return parsedCommand{}, false
+	}
+	end := limitOffset(message.Text, message.Entities[0].Length)
+	if end < 1 {
+		return parsedCommand{}, false
+	}
+
+	name, bot, _ := strings.Cut(message.Text[1:end], "@")
+	return parsedCommand{
+		Name: strings.ToLower(name),
+		Bot:  bot,
+		Args: strings.TrimSpace(message.Text[end:]),
+	}, true
+}
+
+func (gb *GrammarBot) handleCommand(message *tgbotapi.Message) {
+	// Commands like /help@otherbot in groups are meant for another bot
+	parsed, ok := parseCommand(message)
+	if !ok || addressedToOtherBot(message, gb.bot.Self.UserName) {
+		return
+	}
+
+	// Ignore double-tapped or double-sent commands
+	if !gb.debounce.allow(senderID(message), parsed.Name, parsed.Args, time.Now()) {
+		return
+	}
+
+	command, ok := gb.commands.lookup(parsed.Name)
+	if !ok || !gb.features.enabled(command.Feature) || (command.AdminOnly && (message.From == nil || !gb.isAdmin(message.From.ID))) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Unknown command. Use /help to see available commands.")
+		gb.send(msg)
+		return
+	}
+
+	command.Handler(message, parsed.Args)
+}
+
+// addressedToOtherBot reports whether message is a command with an @username
+// suffix naming a bot other than self.
+func addressedToOtherBot(message *tgbotapi.Message, self string) bool {
+	parsed, ok := parseCommand(message)
+	return ok && parsed.Bot != "" && !strings.EqualFold(parsed.Bot, self)
+}
+
+// commandList renders one "/name usage - description" line per command.
+func (gb *GrammarBot) commandList(adminOnly bool) string {
+	var lines []string
+	for _, c := range gb.commands.ordered {
+		if c.AdminOnly != adminOnly || !gb.features.enabled(c.Feature) {
+			continue
+		}
+		line := "/" + c.Name
+		if c.Usage != "" {
+			line += " " + c.Usage
+		}
+		lines = append(lines, line+" - "+c.Description)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// The static replies are MarkdownV2: only the formatting examples are
+// markup, and everything else goes through escapeMarkdownV2.
+
+// handleStartCommand welcomes new users with an introduction and the list
+// of commands. Users who have used the bot before get a short welcome back.
+func (gb *GrammarBot) handleStartCommand(message *tgbotapi.Message, args string) {
+	if gb.store.HasUser(senderID(message)) {
+		name := "there"
+		if message.From != nil {
+			name = message.From.FirstName
+		}
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("👋 Welcome back, %s! Send me any text and I'll check it as before. See your /settings, or /whatsnew for recent updates.", name)))
+		return
+	}
+
+	welcomeText := escapeMarkdownV2(`👋 Welcome to Grammar Check Bot!
+
+Send me any text message and I'll check it for grammar, spelling, and punctuation errors.
+
+I'll show corrections with:
+`) + `\- ~strikethrough~ for original mistakes
+\- *bold* for corrections
+
+` + escapeMarkdownV2("Commands:\n"+gb.commandList(false))
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, welcomeText)
+	msg.ParseMode = "MarkdownV2"
+	gb.send(msg)
+}
+
+func (gb *GrammarBot) handleHelpCommand(message *tgbotapi.Message, args string) {
+	intro := escapeMarkdownV2(`🔍 How to use Grammar Check Bot:
+
+1. Simply send me any text message
+2. I'll analyze it for grammar, spelling, and punctuation errors
+3. You'll receive a corrected version with highlighted changes
+
+📝 Example:
+Your text: "I goes to store yesterday"
+My response: `)
+	example := `"I ~goes~ *went* to ~store~ *the store* yesterday"`
+	scopeHelp := ""
+	if marker := gb.cfg.ScopeMarker; marker != "" {
+		scopeHelp = fmt.Sprintf("\n\nTo check only part of a message, such as one without a quote or code you'd rather I left alone, put the part in quotes after %s, for example: %s \"the part to check\". Or reply to a message with /check, quoting the part to check.", marker, marker)
+	}
+	rest := fmt.Sprintf(`
+
+💡 This helps you verify that your message conveys what you intended before sending it elsewhere!
+
+Very short messages (fewer than %d words) are not checked automatically. Use /check <text> to check them anyway.%s
+
+Commands:
+%s`, gb.cfg.MinWords, scopeHelp, gb.commandList(false))
+	if message.From != nil && gb.isAdmin(message.From.ID) {
+		rest += "\n\nAdmin commands:\n" + gb.commandList(true)
+	}
+
+	text := intro + example + escapeMarkdownV2(rest)
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	msg.ParseMode = "MarkdownV2"
+	gb.send(msg)
+}
+
+func (gb *GrammarBot) handleCheckCommand(message *tgbotapi.Message, text string) {
+	if text == "" && message.ReplyToMessage != nil && len(message.ReplyToMessage.Photo) > 0 && gb.features.get().Photos {
+		gb.handlePhoto(message.ReplyToMessage)
+		return
+	}
+	if text == "" && message.ReplyToMessage != nil {
+		// Check only the quoted part when replying with a quote
+		quote, ok := gb.extras.quote(message)
+		text = quotedText(message.ReplyToMessage.Text, quote, ok)
+	}
+	if text == "" {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Usage: /check <text>, or reply to a message with /check.")
+		gb.send(msg)
+		return
+	}
+	if gb.store.GetUserSettings(senderID(message)).Report && gb.features.get().Reports {
+		gb.checkAndReport(message, text)
+		return
+	}
+	if !message.Chat.IsPrivate() {
+		if mode := gb.store.GetChatSettings(message.Chat.ID).Preview; mode != "" {
+			gb.previewCorrection(message, text, mode)
+			return
+		}
+	}
+	gb.checkAndReply(message, text)
+}
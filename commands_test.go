@@ -0,0 +1,110 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		message *tgbotapi.Message
+		want    parsedCommand
+		ok      bool
+	}{
+		{"plain", command(7, "/help"), parsedCommand{Name: "help"}, true},
+		{"bot suffix", command(7, "/help@grammar_bot"), parsedCommand{Name: "help", Bot: "grammar_bot"}, true},
+		{"bot suffix with arguments", command(7, "/style@Grammar_Bot arrows"), parsedCommand{Name: "style", Bot: "Grammar_Bot", Args: "arrows"}, true},
+		{"uppercase", command(7, "/STYLE arrows"), parsedCommand{Name: "style", Args: "arrows"}, true},
+		{"multi-line arguments", command(7, "/check She go home.\nThey was late."), parsedCommand{Name: "check", Args: "She go home.\nThey was late."}, true},
+		{"arguments on the next line", command(7, "/check\nShe go home."), parsedCommand{Name: "check", Args: "She go home."}, true},
+		{"extra whitespace", command(7, "/language   German  \n "), parsedCommand{Name: "language", Args: "German"}, true},
+		{"inner whitespace kept", command(7, "/check She  go   home."), parsedCommand{Name: "check", Args: "She  go   home."}, true},
+		{"unicode arguments", command(7, "/synonyms schön 😀 früh"), parsedCommand{Name: "synonyms", Args: "schön 😀 früh"}, true},
+		{"mid-text command", &tgbotapi.Message{
+			Text:     "see /help",
+			Entities: []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 4, Length: 5}},
+		}, parsedCommand{}, false},
+		{"no entity", privateMessage(7, "/help"), parsedCommand{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseCommand(tt.message)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseCommand(%q) = %+v, %v, want %+v, %v", tt.message.Text, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// TestHandleCommandPassesParsedArgs checks that handlers get the arguments
+// parseCommand found, without the @bot suffix.
+func TestHandleCommandPassesParsedArgs(t *testing.T) {
+	gb, _ := newTestBot(t, testConfig(t, nil), nil)
+	var got []string
+	gb.commands.register(Command{Name: "echo", Handler: func(message *tgbotapi.Message, args string) { got = append(got, args) }})
+
+	gb.handleCommand(command(7, "/echo@grammar_bot  She go home.\nThey was late. "))
+	gb.handleCommand(command(7, "/echo@other_bot She go home."))
+
+	if len(got) != 1 || got[0] != "She go home.\nThey was late." {
+		t.Errorf("handler got %q, want only the arguments of the command for this bot", got)
+	}
+}
+
+// TestCommandsForOtherBotsIgnored checks that in groups /help@otherbot gets
+// no answer at all, while /help@grammar_bot and /help are handled.
+func TestCommandsForOtherBotsIgnored(t *testing.T) {
+	tests := []struct {
+		text    string
+		handled bool
+	}{
+		{"/help@otherbot", false},
+		{"/unknown@otherbot", false},
+		{"/help@grammar_bot", true},
+		{"/help@Grammar_Bot", true},
+		{"/help", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			gb, tg := newTestBot(t, testConfig(t, nil), nil)
+			message := command(7, tt.text)
+			message.Chat = &tgbotapi.Chat{ID: -100, Type: "supergroup"}
+
+			gb.handleUpdate(tgbotapi.Update{UpdateID: 1, Message: message})
+			if handled := len(tg.callsTo("sendMessage")) > 0; handled != tt.handled {
+				t.Errorf("answered = %v, want %v", handled, tt.handled)
+			}
+		})
+	}
+}
+
+// TestStaticResponsesAreValidMarkdownV2 runs every command without
+// arguments, as an admin so admin commands answer too, and checks that no
+// MarkdownV2 reply had to fall back to plain text.
+func TestStaticResponsesAreValidMarkdownV2(t *testing.T) {
+	logs := captureLogs(t)
+
+	gb, tg := newTestBot(t, testConfig(t, map[string]string{"ADMIN_USER_IDS": "7"}), nil)
+	for _, c := range gb.commands.ordered {
+		c.Handler(command(7, "/"+c.Name), "")
+	}
+	if strings.Contains(logs.String(), "Invalid MarkdownV2") {
+		t.Errorf("a command reply wasn't valid MarkdownV2:\n%s", logs.String())
+	}
+
+	// The introductions are mostly escaped literal text, with some markup
+	gb.handleStartCommand(command(8, "/start"), "")
+	gb.handleHelpCommand(command(8, "/help"), "")
+	gb.handleHelpCommand(command(7, "/help"), "")
+	calls := tg.callsTo("sendMessage")
+	for _, params := range calls[len(calls)-3:] {
+		if mode := params.Get("parse_mode"); mode != "MarkdownV2" {
+			t.Errorf("reply %.40q... sent with parse mode %q, want MarkdownV2", params.Get("text"), mode)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// modelComparison is one model's correction of the compared text.
+type modelComparison struct {
+	model   string
+	raw     string
+	err     error
+	latency time.Duration
+}
+
+// comparedModels returns the standard and pro models and COMPARE_MODELS,
+// each once.
+func (gb *GrammarBot) comparedModels() []string {
+	var models []string
+	for _, model := range append([]string{gb.modelName(modelStandard), gb.modelName(modelPro)}, gb.cfg.CompareModels...) {
+		if model != "" && !containsString(models, model) {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+// handleCompareModelsCommand corrects a text with every compared model,
+// bypassing the cache, and shows admins the corrections side by side with
+// how long each took. At most cfg.SentenceConcurrency models run at once.
+func (gb *GrammarBot) handleCompareModelsCommand(message *tgbotapi.Message, text string) {
+	if text == "" && message.ReplyToMessage != nil {
+		text = messageText(message.ReplyToMessage)
+	}
+	if text == "" {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /comparemodels <text>, or reply to a message with /comparemodels."))
+		return
+	}
+
+	defer gb.startTyping(message.Chat.ID)()
+
+	opts := gb.correctOptions(message)
+	models := gb.comparedModels()
+	results := make([]modelComparison, len(models))
+	slots := make(chan struct{}, gb.cfg.SentenceConcurrency)
+	var wg sync.WaitGroup
+	for i, model := range models {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			modelOpts := opts
+			modelOpts.Model = model
+			start := time.Now()
+			raw, err := gb.correctOnce(gb.ctx, text, modelOpts)
+			results[i] = modelComparison{model: model, raw: raw, err: err, latency: time.Since(start)}
+		}()
+	}
+	wg.Wait()
+
+	var b strings.Builder
+	b.WriteString("⚖️ *Model comparison*")
+	for _, result := range results {
+		fmt.Fprintf(&b, "\n\n*%s* \\(%s\\)\n", escapeMarkdownV2(result.model), escapeMarkdownV2(result.latency.Round(time.Millisecond).String()))
+		switch edits, err := parseInlineEdits(result.raw); {
+		case result.err != nil:
+			b.WriteString(escapeMarkdownV2(fmt.Sprintf("❌ failed (%s): %v", classifyError(result.err), result.err)))
+		case err == nil && !opts.FlagOnly:
+			b.WriteString(renderEdits(edits, styleInline))
+		default:
+			fmt.Fprintf(&b, "```\n%s\n```", escapeCodeBlock(result.raw))
+		}
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, b.String())
+	msg.ParseMode = "MarkdownV2"
+	if _, err := gb.send(msg); err != nil {
+		log.Printf("Error sending model comparison: %v", err)
+	}
+}
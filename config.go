@@ -0,0 +1,670 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// Supported values of the BACKEND environment variable.
+const (
+	backendGemini = "gemini"
+	backendOpenAI = "openai"
+)
+
+// Config holds the runtime settings read from the environment.
+type Config struct {
+	TelegramToken string
+
+	// Backend selects the GrammarEngine: "gemini" (default) or "openai"
+	// for any OpenAI-compatible chat-completions endpoint.
+	Backend      string
+	GeminiAPIKey string
+
+	OpenAIBaseURL string
+	OpenAIAPIKey  string
+
+	// Standard and pro model names of each backend (GEMINI_MODEL,
+	// GEMINI_PRO_MODEL, OPENAI_MODEL, OPENAI_PRO_MODEL).
+	GeminiModel    string
+	GeminiProModel string
+	OpenAIModel    string
+	OpenAIProModel string
+
+	// ModelSelection is "standard" (default) or "pro" to force one model, or
+	// "auto" to use the pro model only for inputs of at least AutoModelWords
+	// words or AutoModelChars characters (MODEL_SELECTION, AUTO_MODEL_WORDS,
+	// AUTO_MODEL_CHARS).
+	ModelSelection string
+	AutoModelWords int
+	AutoModelChars int
+
+	// MinWords is the minimum number of words a private message must have
+	// before it is checked automatically (MIN_WORDS, default 3). Shorter
+	// messages such as "ok" or "thanks" are skipped; /check always runs.
+	MinWords int
+
+	// OfflineFallback enables basic rule-based corrections when the AI
+	// backend fails (OFFLINE_FALLBACK, default false).
+	OfflineFallback bool
+
+	// StorePath is the JSON file user settings are persisted to
+	// (STORE_PATH). When empty, settings live in memory only.
+	StorePath string
+
+	// Workers is how many updates are processed concurrently (WORKERS,
+	// default 4). QueueSize bounds how many updates may wait for a worker
+	// (QUEUE_SIZE, default 100) before polling blocks.
+	Workers   int
+	QueueSize int
+
+	// AdminIDs lists the Telegram user IDs allowed to run admin commands
+	// (ADMIN_USER_IDS, comma-separated).
+	AdminIDs []int64
+
+	// ChannelCorrections enables correcting channel posts (CHANNEL_CORRECTIONS,
+	// default false). Corrections are posted as comments in the channel's
+	// linked discussion group, so the bot must be an admin of the channel (to
+	// receive posts) and a member of the discussion group with privacy mode
+	// disabled or admin rights there (to see and reply to forwarded posts).
+	ChannelCorrections bool
+
+	// ProUserIDs may choose the pro model with /usemodel pro
+	// (PRO_USER_IDS). Admins can grant it to more users with /grantpro.
+	ProUserIDs []int64
+
+	// BlockedUserIDs are ignored entirely (BLOCKLIST_USER_IDS). Admins can
+	// block more users at runtime with /block.
+	BlockedUserIDs []int64
+	// AllowedChatIDs, when set, restricts the bot to these chats
+	// (ALLOWLIST_CHAT_IDS). Admins are exempt from both lists.
+	AllowedChatIDs []int64
+
+	// MaxImageBytes is the largest photo checked for text (MAX_IMAGE_BYTES,
+	// default 5 MiB).
+	MaxImageBytes int64
+	// DownloadTimeout is how long downloading a file the user sent may take
+	// (DOWNLOAD_TIMEOUT, default 30s).
+	DownloadTimeout time.Duration
+
+	// Soft usage limit for free-tier fairness: once a user makes more than
+	// SoftLimitChecks checks within SoftLimitWindow, each further reply is
+	// delayed by SoftLimitDelay. Other users are unaffected, and nothing is
+	// refused. SOFT_LIMIT_CHECKS=0 (the default) disables it.
+	SoftLimitChecks int
+	SoftLimitWindow time.Duration
+	SoftLimitDelay  time.Duration
+
+	// MaxHighlights caps how many changes are marked up in a correction
+	// (MAX_HIGHLIGHTS, default 0 for no limit). The most significant changes
+	// are highlighted; the rest are applied silently and summarized.
+	MaxHighlights int
+
+	// TypingAction shows the typing indicator while a check runs
+	// (TYPING_ACTION, default true). Chats can also turn it off with /typing.
+	TypingAction bool
+
+	// CacheSize is how many corrections are cached for repeated texts
+	// (CACHE_SIZE, default 0 for no cache), each for CacheTTL (CACHE_TTL,
+	// default 1h).
+	CacheSize int
+	CacheTTL  time.Duration
+
+	// RepeatWindow suppresses sending a chat the same correction twice within
+	// this long, reacting to the message instead (REPEAT_WINDOW, default 0
+	// to always reply).
+	RepeatWindow time.Duration
+
+	// HTTPAPIAddr enables the HTTP API on this address, e.g. ":8080"
+	// (HTTP_API_ADDR). Requests must carry HTTPAPIToken as a bearer token
+	// (HTTP_API_TOKEN).
+	HTTPAPIAddr  string
+	HTTPAPIToken string
+	// Dashboard serves an admin web dashboard at /dashboard on the HTTP API
+	// (DASHBOARD, default false). Browsers sign in with HTTPAPIToken as the
+	// password.
+	Dashboard bool
+
+	// ChunkMarkers prefixes each part of a reply too long for one message
+	// with "(part k/n)" (CHUNK_MARKERS, default true).
+	ChunkMarkers bool
+
+	// RateLimitQueue queues checks that fail because the AI backend is
+	// rate-limited and retries them every RateLimitRetry, instead of failing
+	// at once (RATE_LIMIT_QUEUE, default false; RATE_LIMIT_RETRY, default
+	// 1m). Checks still queued after RateLimitQueueMaxAge are dropped with an
+	// apology (RATE_LIMIT_QUEUE_MAX_AGE, default 1h).
+	RateLimitQueue       bool
+	RateLimitRetry       time.Duration
+	RateLimitQueueMaxAge time.Duration
+
+	// ResponseTag is prepended to everything the bot sends, e.g. "[staging]",
+	// to tell instances sharing a chat apart (RESPONSE_TAG, default none).
+	ResponseTag string
+
+	// BusinessMode corrects messages customers send to connected Telegram
+	// Business accounts, replying on the account's behalf (BUSINESS_MODE,
+	// default false). To set it up, enable Business Mode for the bot in
+	// @BotFather, then connect the bot in the account's Telegram Business >
+	// Chatbots settings and allow it to reply to messages. The account
+	// owner's settings apply to the corrections.
+	BusinessMode bool
+
+	// SentenceSplitChars corrects texts of at least this many characters in
+	// batches of sentences, which avoids truncated output and keeps quality
+	// up on long texts (SENTENCE_SPLIT_CHARS, default 0 to never split). Up
+	// to SentenceConcurrency batches of one text are corrected at once
+	// (SENTENCE_CONCURRENCY, default 3).
+	SentenceSplitChars  int
+	SentenceConcurrency int
+
+	// InlineDebounce is how long a user must pause typing an inline query
+	// before it is checked (INLINE_DEBOUNCE, default 800ms). Inline mode
+	// itself is enabled for the bot with /setinline in @BotFather.
+	InlineDebounce time.Duration
+
+	// Retry makes up to Retry.Attempts attempts of AI calls that time out,
+	// are rate-limited or fail on the server (RETRY_MAX_ATTEMPTS, default 3).
+	// Retries wait Retry.BaseDelay (RETRY_BASE_DELAY, default 500ms), times
+	// Retry.Multiplier after each retry (RETRY_MULTIPLIER, default 2), up to
+	// Retry.MaxDelay (RETRY_MAX_DELAY, default 5s), each shortened by a
+	// random fraction of up to Retry.Jitter (RETRY_JITTER, default 0.2).
+	Retry backoffPolicy
+
+	// NonTextMessages is how private messages without text are answered
+	// (NON_TEXT_MESSAGES): nonTextCheck (the default) checks media captions
+	// and venue names and says other messages can't be checked, nonTextNote
+	// only says so, and nonTextIgnore doesn't answer.
+	NonTextMessages string
+
+	// Maintenance turns maintenance mode on at startup (MAINTENANCE, default
+	// false); otherwise the mode last set with /maintenance is kept. Users
+	// get MaintenanceMessage (MAINTENANCE_MESSAGE) instead of checks.
+	Maintenance        bool
+	MaintenanceMessage string
+
+	// CorrectionFormat is how the model answers corrections
+	// (CORRECTION_FORMAT): formatText (the default) in MarkdownV2, or
+	// formatJSON as a list of edits the bot renders itself, falling back to
+	// the text format when the JSON can't be used.
+	CorrectionFormat string
+
+	// OffsetCommitInterval is how often the offset of handled updates is
+	// saved (OFFSET_COMMIT_INTERVAL). The default 0 saves it after every
+	// update; longer intervals write less but may repeat the updates of the
+	// last interval after a crash.
+	OffsetCommitInterval time.Duration
+
+	// DetectConfidence is how sure language detection for /language auto
+	// must be, from 0 to 1, to go ahead without asking the user
+	// (LANGUAGE_DETECT_CONFIDENCE, default 0.7).
+	DetectConfidence float64
+	// DetectAsk asks the user which language a message is in when detection
+	// isn't sure enough and they haven't picked one lately
+	// (LANGUAGE_DETECT_ASK, default true). Without asking, and in inline
+	// queries, which can't ask, FallbackLanguage is used (FALLBACK_LANGUAGE,
+	// default the user's /language). FallbackLanguage is also used when
+	// detection fails. A chat's language lock takes precedence over all of
+	// this: detection only runs where it isn't set.
+	DetectAsk        bool
+	FallbackLanguage string
+
+	// VersionDiffDepth is how many of the user's latest checks a text is
+	// compared with for /versions (VERSION_DIFF_DEPTH, default 5).
+	VersionDiffDepth int
+
+	// ScopeMarker, followed by a quoted part of a message as in
+	// `check: "the part"`, has only that part checked (SCOPE_MARKER,
+	// default "check:"; "off" turns it off, leaving ScopeMarker empty).
+	ScopeMarker string
+
+	// Features are the optional features turned on, all but those listed in
+	// DISABLED_FEATURES.
+	Features Features
+
+	// GreetingCooldown is the least time between two greetings of new
+	// members in a chat (GREETING_COOLDOWN, default 10m).
+	GreetingCooldown time.Duration
+
+	// SafetyBlockMessage answers checks the AI's safety filters blocked
+	// (SAFETY_BLOCK_MESSAGE).
+	SafetyBlockMessage string
+
+	// MentionHint answers a mention with nothing to check in a mention-only
+	// group (MENTION_HINT). Empty uses a built-in hint in the sender's
+	// language.
+	MentionHint string
+
+	// DigestInterval and DigestSize are how long after its first correction
+	// and at how many corrections a /digest is sent (DIGEST_INTERVAL,
+	// default 5m, and DIGEST_SIZE, default 10).
+	DigestInterval time.Duration
+	DigestSize     int
+
+	// GeminiSafety are the safety thresholds sent to Gemini, from
+	// GEMINI_SAFETY_THRESHOLD and GEMINI_SAFETY_<CATEGORY>. Categories not
+	// listed use the API's defaults.
+	GeminiSafety []*genai.SafetySetting
+
+	// CompareModels are models /comparemodels tries besides the standard and
+	// pro ones (COMPARE_MODELS, comma-separated).
+	CompareModels []string
+
+	// LogRepeatInterval is how long an error that fails every check, as
+	// during an outage, is logged once and its repeats only counted
+	// (LOG_REPEAT_INTERVAL, default 1m, 0 logs every one).
+	LogRepeatInterval time.Duration
+
+	// ChatCooldown spaces out the automatic checks of each group chat
+	// (CHAT_COOLDOWN, default 0, off). Messages arriving sooner are skipped,
+	// or with CHAT_COOLDOWN_MODE=queue checked later, at most
+	// maxQueuedPerChat at a time. /check is never held back. The cooldown
+	// applies before a check and the per-user soft limit after it, so a user
+	// over their limit in a cooling chat waits for both.
+	ChatCooldown     time.Duration
+	ChatCooldownMode string
+
+	// OTLPEndpoint, when set, exports traces of each update's handling and
+	// the metrics to an OpenTelemetry collector over OTLP/HTTP
+	// (OTEL_EXPORTER_OTLP_ENDPOINT, such as http://collector:4318), as
+	// OTelServiceName (OTEL_SERVICE_NAME, default smart-grammar-bot).
+	OTLPEndpoint    string
+	OTelServiceName string
+}
+
+func loadConfig() (Config, error) {
+	cfg := Config{
+		TelegramToken: os.Getenv("TELEGRAM_BOT_TOKEN"),
+		Backend:       envString("BACKEND", backendGemini),
+		GeminiAPIKey:  os.Getenv("GEMINI_API_KEY"),
+		OpenAIBaseURL: os.Getenv("OPENAI_BASE_URL"),
+		OpenAIAPIKey:  os.Getenv("OPENAI_API_KEY"),
+		StorePath:     os.Getenv("STORE_PATH"),
+		HTTPAPIAddr:   os.Getenv("HTTP_API_ADDR"),
+		HTTPAPIToken:  os.Getenv("HTTP_API_TOKEN"),
+		ResponseTag:   strings.TrimSpace(os.Getenv("RESPONSE_TAG")),
+
+		GeminiModel:    envString("GEMINI_MODEL", "gemini-2.5-flash-preview-05-20"),
+		GeminiProModel: envString("GEMINI_PRO_MODEL", "gemini-2.5-pro"),
+		OpenAIModel:    os.Getenv("OPENAI_MODEL"),
+		ModelSelection: envString("MODEL_SELECTION", modelStandard),
+
+		NonTextMessages:    envString("NON_TEXT_MESSAGES", nonTextCheck),
+		MaintenanceMessage: envString("MAINTENANCE_MESSAGE", "🛠 I'm under maintenance right now and will be back soon. Please try again later."),
+		CorrectionFormat:   envString("CORRECTION_FORMAT", formatText),
+		ScopeMarker:        strings.TrimSpace(envString("SCOPE_MARKER", "check:")),
+		SafetyBlockMessage: envString("SAFETY_BLOCK_MESSAGE", "Sorry, I can't process text with that kind of content."),
+		MentionHint:        strings.TrimSpace(os.Getenv("MENTION_HINT")),
+	}
+	cfg.OpenAIProModel = envString("OPENAI_PRO_MODEL", cfg.OpenAIModel)
+
+	if cfg.TelegramToken == "" {
+		return cfg, fmt.Errorf("TELEGRAM_BOT_TOKEN environment variable is required")
+	}
+
+	switch cfg.Backend {
+	case backendGemini:
+		if cfg.GeminiAPIKey == "" {
+			return cfg, fmt.Errorf("GEMINI_API_KEY environment variable is required")
+		}
+	case backendOpenAI:
+		if cfg.OpenAIBaseURL == "" {
+			return cfg, fmt.Errorf("OPENAI_BASE_URL environment variable is required for the openai backend")
+		}
+		if cfg.OpenAIModel == "" {
+			return cfg, fmt.Errorf("OPENAI_MODEL environment variable is required for the openai backend")
+		}
+	default:
+		return cfg, fmt.Errorf("unknown BACKEND %q, expected %q or %q", cfg.Backend, backendGemini, backendOpenAI)
+	}
+
+	if cfg.HTTPAPIAddr != "" && cfg.HTTPAPIToken == "" {
+		return cfg, fmt.Errorf("HTTP_API_TOKEN environment variable is required when HTTP_API_ADDR is set")
+	}
+
+	switch cfg.ModelSelection {
+	case modelStandard, modelPro, modelAuto:
+	default:
+		return cfg, fmt.Errorf("unknown MODEL_SELECTION %q, expected %q, %q or %q", cfg.ModelSelection, modelStandard, modelPro, modelAuto)
+	}
+
+	switch cfg.NonTextMessages {
+	case nonTextCheck, nonTextNote, nonTextIgnore:
+	default:
+		return cfg, fmt.Errorf("unknown NON_TEXT_MESSAGES %q, expected %q, %q or %q", cfg.NonTextMessages, nonTextCheck, nonTextNote, nonTextIgnore)
+	}
+
+	switch cfg.CorrectionFormat {
+	case formatText, formatJSON:
+	default:
+		return cfg, fmt.Errorf("unknown CORRECTION_FORMAT %q, expected %q or %q", cfg.CorrectionFormat, formatText, formatJSON)
+	}
+
+	var err error
+	if cfg.AutoModelWords, err = envInt("AUTO_MODEL_WORDS", 60); err != nil {
+		return cfg, err
+	}
+	if cfg.AutoModelChars, err = envInt("AUTO_MODEL_CHARS", 400); err != nil {
+		return cfg, err
+	}
+	if cfg.MinWords, err = envInt("MIN_WORDS", 3); err != nil {
+		return cfg, err
+	}
+	if cfg.MinWords < 0 {
+		return cfg, fmt.Errorf("MIN_WORDS must not be negative, got %d", cfg.MinWords)
+	}
+
+	if cfg.OfflineFallback, err = envBool("OFFLINE_FALLBACK", false); err != nil {
+		return cfg, err
+	}
+	if cfg.Workers, err = envInt("WORKERS", 4); err != nil {
+		return cfg, err
+	}
+	if cfg.Workers < 1 {
+		return cfg, fmt.Errorf("WORKERS must be at least 1, got %d", cfg.Workers)
+	}
+	if cfg.QueueSize, err = envInt("QUEUE_SIZE", 100); err != nil {
+		return cfg, err
+	}
+	if cfg.QueueSize < 0 {
+		return cfg, fmt.Errorf("QUEUE_SIZE must not be negative, got %d", cfg.QueueSize)
+	}
+	if cfg.AdminIDs, err = envInt64List("ADMIN_USER_IDS"); err != nil {
+		return cfg, err
+	}
+	if cfg.ChannelCorrections, err = envBool("CHANNEL_CORRECTIONS", false); err != nil {
+		return cfg, err
+	}
+	if cfg.ProUserIDs, err = envInt64List("PRO_USER_IDS"); err != nil {
+		return cfg, err
+	}
+	if cfg.BlockedUserIDs, err = envInt64List("BLOCKLIST_USER_IDS"); err != nil {
+		return cfg, err
+	}
+	if cfg.AllowedChatIDs, err = envInt64List("ALLOWLIST_CHAT_IDS"); err != nil {
+		return cfg, err
+	}
+	maxImageBytes, err := envInt("MAX_IMAGE_BYTES", 5<<20)
+	if err != nil {
+		return cfg, err
+	}
+	if maxImageBytes < 1 {
+		return cfg, fmt.Errorf("MAX_IMAGE_BYTES must be positive, got %d", maxImageBytes)
+	}
+	cfg.MaxImageBytes = int64(maxImageBytes)
+	if cfg.DownloadTimeout, err = envDuration("DOWNLOAD_TIMEOUT", 30*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.DownloadTimeout <= 0 {
+		return cfg, fmt.Errorf("DOWNLOAD_TIMEOUT must be positive, got %s", cfg.DownloadTimeout)
+	}
+	if cfg.SoftLimitChecks, err = envInt("SOFT_LIMIT_CHECKS", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.SoftLimitChecks < 0 {
+		return cfg, fmt.Errorf("SOFT_LIMIT_CHECKS must not be negative, got %d", cfg.SoftLimitChecks)
+	}
+	if cfg.SoftLimitWindow, err = envDuration("SOFT_LIMIT_WINDOW", time.Hour); err != nil {
+		return cfg, err
+	}
+	if cfg.SoftLimitDelay, err = envDuration("SOFT_LIMIT_DELAY", 5*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxHighlights, err = envInt("MAX_HIGHLIGHTS", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxHighlights < 0 {
+		return cfg, fmt.Errorf("MAX_HIGHLIGHTS must not be negative, got %d", cfg.MaxHighlights)
+	}
+	if cfg.TypingAction, err = envBool("TYPING_ACTION", true); err != nil {
+		return cfg, err
+	}
+	if cfg.CacheSize, err = envInt("CACHE_SIZE", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.CacheSize < 0 {
+		return cfg, fmt.Errorf("CACHE_SIZE must not be negative, got %d", cfg.CacheSize)
+	}
+	if cfg.CacheTTL, err = envDuration("CACHE_TTL", time.Hour); err != nil {
+		return cfg, err
+	}
+	if cfg.RepeatWindow, err = envDuration("REPEAT_WINDOW", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.ChunkMarkers, err = envBool("CHUNK_MARKERS", true); err != nil {
+		return cfg, err
+	}
+	if cfg.RateLimitQueue, err = envBool("RATE_LIMIT_QUEUE", false); err != nil {
+		return cfg, err
+	}
+	if cfg.RateLimitRetry, err = envDuration("RATE_LIMIT_RETRY", time.Minute); err != nil {
+		return cfg, err
+	}
+	if cfg.RateLimitRetry == 0 {
+		return cfg, fmt.Errorf("RATE_LIMIT_RETRY must be positive")
+	}
+	if cfg.RateLimitQueueMaxAge, err = envDuration("RATE_LIMIT_QUEUE_MAX_AGE", time.Hour); err != nil {
+		return cfg, err
+	}
+	if cfg.BusinessMode, err = envBool("BUSINESS_MODE", false); err != nil {
+		return cfg, err
+	}
+	if cfg.SentenceSplitChars, err = envInt("SENTENCE_SPLIT_CHARS", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.SentenceSplitChars < 0 {
+		return cfg, fmt.Errorf("SENTENCE_SPLIT_CHARS must not be negative, got %d", cfg.SentenceSplitChars)
+	}
+	if cfg.SentenceConcurrency, err = envInt("SENTENCE_CONCURRENCY", 3); err != nil {
+		return cfg, err
+	}
+	if cfg.SentenceConcurrency < 1 {
+		return cfg, fmt.Errorf("SENTENCE_CONCURRENCY must be at least 1, got %d", cfg.SentenceConcurrency)
+	}
+	if cfg.InlineDebounce, err = envDuration("INLINE_DEBOUNCE", 800*time.Millisecond); err != nil {
+		return cfg, err
+	}
+	if cfg.Retry.Attempts, err = envInt("RETRY_MAX_ATTEMPTS", 3); err != nil {
+		return cfg, err
+	}
+	if cfg.Retry.Attempts < 1 {
+		return cfg, fmt.Errorf("RETRY_MAX_ATTEMPTS must be at least 1, got %d", cfg.Retry.Attempts)
+	}
+	if cfg.Retry.BaseDelay, err = envDuration("RETRY_BASE_DELAY", 500*time.Millisecond); err != nil {
+		return cfg, err
+	}
+	if cfg.Retry.MaxDelay, err = envDuration("RETRY_MAX_DELAY", 5*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.Retry.MaxDelay < cfg.Retry.BaseDelay {
+		return cfg, fmt.Errorf("RETRY_MAX_DELAY must not be less than RETRY_BASE_DELAY, got %s and %s", cfg.Retry.MaxDelay, cfg.Retry.BaseDelay)
+	}
+	if cfg.Retry.Multiplier, err = envFloat("RETRY_MULTIPLIER", 2); err != nil {
+		return cfg, err
+	}
+	if cfg.Retry.Multiplier < 1 {
+		return cfg, fmt.Errorf("RETRY_MULTIPLIER must be at least 1, got %g", cfg.Retry.Multiplier)
+	}
+	if cfg.Retry.Jitter, err = envFloat("RETRY_JITTER", 0.2); err != nil {
+		return cfg, err
+	}
+	if cfg.Retry.Jitter < 0 || cfg.Retry.Jitter > 1 {
+		return cfg, fmt.Errorf("RETRY_JITTER must be between 0 and 1, got %g", cfg.Retry.Jitter)
+	}
+	if cfg.Maintenance, err = envBool("MAINTENANCE", false); err != nil {
+		return cfg, err
+	}
+	if strings.EqualFold(cfg.ScopeMarker, "off") {
+		cfg.ScopeMarker = ""
+	}
+	if cfg.Features, err = loadFeatures(); err != nil {
+		return cfg, err
+	}
+	if cfg.GreetingCooldown, err = envDuration("GREETING_COOLDOWN", 10*time.Minute); err != nil {
+		return cfg, err
+	}
+	if cfg.GreetingCooldown < 0 {
+		return cfg, fmt.Errorf("GREETING_COOLDOWN must not be negative, got %s", cfg.GreetingCooldown)
+	}
+	if cfg.OffsetCommitInterval, err = envDuration("OFFSET_COMMIT_INTERVAL", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.OffsetCommitInterval < 0 {
+		return cfg, fmt.Errorf("OFFSET_COMMIT_INTERVAL must not be negative, got %s", cfg.OffsetCommitInterval)
+	}
+	if cfg.DetectConfidence, err = envFloat("LANGUAGE_DETECT_CONFIDENCE", 0.7); err != nil {
+		return cfg, err
+	}
+	if cfg.DetectConfidence < 0 || cfg.DetectConfidence > 1 {
+		return cfg, fmt.Errorf("LANGUAGE_DETECT_CONFIDENCE must be between 0 and 1, got %g", cfg.DetectConfidence)
+	}
+	if cfg.DetectAsk, err = envBool("LANGUAGE_DETECT_ASK", true); err != nil {
+		return cfg, err
+	}
+	if name := os.Getenv("FALLBACK_LANGUAGE"); name != "" {
+		language, ok := normalizeLanguage(name)
+		if !ok {
+			return cfg, fmt.Errorf("unknown FALLBACK_LANGUAGE %q, expected a language name such as German", name)
+		}
+		cfg.FallbackLanguage = language
+	}
+	if cfg.VersionDiffDepth, err = envInt("VERSION_DIFF_DEPTH", 5); err != nil {
+		return cfg, err
+	}
+	if cfg.VersionDiffDepth < 1 || cfg.VersionDiffDepth > maxHistoryEntries {
+		return cfg, fmt.Errorf("VERSION_DIFF_DEPTH must be between 1 and %d, got %d", maxHistoryEntries, cfg.VersionDiffDepth)
+	}
+	if cfg.DigestInterval, err = envDuration("DIGEST_INTERVAL", 5*time.Minute); err != nil {
+		return cfg, err
+	}
+	if cfg.DigestInterval <= 0 {
+		return cfg, fmt.Errorf("DIGEST_INTERVAL must be positive, got %s", cfg.DigestInterval)
+	}
+	if cfg.DigestSize, err = envInt("DIGEST_SIZE", 10); err != nil {
+		return cfg, err
+	}
+	if cfg.DigestSize < 1 {
+		return cfg, fmt.Errorf("DIGEST_SIZE must be at least 1, got %d", cfg.DigestSize)
+	}
+	for _, model := range strings.Split(os.Getenv("COMPARE_MODELS"), ",") {
+		if model = strings.TrimSpace(model); model != "" {
+			cfg.CompareModels = append(cfg.CompareModels, model)
+		}
+	}
+	if cfg.LogRepeatInterval, err = envDuration("LOG_REPEAT_INTERVAL", time.Minute); err != nil {
+		return cfg, err
+	}
+	if cfg.ChatCooldown, err = envDuration("CHAT_COOLDOWN", 0); err != nil {
+		return cfg, err
+	}
+	cfg.ChatCooldownMode = envString("CHAT_COOLDOWN_MODE", cooldownDrop)
+	switch cfg.ChatCooldownMode {
+	case cooldownDrop, cooldownQueue:
+	default:
+		return cfg, fmt.Errorf("unknown CHAT_COOLDOWN_MODE %q, expected %q or %q", cfg.ChatCooldownMode, cooldownDrop, cooldownQueue)
+	}
+	cfg.OTLPEndpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	cfg.OTelServiceName = envString("OTEL_SERVICE_NAME", "smart-grammar-bot")
+	if cfg.GeminiSafety, err = loadGeminiSafety(); err != nil {
+		return cfg, err
+	}
+	if cfg.Dashboard, err = envBool("DASHBOARD", false); err != nil {
+		return cfg, err
+	}
+	if cfg.Dashboard && cfg.HTTPAPIAddr == "" {
+		return cfg, fmt.Errorf("HTTP_API_ADDR environment variable is required when DASHBOARD is on")
+	}
+
+	return cfg, nil
+}
+
+// envString reads a string environment variable, returning def when it is unset.
+func envString(name, def string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return def
+}
+
+// envInt reads an integer environment variable, returning def when it is unset.
+func envInt(name string, def int) (int, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, raw, err)
+	}
+	return value, nil
+}
+
+// envFloat reads a floating-point environment variable, returning def when
+// it is unset.
+func envFloat(name string, def float64) (float64, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, raw, err)
+	}
+	return value, nil
+}
+
+// envDuration reads a duration such as "90s" or "1h", returning def when it
+// is unset. Negative durations are rejected.
+func envDuration(name string, def time.Duration) (time.Duration, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+
+	value, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, raw, err)
+	}
+	if value < 0 {
+		return 0, fmt.Errorf("%s must not be negative, got %s", name, raw)
+	}
+	return value, nil
+}
+
+// envInt64List reads a comma-separated list of integers, such as Telegram IDs.
+func envInt64List(name string) ([]int64, error) {
+	var values []int64
+	for _, field := range strings.Split(os.Getenv(name), ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		value, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q: %w", name, field, err)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// envBool reads a boolean environment variable, returning def when it is unset.
+func envBool(name string, def bool) (bool, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q: %w", name, raw, err)
+	}
+	return value, nil
+}
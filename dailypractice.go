@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// dailyPracticeTick is how often the scheduler looks for exercises due.
+	dailyPracticeTick = 30 * time.Second
+	// dailyPracticeGrace is how late an exercise may still be sent, as after
+	// a restart; older ones are skipped until the next day.
+	dailyPracticeGrace = 2 * time.Hour
+	// dailyPracticePace spaces out exercises due at the same time, keeping
+	// well under Telegram's limit of 30 messages a second.
+	dailyPracticePace = 100 * time.Millisecond
+	// defaultDailyPracticeTime is used by "/dailypractice on" without a time.
+	defaultDailyPracticeTime = "09:00"
+)
+
+// parseClock parses an "HH:MM" time of day.
+func parseClock(clock string) (hour, minute int, ok bool) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, 0, false
+	}
+	return t.Hour(), t.Minute(), true
+}
+
+// nextDailyPractice returns the first time after after that the clock in
+// loc shows clock. Each day's time is computed from the clock rather than by
+// adding 24 hours, so it doesn't drift across daylight saving changes.
+func nextDailyPractice(clock string, loc *time.Location, after time.Time) time.Time {
+	hour, minute, _ := parseClock(clock)
+	local := after.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc)
+	if !next.After(after) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, hour, minute, 0, 0, loc)
+	}
+	return next.UTC()
+}
+
+// runDailyPractice sends daily exercises as they come due until ctx is
+// cancelled.
+func (gb *GrammarBot) runDailyPractice(ctx context.Context) {
+	ticker := time.NewTicker(dailyPracticeTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			gb.sendDueDailyPractice(ctx)
+		}
+	}
+}
+
+// sendDueDailyPractice sends every exercise due, one at a time. Each user's
+// next time is saved before their exercise is sent, so a restart midway
+// never sends one twice.
+func (gb *GrammarBot) sendDueDailyPractice(ctx context.Context) {
+	if !gb.features.get().Practice || gb.store.Maintenance() {
+		return
+	}
+
+	for _, userID := range gb.store.DueDailyPractice(time.Now()) {
+		if ctx.Err() != nil {
+			return
+		}
+
+		now := time.Now()
+		var due time.Time
+		err := gb.store.UpdateUserSettings(userID, func(settings *UserSettings) {
+			due = settings.DailyPracticeNext
+			settings.DailyPracticeNext = nextDailyPractice(settings.DailyPractice, settingsLocation(*settings), now)
+			if now.Sub(due) <= dailyPracticeGrace {
+				settings.DailyPracticeSent++
+			}
+		})
+		if err != nil {
+			log.Printf("Error scheduling daily practice: %v", err)
+			continue
+		}
+		if now.Sub(due) > dailyPracticeGrace {
+			continue
+		}
+
+		exercise, err := gb.generateExercise(userID)
+		if err != nil {
+			log.Printf("Error generating daily exercise: %v", err)
+			continue
+		}
+		exercise.daily = true
+		if err := gb.sendExercise(userID, userID, exercise, "☀️ Your daily exercise: find and fix the mistake."); err != nil {
+			log.Printf("Error sending daily exercise: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(dailyPracticePace):
+		}
+	}
+}
+
+// handleDailyPracticeCommand shows or sets the user's daily exercise:
+// "/dailypractice on [HH:MM]" or "/dailypractice off". Exercises are sent
+// to the private chat at that time in the user's time zone.
+func (gb *GrammarBot) handleDailyPracticeCommand(message *tgbotapi.Message, args string) {
+	userID := senderID(message)
+	settings := gb.store.GetUserSettings(userID)
+	words := strings.Fields(strings.ToLower(args))
+
+	if len(words) == 0 {
+		reply := "Daily practice is off. Use /dailypractice on 09:00 to get an exercise every day at that time."
+		if settings.DailyPractice != "" {
+			reply = fmt.Sprintf("Daily practice is on at %s (%s). You've answered %d of %d daily exercises.",
+				settings.DailyPractice, gb.userLocation(userID), settings.DailyPracticeDone, settings.DailyPracticeSent)
+		}
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, reply))
+		return
+	}
+
+	// clock is the new daily time, or empty to turn daily practice off
+	var clock string
+	switch {
+	case words[0] == "off" && len(words) == 1:
+	case words[0] == "on" && len(words) <= 2:
+		if !message.Chat.IsPrivate() {
+			gb.send(tgbotapi.NewMessage(message.Chat.ID, "Daily practice is sent in our private chat. Send /dailypractice on there."))
+			return
+		}
+		clock = defaultDailyPracticeTime
+		if len(words) == 2 {
+			clock = words[1]
+		}
+		hour, minute, ok := parseClock(clock)
+		if !ok {
+			gb.send(tgbotapi.NewMessage(message.Chat.ID, "That isn't a time I understand. Use 24-hour HH:MM, for example /dailypractice on 09:00."))
+			return
+		}
+		clock = fmt.Sprintf("%02d:%02d", hour, minute)
+	default:
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /dailypractice [on [HH:MM]|off]"))
+		return
+	}
+
+	// The next exercise is due in the stored time zone, so a concurrent
+	// /timezone can't leave it at the old one
+	if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) {
+		stored.DailyPractice = clock
+		stored.DailyPracticeNext = time.Time{}
+		if clock != "" {
+			stored.DailyPracticeNext = nextDailyPractice(clock, settingsLocation(*stored), time.Now())
+		}
+		settings = stored.clone()
+	}); err != nil {
+		log.Printf("Error saving settings: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	reply := "Daily practice is off."
+	if clock != "" {
+		reply = fmt.Sprintf("Daily practice is on. I'll send you an exercise every day at %s (%s); change your time zone with /timezone.",
+			clock, settingsLocation(settings))
+	}
+	gb.replyWithSettings(message, reply)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+)
+
+//go:embed templates/dashboard.html
+var dashboardFS embed.FS
+
+var dashboardTemplate = template.Must(template.New("dashboard.html").Funcs(template.FuncMap{
+	"percent": func(f float64) string { return fmt.Sprintf("%.1f%%", f*100) },
+	"ms": func(d time.Duration) string {
+		return fmt.Sprintf("%d ms", d.Milliseconds())
+	},
+	"clock": func(t time.Time) string { return t.Format("2006-01-02 15:04:05 UTC") },
+}).ParseFS(dashboardFS, "templates/dashboard.html"))
+
+// dashboardPage is what the dashboard template renders.
+type dashboardPage struct {
+	internalsSnapshot
+	Maintenance bool
+	Features    []dashboardFeature
+	Errors      []recentError
+	FloodWait   time.Duration
+}
+
+type dashboardFeature struct {
+	Name    string
+	Enabled bool
+}
+
+// registerDashboard adds the dashboard to mux: GET /dashboard shows the
+// bot's state, and its forms switch maintenance mode and features.
+func (gb *GrammarBot) registerDashboard(mux *http.ServeMux) {
+	mux.HandleFunc("GET /dashboard", gb.handleDashboard)
+	mux.HandleFunc("POST /dashboard/maintenance", gb.handleDashboardMaintenance)
+	mux.HandleFunc("POST /dashboard/features", gb.handleDashboardFeatures)
+}
+
+func (gb *GrammarBot) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	page := dashboardPage{
+		internalsSnapshot: gb.internals(),
+		Maintenance:       gb.store.Maintenance(),
+		Errors:            gb.metrics.recentErrors(),
+		FloodWait:         gb.flood.remaining().Round(time.Second),
+	}
+	features := gb.features.get()
+	for _, flag := range features.flags() {
+		page.Features = append(page.Features, dashboardFeature{Name: flag.name, Enabled: *flag.enabled})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplate.Execute(w, page); err != nil {
+		log.Printf("Error rendering dashboard: %v", err)
+	}
+}
+
+func (gb *GrammarBot) handleDashboardMaintenance(w http.ResponseWriter, r *http.Request) {
+	if !sameOrigin(r) {
+		http.Error(w, "cross-origin request refused", http.StatusForbidden)
+		return
+	}
+	on := r.FormValue("on") == "true"
+	if err := gb.store.SetMaintenance(on); err != nil {
+		log.Printf("Error saving maintenance mode: %v", err)
+		http.Error(w, "couldn't save maintenance mode", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Maintenance mode turned %s from the dashboard", onOff(on))
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+// handleDashboardFeatures switches a feature until the next restart, and
+// republishes the command menu to match.
+func (gb *GrammarBot) handleDashboardFeatures(w http.ResponseWriter, r *http.Request) {
+	if !sameOrigin(r) {
+		http.Error(w, "cross-origin request refused", http.StatusForbidden)
+		return
+	}
+	name, on := r.FormValue("name"), r.FormValue("on") == "true"
+	if !gb.features.set(name, on) {
+		http.Error(w, "unknown feature", http.StatusBadRequest)
+		return
+	}
+	log.Printf("Feature %s turned %s from the dashboard", name, onOff(on))
+	go gb.registerCommands()
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+// sameOrigin reports whether a form was posted from the dashboard itself.
+// Browsers resend Basic auth credentials to any site, so without this check
+// another page could submit the forms on an operator's behalf.
+func sameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return true
+	case "":
+		origin := r.Header.Get("Origin")
+		return origin == "" || origin == "http://"+r.Host || origin == "https://"+r.Host
+	}
+	return false
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// commandDebounceWindow is how long a repeat of the same command is ignored.
+const commandDebounceWindow = time.Second
+
+type debounceKey struct {
+	userID  int64
+	command string
+	// args are the command's arguments with whitespace collapsed
+	args string
+}
+
+// debouncer drops repeats of the same command with the same arguments from
+// the same user within commandDebounceWindow, such as a double-tapped
+// /start. "/check" with two different texts is handled twice.
+type debouncer struct {
+	mu   sync.Mutex
+	seen map[debounceKey]time.Time
+}
+
+// allow records a command and reports whether it should be handled.
+func (d *debouncer) allow(userID int64, command, args string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.seen == nil {
+		d.seen = make(map[debounceKey]time.Time)
+	}
+
+	key := debounceKey{userID: userID, command: command, args: strings.Join(strings.Fields(args), " ")}
+	if last, ok := d.seen[key]; ok && now.Sub(last) < commandDebounceWindow {
+		return false
+	}
+	d.seen[key] = now
+
+	// Drop stale entries so the map doesn't grow with every user ever seen
+	if len(d.seen) > 1000 {
+		for k, t := range d.seen {
+			if now.Sub(t) >= commandDebounceWindow {
+				delete(d.seen, k)
+			}
+		}
+	}
+
+	return true
+}
+
+// size returns how many recent commands are remembered.
+func (d *debouncer) size() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.seen)
+}
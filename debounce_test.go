@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDebouncerAllow(t *testing.T) {
+	var d debouncer
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		userID  int64
+		command string
+		args    string
+		after   time.Duration
+		want    bool
+	}{
+		{"first command", 1, "check", "She go home.", 0, true},
+		{"double-sent", 1, "check", "She go home.", 100 * time.Millisecond, false},
+		{"same arguments spaced differently", 1, "check", " She  go\nhome. ", 200 * time.Millisecond, false},
+		{"other arguments", 1, "check", "They was late.", 300 * time.Millisecond, true},
+		{"other command", 1, "start", "", 300 * time.Millisecond, true},
+		{"other user", 2, "check", "She go home.", 300 * time.Millisecond, true},
+		{"after the window", 1, "check", "She go home.", commandDebounceWindow + 100*time.Millisecond, true},
+	}
+	for _, tt := range tests {
+		if got := d.allow(tt.userID, tt.command, tt.args, now.Add(tt.after)); got != tt.want {
+			t.Errorf("%s: allow() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
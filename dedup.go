@@ -0,0 +1,50 @@
+package main
+
+import "sync"
+
+// seenUpdatesWindow is how many recent update IDs are remembered.
+const seenUpdatesWindow = 1024
+
+// seenUpdates remembers recently processed update IDs so updates Telegram
+// redelivers, for example after a restart with a stale offset, are skipped.
+type seenUpdates struct {
+	mu    sync.Mutex
+	ids   map[int]struct{}
+	order [seenUpdatesWindow]int
+	next  int
+	full  bool
+}
+
+// size returns how many update IDs are remembered.
+func (s *seenUpdates) size() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.ids)
+}
+
+// add records id and reports whether it was new.
+func (s *seenUpdates) add(id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ids == nil {
+		s.ids = make(map[int]struct{}, seenUpdatesWindow)
+	}
+	if _, dup := s.ids[id]; dup {
+		return false
+	}
+
+	// Forget the oldest ID once the window is full
+	if s.full {
+		delete(s.ids, s.order[s.next])
+	}
+	s.ids[id] = struct{}{}
+	s.order[s.next] = id
+	s.next = (s.next + 1) % seenUpdatesWindow
+	if s.next == 0 {
+		s.full = true
+	}
+
+	return true
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxDeferredChecks bounds the rate-limit queue; once full, checks fail as
+// usual.
+const maxDeferredChecks = 1000
+
+// DeferredCheck is a check postponed because the AI backend was rate-limited.
+type DeferredCheck struct {
+	// Message is the message to reply to, without its reply chain.
+	Message *tgbotapi.Message `json:"message"`
+	Text    string            `json:"text"`
+	Queued  time.Time         `json:"queued"`
+}
+
+// deferIfRateLimited queues the check of text when err means the backend is
+// rate-limited and queuing is enabled, and reports whether it did.
+func (gb *GrammarBot) deferIfRateLimited(message *tgbotapi.Message, text string, err error) bool {
+	if !gb.cfg.RateLimitQueue || classifyError(err) != errorRateLimited {
+		return false
+	}
+
+	stored := *message
+	stored.ReplyToMessage = nil
+	queued, saveErr := gb.store.AddDeferredCheck(DeferredCheck{Message: &stored, Text: text, Queued: time.Now().UTC()}, maxDeferredChecks)
+	if saveErr != nil {
+		log.Printf("Error queuing check: %v", saveErr)
+		return false
+	}
+	if !queued {
+		log.Printf("Rate-limit queue is full, failing check in chat %d", message.Chat.ID)
+		return false
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, "⏳ I'm over my usage limit right now, so your message is queued. You'll get your correction shortly.")
+	msg.ReplyToMessageID = message.MessageID
+	gb.send(msg)
+	return true
+}
+
+// processDeferredChecks retries queued checks every RateLimitRetry until ctx
+// is cancelled.
+func (gb *GrammarBot) processDeferredChecks(ctx context.Context) {
+	ticker := time.NewTicker(gb.cfg.RateLimitRetry)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			gb.retryDeferredChecks()
+		}
+	}
+}
+
+// retryDeferredChecks works through the queue oldest first, dropping checks
+// older than RateLimitQueueMaxAge and stopping at the first one that is
+// still rate-limited.
+func (gb *GrammarBot) retryDeferredChecks() {
+	if gb.store.Maintenance() {
+		return
+	}
+	for _, check := range gb.store.DeferredChecks() {
+		message := check.Message
+		if time.Since(check.Queued) > gb.cfg.RateLimitQueueMaxAge {
+			gb.removeDeferredCheck(check)
+			msg := tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't check your message in time because I was over my usage limit. Please send it again.")
+			msg.ReplyToMessageID = message.MessageID
+			gb.send(msg)
+			continue
+		}
+
+		opts := gb.correctOptions(message)
+		correctedText, err := gb.checkGrammar(check.Text, opts)
+		if err != nil && classifyError(err) == errorRateLimited {
+			return
+		}
+		gb.removeDeferredCheck(check)
+		if err != nil {
+			gb.logError("Error checking queued message: %v", err)
+			msg := tgbotapi.NewMessage(message.Chat.ID, "Sorry, I encountered an error while checking your grammar. Please try again later.")
+			msg.ReplyToMessageID = message.MessageID
+			gb.send(msg)
+			continue
+		}
+		gb.replyWithCorrection(message, check.Text, opts, correctedText)
+	}
+}
+
+func (gb *GrammarBot) removeDeferredCheck(check DeferredCheck) {
+	if err := gb.store.RemoveDeferredCheck(check.Message.Chat.ID, check.Message.MessageID); err != nil {
+		log.Printf("Error removing queued check: %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// digestKey identifies a user's digest in a chat.
+type digestKey struct {
+	chatID int64
+	userID int64
+}
+
+// digestEntry is a correction held back for a digest.
+type digestEntry struct {
+	corrected string
+	opts      CorrectOptions
+}
+
+type digestBuffer struct {
+	entries []digestEntry
+	name    string
+	timer   *time.Timer
+}
+
+// digests buffers the corrections of users in digest mode until they are
+// sent together.
+type digests struct {
+	mu      sync.Mutex
+	buffers map[digestKey]*digestBuffer
+}
+
+// add buffers entry and returns the buffer's entries when it is full, for
+// sending right away. flush is called with a new buffer after interval.
+func (d *digests) add(key digestKey, name string, entry digestEntry, size int, interval time.Duration, flush func(*digestBuffer)) []digestEntry {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.buffers == nil {
+		d.buffers = make(map[digestKey]*digestBuffer)
+	}
+	buffer, ok := d.buffers[key]
+	if !ok {
+		buffer = &digestBuffer{name: name}
+		buffer.timer = time.AfterFunc(interval, func() { flush(buffer) })
+		d.buffers[key] = buffer
+	}
+	buffer.entries = append(buffer.entries, entry)
+	if len(buffer.entries) < size {
+		return nil
+	}
+	buffer.timer.Stop()
+	delete(d.buffers, key)
+	return buffer.entries
+}
+
+// take removes and returns the buffered entries of key. With a non-nil
+// only, they are only taken from that buffer, so a timer that fired late
+// doesn't send the next digest early.
+func (d *digests) take(key digestKey, only *digestBuffer) ([]digestEntry, string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	buffer, ok := d.buffers[key]
+	if !ok || only != nil && buffer != only {
+		return nil, ""
+	}
+	buffer.timer.Stop()
+	delete(d.buffers, key)
+	return buffer.entries, buffer.name
+}
+
+// keys returns the keys of all buffered digests.
+func (d *digests) keys() []digestKey {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	keys := make([]digestKey, 0, len(d.buffers))
+	for key := range d.buffers {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// addToDigest holds back a correction for the sender's digest, sending the
+// digest once it has cfg.DigestSize corrections or cfg.DigestInterval after
+// its first.
+func (gb *GrammarBot) addToDigest(message *tgbotapi.Message, opts CorrectOptions, correctedText string) {
+	key := digestKey{chatID: message.Chat.ID, userID: senderID(message)}
+	name := ""
+	if !message.Chat.IsPrivate() && message.From != nil {
+		name = message.From.FirstName
+	}
+
+	entries := gb.digests.add(key, name, digestEntry{corrected: correctedText, opts: opts}, gb.cfg.DigestSize, gb.cfg.DigestInterval, func(buffer *digestBuffer) {
+		if entries, name := gb.digests.take(key, buffer); len(entries) > 0 {
+			gb.sendDigest(key, name, entries)
+		}
+	})
+	if entries != nil {
+		gb.sendDigest(key, name, entries)
+	}
+}
+
+// flushDigest sends the digest of key, if it has any corrections.
+func (gb *GrammarBot) flushDigest(key digestKey) {
+	if entries, name := gb.digests.take(key, nil); len(entries) > 0 {
+		gb.sendDigest(key, name, entries)
+	}
+}
+
+// flushDigests sends every digest, as on shutdown.
+func (gb *GrammarBot) flushDigests() {
+	for _, key := range gb.digests.keys() {
+		gb.flushDigest(key)
+	}
+}
+
+// sendDigest sends entries as one numbered list of corrections, each
+// showing the original words next to their correction.
+func (gb *GrammarBot) sendDigest(key digestKey, name string, entries []digestEntry) {
+	style := gb.userStyle(key.userID)
+	if style == styleMinimal {
+		style = styleInline
+	}
+
+	heading := fmt.Sprintf("your last %d corrections", len(entries))
+	if len(entries) == 1 {
+		heading = "your latest correction"
+	}
+	if name != "" {
+		heading = "🗒 " + name + ", " + heading + ":"
+	} else {
+		heading = "🗒 " + capitalize(heading) + ":"
+	}
+
+	var b strings.Builder
+	b.WriteString(escapeMarkdownV2(heading))
+	for i, entry := range entries {
+		body := entry.corrected
+		if edits, err := parseInlineEdits(entry.corrected); err == nil && !entry.opts.FlagOnly {
+			body = renderEdits(edits, style)
+		}
+		fmt.Fprintf(&b, "\n\n*%d\\.* %s", i+1, body)
+	}
+
+	msg := tgbotapi.NewMessage(key.chatID, b.String())
+	msg.ParseMode = "MarkdownV2"
+	if _, err := gb.send(msg); err != nil {
+		log.Printf("Error sending digest: %v", err)
+	}
+}
+
+// handleDigestCommand toggles digest mode, or sets it with
+// "/digest on|off". Turning it off sends what is buffered right away.
+func (gb *GrammarBot) handleDigestCommand(message *tgbotapi.Message, args string) {
+	userID := senderID(message)
+	if _, ok := parseToggle(args, false); !ok {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /digest [on|off]"))
+		return
+	}
+
+	// No argument flips the stored value
+	var settings UserSettings
+	if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) {
+		stored.Digest, _ = parseToggle(args, stored.Digest)
+		settings = stored.clone()
+	}); err != nil {
+		log.Printf("Error saving settings: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	reply := "Digest mode is off. I'll reply to each message again."
+	if settings.Digest {
+		reply = fmt.Sprintf("Digest mode is on. Instead of replying to each message, I'll collect my corrections and send them together every %s, or sooner once there are %d.",
+			gb.cfg.DigestInterval, gb.cfg.DigestSize)
+	}
+	gb.replyWithSettings(message, reply)
+	if !settings.Digest {
+		gb.flushDigest(digestKey{chatID: message.Chat.ID, userID: userID})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// errFileTooLarge is returned when a download exceeds its size limit.
+var errFileTooLarge = errors.New("file is too large")
+
+// errUnsupportedContent is returned when a download isn't of an expected
+// content type.
+var errUnsupportedContent = errors.New("unsupported file content")
+
+// downloadFile fetches a file the user sent, reading at most maxBytes and
+// giving up after cfg.DownloadTimeout. Files announced as larger than
+// maxBytes aren't fetched at all, and a download that grows past it is cut
+// off. With allowed content type prefixes such as "image/", the content
+// must be of one of them; the type is sniffed from the data, since
+// Telegram serves every file as application/octet-stream.
+func (gb *GrammarBot) downloadFile(fileID string, maxBytes int64, allowed ...string) ([]byte, error) {
+	var url string
+	err := gb.outbound(func() (err error) {
+		url, err = gb.bot.GetFileDirectURL(fileID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file URL: %w", err)
+	}
+	return fetchLimited(gb.ctx, url, maxBytes, gb.cfg.DownloadTimeout, allowed)
+}
+
+// fetchLimited downloads url for downloadFile.
+func fetchLimited(ctx context.Context, url string, maxBytes int64, timeout time.Duration, allowed []string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create download request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download file: unexpected status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxBytes {
+		return nil, errFileTooLarge
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, errFileTooLarge
+	}
+
+	if len(allowed) > 0 {
+		contentType := http.DetectContentType(data)
+		if !hasAnyPrefix(contentType, allowed) {
+			return nil, fmt.Errorf("%w: %s", errUnsupportedContent, contentType)
+		}
+	}
+	return data, nil
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// largestPhoto picks the biggest photo size that fits within maxBytes.
+func largestPhoto(sizes []tgbotapi.PhotoSize, maxBytes int64) (tgbotapi.PhotoSize, bool) {
+	// Telegram lists sizes from smallest to largest
+	for i := len(sizes) - 1; i >= 0; i-- {
+		if int64(sizes[i].FileSize) <= maxBytes {
+			return sizes[i], true
+		}
+	}
+	return tgbotapi.PhotoSize{}, false
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// pngHeader is enough of a PNG file for content sniffing.
+const pngHeader = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+func serveFile(t *testing.T, handler http.HandlerFunc) string {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestFetchLimited(t *testing.T) {
+	announced := serveFile(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("a", 2048)))
+	})
+	// A chunked response doesn't announce its length up front
+	streamed := serveFile(t, func(w http.ResponseWriter, r *http.Request) {
+		for range 64 {
+			w.Write([]byte(strings.Repeat("a", 64)))
+			w.(http.Flusher).Flush()
+		}
+	})
+	image := serveFile(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(pngHeader))
+	})
+	missing := serveFile(t, http.NotFound)
+
+	if _, err := fetchLimited(context.Background(), announced, 1024, time.Second, nil); !errors.Is(err, errFileTooLarge) {
+		t.Errorf("announced oversized file: err = %v, want errFileTooLarge", err)
+	}
+	if _, err := fetchLimited(context.Background(), streamed, 1024, time.Second, nil); !errors.Is(err, errFileTooLarge) {
+		t.Errorf("streamed oversized file: err = %v, want errFileTooLarge", err)
+	}
+	if data, err := fetchLimited(context.Background(), image, 1024, time.Second, []string{"image/"}); err != nil || string(data) != pngHeader {
+		t.Errorf("image: got %d bytes, %v, want the file", len(data), err)
+	}
+	if _, err := fetchLimited(context.Background(), announced, 4096, time.Second, []string{"image/"}); !errors.Is(err, errUnsupportedContent) {
+		t.Errorf("text for an image: err = %v, want errUnsupportedContent", err)
+	}
+	if _, err := fetchLimited(context.Background(), missing, 1024, time.Second, nil); err == nil {
+		t.Error("missing file: no error")
+	}
+}
+
+// TestFetchLimitedSlowSource checks that a download that stalls is given up
+// after the timeout.
+func TestFetchLimitedSlowSource(t *testing.T) {
+	slow := serveFile(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(pngHeader))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	})
+
+	start := time.Now()
+	_, err := fetchLimited(context.Background(), slow, 1024, 100*time.Millisecond, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("gave up after %s, want soon after the timeout", elapsed)
+	}
+}
+
+func TestLargestPhoto(t *testing.T) {
+	sizes := []tgbotapi.PhotoSize{{FileID: "small", FileSize: 100}, {FileID: "medium", FileSize: 1000}, {FileID: "large", FileSize: 10000}}
+	if photo, ok := largestPhoto(sizes, 5000); !ok || photo.FileID != "medium" {
+		t.Errorf("largestPhoto() = %q, %v, want medium", photo.FileID, ok)
+	}
+	if _, ok := largestPhoto(sizes, 50); ok {
+		t.Error("largestPhoto() found a photo though all are too large")
+	}
+}
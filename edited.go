@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxTrackedReplies bounds how many corrections are remembered for
+// re-checking edited messages; the oldest are forgotten first.
+const maxTrackedReplies = 1000
+
+// trackedReplies maps messages the bot corrected to its correction replies,
+// so an edit of the message can update the reply.
+type trackedReplies struct {
+	mu      sync.Mutex
+	replies map[messageKey]int
+	order   []messageKey
+}
+
+func (t *trackedReplies) track(key messageKey, replyID int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.replies == nil {
+		t.replies = make(map[messageKey]int)
+	}
+	if _, ok := t.replies[key]; !ok {
+		t.order = append(t.order, key)
+	}
+	t.replies[key] = replyID
+	for len(t.order) > maxTrackedReplies {
+		delete(t.replies, t.order[0])
+		t.order = t.order[1:]
+	}
+}
+
+// reply returns the correction reply to the message key identifies.
+func (t *trackedReplies) reply(key messageKey) (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	replyID, ok := t.replies[key]
+	return replyID, ok
+}
+
+// editRun is the re-check of one edit of a message.
+type editRun struct {
+	cancel context.CancelFunc
+	slot   *editSlot
+}
+
+// editSlot tracks the re-checks of one message. Its send lock orders the
+// updates of the reply, so an earlier edit's update still being sent can't
+// overtake a later one.
+type editSlot struct {
+	send   sync.Mutex
+	latest *editRun
+	// runs counts the checks begun and not yet finished
+	runs int
+}
+
+// editChecks serializes re-checks of edited messages: a new edit of a
+// message cancels the check of the previous one, so only the latest edit
+// updates the reply. Edits of different messages don't wait for each other.
+type editChecks struct {
+	mu    sync.Mutex
+	slots map[messageKey]*editSlot
+}
+
+// begin starts the check of an edit of the message key identifies,
+// cancelling any check of an earlier edit, and returns its context.
+func (e *editChecks) begin(parent context.Context, key messageKey) (context.Context, *editRun) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.slots == nil {
+		e.slots = make(map[messageKey]*editSlot)
+	}
+	slot, ok := e.slots[key]
+	if !ok {
+		slot = &editSlot{}
+		e.slots[key] = slot
+	}
+	if slot.latest != nil {
+		slot.latest.cancel()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	run := &editRun{cancel: cancel, slot: slot}
+	slot.latest = run
+	slot.runs++
+	return ctx, run
+}
+
+// current reports whether no later edit superseded run.
+func (e *editChecks) current(run *editRun) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return run.slot.latest == run
+}
+
+// finish ends run, calling apply first if no later edit superseded it.
+// apply holds only the message's send lock, and is skipped if a later edit
+// began while it waited for it.
+func (e *editChecks) finish(key messageKey, run *editRun, apply func()) {
+	run.cancel()
+	if apply != nil && e.current(run) {
+		run.slot.send.Lock()
+		if e.current(run) {
+			apply()
+		}
+		run.slot.send.Unlock()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if run.slot.runs--; run.slot.runs == 0 {
+		delete(e.slots, key)
+	}
+}
+
+// handleEditedMessage re-checks an edited message the bot corrected and
+// updates the correction in place. Edits of messages the bot didn't answer,
+// or answered long enough ago to have forgotten, are left alone.
+func (gb *GrammarBot) handleEditedMessage(message *tgbotapi.Message) {
+	text := message.Text
+	if text == "" || strings.HasPrefix(text, "/") {
+		return
+	}
+	key := messageKey{message.Chat.ID, message.MessageID}
+	replyID, ok := gb.replies.reply(key)
+	if !ok {
+		return
+	}
+	if !message.Chat.IsPrivate() && gb.store.GetChatSettings(message.Chat.ID).MentionOnly {
+		text, _ = stripMentions(text, message.Entities, gb.bot.Self.UserName)
+		if !checkable(text) {
+			return
+		}
+	}
+	if scoped, ok := scopedText(text, gb.cfg.ScopeMarker); ok {
+		text = scoped
+	}
+
+	ctx, run := gb.editChecks.begin(gb.ctx, key)
+	opts := gb.correctOptions(message)
+	correctedText, err := gb.checkGrammarContext(ctx, text, opts)
+	if err != nil {
+		// Cancelled checks were superseded by a later edit or by shutdown
+		if ctx.Err() == nil {
+			gb.logError("Error re-checking edited message: %v", err)
+		}
+		gb.editChecks.finish(key, run, nil)
+		return
+	}
+
+	userID := senderID(message)
+	gb.editChecks.finish(key, run, func() {
+		edit := tgbotapi.NewEditMessageText(message.Chat.ID, replyID, renderCorrection(correctedText, opts, gb.userStyle(userID), gb.cfg.MaxHighlights))
+		edit.ParseMode = "MarkdownV2"
+		if keyboard := gb.correctionKeyboard(message.Chat, userID, opts.Language, feedbackVariant(gb.resolveModel(text, opts)), text, correctedText); keyboard != nil {
+			edit.ReplyMarkup = keyboard
+		}
+		if _, err := gb.send(edit); err != nil && !strings.Contains(err.Error(), "message is not modified") {
+			log.Printf("Error updating correction: %v", err)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestQuickEditsUpdateWithLatest edits a message twice in quick succession
+// and checks that only the second edit updates the correction.
+func TestQuickEditsUpdateWithLatest(t *testing.T) {
+	started := make(chan string, 2)
+	gb, tg := newTestBot(t, testConfig(t, nil), &fakeEngine{correct: func(ctx context.Context, text string, opts CorrectOptions) (string, error) {
+		started <- text
+		if strings.Contains(text, "first") {
+			<-ctx.Done()
+			return "", ctx.Err()
+		}
+		return text, nil
+	}})
+	gb.replies.track(messageKey{7, 1}, 100)
+
+	done := make(chan struct{})
+	go func() {
+		gb.handleEditedMessage(privateMessage(7, "This is the first edit."))
+		close(done)
+	}()
+	<-started
+	gb.handleEditedMessage(privateMessage(7, "This is the second edit."))
+	<-done
+
+	edits := tg.callsTo("editMessageText")
+	if len(edits) != 1 {
+		t.Fatalf("correction updated %d times, want once", len(edits))
+	}
+	if text := edits[0].Get("text"); !strings.Contains(text, "second") {
+		t.Errorf("correction updated to %q, want the second edit", text)
+	}
+}
+
+// TestEditUpdateHoldsOnlyItsMessage keeps the update of one correction
+// hanging and checks that corrections of other messages are still updated,
+// while a later edit of the same message waits and is applied last.
+func TestEditUpdateHoldsOnlyItsMessage(t *testing.T) {
+	gb, tg := newTestBot(t, testConfig(t, nil), nil)
+	release := make(chan struct{})
+	tg.mu.Lock()
+	tg.fail = map[string]func(url.Values) (int, string, int){
+		"editMessageText": func(params url.Values) (int, string, int) {
+			if strings.Contains(params.Get("text"), "first") {
+				<-release
+			}
+			return 0, "", 0
+		},
+	}
+	tg.mu.Unlock()
+	gb.replies.track(messageKey{7, 1}, 100)
+	gb.replies.track(messageKey{8, 1}, 200)
+
+	first := make(chan struct{})
+	go func() {
+		gb.handleEditedMessage(privateMessage(7, "This is the first edit."))
+		close(first)
+	}()
+	waitFor(t, "the first update to be sent", func() bool { return len(tg.callsTo("editMessageText")) == 1 })
+
+	other := make(chan struct{})
+	go func() {
+		gb.handleEditedMessage(privateMessage(8, "This is another message."))
+		close(other)
+	}()
+	select {
+	case <-other:
+	case <-time.After(2 * time.Second):
+		t.Fatal("update of another message waited for the hanging one")
+	}
+
+	second := make(chan struct{})
+	go func() {
+		gb.handleEditedMessage(privateMessage(7, "This is the second edit."))
+		close(second)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	<-first
+	<-second
+
+	var updates []string
+	for _, params := range tg.callsTo("editMessageText") {
+		if params.Get("chat_id") == "7" {
+			updates = append(updates, params.Get("text"))
+		}
+	}
+	if len(updates) != 2 || !strings.Contains(updates[1], "second") {
+		t.Errorf("correction in chat 7 updated to %q, want the second edit last", updates)
+	}
+}
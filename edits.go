@@ -0,0 +1,252 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/rivo/uniseg"
+)
+
+// Edit is one span of a correction. Unchanged text has Original equal to
+// Corrected; a deletion has an empty Corrected and an insertion an empty
+// Original.
+type Edit struct {
+	Original  string
+	Corrected string
+}
+
+// Changed reports whether the edit modifies the text.
+func (e Edit) Changed() bool {
+	return e.Original != e.Corrected
+}
+
+var errUnbalancedMarkup = errors.New("unbalanced strikethrough or bold markup")
+
+type segmentKind int
+
+const (
+	segmentKeep segmentKind = iota
+	segmentDelete
+	segmentInsert
+)
+
+type segment struct {
+	kind segmentKind
+	text string
+}
+
+// parseInlineEdits turns a MarkdownV2 correction that marks mistakes with
+// ~strikethrough~ and corrections with *bold* (or **bold**) into edits with
+// all escaping removed.
+func parseInlineEdits(formatted string) ([]Edit, error) {
+	var (
+		segments []segment
+		buf      strings.Builder
+		kind     = segmentKeep
+	)
+
+	flush := func() {
+		if buf.Len() > 0 {
+			segments = append(segments, segment{kind: kind, text: buf.String()})
+			buf.Reset()
+		}
+	}
+
+	runes := []rune(formatted)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+				buf.WriteRune(runes[i])
+			}
+		case '~':
+			flush()
+			switch kind {
+			case segmentKeep:
+				kind = segmentDelete
+			case segmentDelete:
+				kind = segmentKeep
+			default:
+				return nil, errUnbalancedMarkup
+			}
+		case '*':
+			if i+1 < len(runes) && runes[i+1] == '*' {
+				i++
+			}
+			flush()
+			switch kind {
+			case segmentKeep:
+				kind = segmentInsert
+			case segmentInsert:
+				kind = segmentKeep
+			default:
+				return nil, errUnbalancedMarkup
+			}
+		case '_':
+			// Italic and underline markers carry no edit information
+		case '|':
+			// Neither do spoiler markers; a lone | is kept as text
+			if i+1 < len(runes) && runes[i+1] == '|' {
+				i++
+			} else {
+				buf.WriteRune(r)
+			}
+		case '!', '[':
+			// Links and custom emoji, ![👍](tg://emoji?id=...), keep only
+			// their text, so a custom emoji falls back to its plain emoji
+			if r == '!' && (i+1 >= len(runes) || runes[i+1] != '[') {
+				buf.WriteRune(r)
+			}
+		case ']':
+			if i+1 < len(runes) && runes[i+1] == '(' {
+				i = skipLinkTarget(runes, i+2)
+			} else {
+				buf.WriteRune(r)
+			}
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	if kind != segmentKeep {
+		return nil, errUnbalancedMarkup
+	}
+	flush()
+
+	return alignToGraphemes(foldSegments(segments)), nil
+}
+
+// skipLinkTarget returns the index of the ')' closing a link target that
+// starts at from, or the last index if it is unclosed.
+func skipLinkTarget(runes []rune, from int) int {
+	for i := from; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			i++
+		case ')':
+			return i
+		}
+	}
+	return len(runes) - 1
+}
+
+// foldSegments merges a deletion directly followed by an insertion into a
+// single replacement, dropping the separator the model puts between them.
+func foldSegments(segments []segment) []Edit {
+	var edits []Edit
+	for i := 0; i < len(segments); i++ {
+		seg := segments[i]
+		switch seg.kind {
+		case segmentKeep:
+			edits = append(edits, Edit{Original: seg.text, Corrected: seg.text})
+		case segmentInsert:
+			edits = append(edits, Edit{Corrected: seg.text})
+		case segmentDelete:
+			next := i + 1
+			if next < len(segments) && segments[next].kind == segmentKeep && strings.TrimSpace(segments[next].text) == "" {
+				next++
+			}
+			if next < len(segments) && segments[next].kind == segmentInsert {
+				edits = append(edits, Edit{Original: seg.text, Corrected: segments[next].text})
+				i = next
+				continue
+			}
+			edits = append(edits, Edit{Original: seg.text})
+		}
+	}
+	return edits
+}
+
+// alignToGraphemes merges neighbouring edits whose boundary would split a
+// grapheme cluster, such as a letter and its combining accent or the parts of
+// an emoji ZWJ sequence, so wrapping an edit in markup never tears one apart.
+func alignToGraphemes(edits []Edit) []Edit {
+	if len(edits) < 2 {
+		return edits
+	}
+
+	originalBounds := graphemeBoundaries(originalText(edits))
+	correctedBounds := graphemeBoundaries(concatCorrected(edits))
+
+	aligned := []Edit{edits[0]}
+	originalOffset, correctedOffset := len(edits[0].Original), len(edits[0].Corrected)
+	for _, e := range edits[1:] {
+		if originalBounds[originalOffset] && correctedBounds[correctedOffset] {
+			aligned = append(aligned, e)
+		} else {
+			last := &aligned[len(aligned)-1]
+			last.Original += e.Original
+			last.Corrected += e.Corrected
+		}
+		originalOffset += len(e.Original)
+		correctedOffset += len(e.Corrected)
+	}
+	return aligned
+}
+
+// graphemeBoundaries returns the byte offsets of text that lie between two
+// grapheme clusters, including its start and end.
+func graphemeBoundaries(text string) map[int]bool {
+	bounds := map[int]bool{0: true}
+	g := uniseg.NewGraphemes(text)
+	for g.Next() {
+		_, end := g.Positions()
+		bounds[end] = true
+	}
+	return bounds
+}
+
+func concatCorrected(edits []Edit) string {
+	var b strings.Builder
+	for _, e := range edits {
+		b.WriteString(e.Corrected)
+	}
+	return b.String()
+}
+
+// correctedText returns the text with every edit applied.
+func correctedText(edits []Edit) string {
+	var b strings.Builder
+	dropSpace := false
+	for _, e := range edits {
+		text := e.Corrected
+		if dropSpace {
+			text = strings.TrimPrefix(text, " ")
+		}
+		// A deleted word leaves its surrounding spaces behind; keep just one
+		dropSpace = e.Original != "" && e.Corrected == "" && strings.HasSuffix(b.String(), " ")
+		b.WriteString(text)
+	}
+	return b.String()
+}
+
+// originalText returns the text before any edit was applied.
+func originalText(edits []Edit) string {
+	var b strings.Builder
+	for _, e := range edits {
+		b.WriteString(e.Original)
+	}
+	return b.String()
+}
+
+// hasCorrections reports whether the model's answer changes or flags
+// anything in original. Markup and escapes are removed before comparing, so
+// an answer that merely escapes the input's special characters counts as
+// unchanged, while one that rewrote the text without marking it does not.
+func hasCorrections(original, correctedMarkup string) bool {
+	plain := stripMarkdownV2(correctedMarkup)
+	if edits, err := parseInlineEdits(correctedMarkup); err == nil {
+		for _, e := range edits {
+			if e.Changed() {
+				return true
+			}
+		}
+		plain = originalText(edits)
+	}
+	return normalizeSpace(plain) != normalizeSpace(original)
+}
+
+// normalizeSpace collapses runs of whitespace and trims the ends.
+func normalizeSpace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
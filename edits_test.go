@@ -0,0 +1,95 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseInlineEditsGraphemes(t *testing.T) {
+	tests := []struct {
+		name      string
+		formatted string
+		want      []Edit
+	}{
+		{"accent in a corrected span", "The ~cafe~ *café* is open", []Edit{
+			{"The ", "The "}, {"cafe", "café"}, {" is open", " is open"},
+		}},
+		{"accent in a struck span", "~naïve~ *naive* idea", []Edit{
+			{"naïve", "naive"}, {" idea", " idea"},
+		}},
+		{"emoji next to a corrected span", "I ~luv~ *love* 😀 it", []Edit{
+			{"I ", "I "}, {"luv", "love"}, {" 😀 it", " 😀 it"},
+		}},
+		{"struck combining accent", "I like the cafe~\u0301~ here", []Edit{
+			{"I like the cafe\u0301", "I like the cafe"}, {" here", " here"},
+		}},
+		{"struck part of a ZWJ sequence", "Look 👩~\u200d💻~ now", []Edit{
+			{"Look 👩\u200d💻", "Look 👩"}, {" now", " now"},
+		}},
+		{"struck skin tone modifier", "Hi 👍~🏽~ ok", []Edit{
+			{"Hi 👍🏽", "Hi 👍"}, {" ok", " ok"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseInlineEdits(tt.formatted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseInlineEdits(%q) = %q, want %q", tt.formatted, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAlignToGraphemesKeepsClusters checks that no edit boundary falls
+// inside a grapheme cluster of the original or the corrected text.
+func TestAlignToGraphemesKeepsClusters(t *testing.T) {
+	edits := alignToGraphemes([]Edit{
+		{"Great ", "Great "},
+		{"👨\u200d👩", "👨\u200d👩"},
+		{"\u200d👧", ""},
+		{" and e", " and e"},
+		{"\u0301", "\u0300"},
+		{"!", "!"},
+	})
+
+	originalBounds := graphemeBoundaries(originalText(edits))
+	correctedBounds := graphemeBoundaries(concatCorrected(edits))
+	originalOffset, correctedOffset := 0, 0
+	for _, e := range edits {
+		originalOffset += len(e.Original)
+		correctedOffset += len(e.Corrected)
+		if !originalBounds[originalOffset] || !correctedBounds[correctedOffset] {
+			t.Errorf("edit %q ends inside a grapheme cluster", e)
+		}
+	}
+	want := []Edit{{"Great ", "Great "}, {"👨\u200d👩\u200d👧", "👨\u200d👩"}, {" and e\u0301", " and e\u0300"}, {"!", "!"}}
+	if !reflect.DeepEqual(edits, want) {
+		t.Errorf("alignToGraphemes() = %q, want %q", edits, want)
+	}
+}
+
+func TestHasCorrectionsEscapeOnly(t *testing.T) {
+	tests := []struct {
+		original, corrected string
+		want                bool
+	}{
+		{"She goes home.", "She goes home\\.", false},
+		{"Costs 5-10$ (approx.)!", "Costs 5\\-10$ \\(approx\\.\\)\\!", false},
+		{"a_b*c [d] ~e~ #f +g =h |i {j} >k", "a\\_b\\*c \\[d\\] \\~e\\~ \\#f \\+g \\=h \\|i \\{j\\} \\>k", false},
+		{"Path C:\\temp", "Path C:\\\\temp", false},
+		{"She goes  home.", "She goes home\\.", false},
+		{"She go home.", "She ~go~ **goes** home\\.", true},
+		{"She go home.", "She ~go~ *goes* home\\.", true},
+		{"Costs 5-10$.", "Costs 5\\-10€\\.", true},
+		{"She go home.", "She goes home\\.", true},
+	}
+	for _, tt := range tests {
+		if got := hasCorrections(tt.original, tt.corrected); got != tt.want {
+			t.Errorf("hasCorrections(%q, %q) = %v, want %v", tt.original, tt.corrected, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// Special explanation languages accepted by /explainlang.
+const (
+	// explainSame explains in the correction language (the default).
+	explainSame = "same"
+	// explainNative explains in the language of the user's Telegram app.
+	explainNative = "native"
+)
+
+// telegramLanguageName turns a Telegram language code like "de" into an
+// English language name like "German".
+func telegramLanguageName(code string) (string, bool) {
+	tag, err := language.Parse(code)
+	if err != nil {
+		return "", false
+	}
+	base, _ := tag.Base()
+	name := display.English.Languages().Name(base)
+	return name, name != ""
+}
+
+// explanationLanguage resolves the language explanations are written in.
+func explanationLanguage(settings UserSettings, user *tgbotapi.User) string {
+	switch settings.ExplainLanguage {
+	case "", explainSame:
+		return effectiveLanguage(settings)
+	case explainNative:
+		if user != nil {
+			if name, ok := telegramLanguageName(user.LanguageCode); ok {
+				return name
+			}
+		}
+		return effectiveLanguage(settings)
+	default:
+		return settings.ExplainLanguage
+	}
+}
+
+// handleExplainCommand toggles explanation mode, or sets it with "/explain on|off".
+func (gb *GrammarBot) handleExplainCommand(message *tgbotapi.Message, args string) {
+	userID := senderID(message)
+	if _, ok := parseToggle(args, false); !ok {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /explain [on|off]"))
+		return
+	}
+
+	// No argument flips the stored value
+	var settings UserSettings
+	if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) {
+		stored.Explain, _ = parseToggle(args, stored.Explain)
+		settings = stored.clone()
+	}); err != nil {
+		log.Printf("Error saving settings: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	reply := "Explanations are off."
+	if settings.Explain {
+		reply = fmt.Sprintf("Explanations are on. I'll explain each correction in %s. Use /explainlang to change that language.", explanationLanguage(settings, message.From))
+	}
+	gb.replyWithSettings(message, reply)
+}
+
+// handleExplainLangCommand sets the language explanations are written in,
+// independently of the correction language.
+func (gb *GrammarBot) handleExplainLangCommand(message *tgbotapi.Message, arg string) {
+	userID := senderID(message)
+	settings := gb.store.GetUserSettings(userID)
+
+	var explainLanguage string
+	switch strings.ToLower(arg) {
+	case "":
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Explanations are written in %s. Use /explainlang <language>, /explainlang native for your Telegram language, or /explainlang same to match the correction language.", explanationLanguage(settings, message.From))))
+		return
+	case explainSame, explainNative:
+		explainLanguage = strings.ToLower(arg)
+	default:
+		name, ok := normalizeLanguage(arg)
+		if !ok {
+			gb.send(tgbotapi.NewMessage(message.Chat.ID, "Please give a language name like German, or native, or same."))
+			return
+		}
+		explainLanguage = name
+	}
+
+	// The reply names the resulting language, which may depend on the
+	// stored correction language
+	if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) {
+		stored.ExplainLanguage = explainLanguage
+		settings = stored.clone()
+	}); err != nil {
+		log.Printf("Error saving settings: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	gb.replyWithSettings(message, fmt.Sprintf("Explanations will be written in %s.", explanationLanguage(settings, message.From)))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxExportBytes caps the size of an /export file. Older history entries are
+// dropped until the export fits.
+const maxExportBytes = 1 << 20
+
+// userExport is the document /export sends.
+type userExport struct {
+	UserID     int64          `json:"user_id"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Settings   *UserSettings  `json:"settings,omitempty"`
+	History    []HistoryEntry `json:"history"`
+	// Truncated reports that older history was left out to respect the size cap.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// buildExport encodes the user's data as indented JSON of at most maxExportBytes.
+func buildExport(export userExport) ([]byte, error) {
+	for {
+		data, err := json.MarshalIndent(export, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode export: %w", err)
+		}
+		if len(data) <= maxExportBytes || len(export.History) == 0 {
+			return data, nil
+		}
+
+		// Drop the oldest quarter of the history and try again
+		drop := (len(export.History) + 3) / 4
+		export.History = export.History[drop:]
+		export.Truncated = true
+	}
+}
+
+// handleExportCommand sends the user's stored settings and history as a file.
+func (gb *GrammarBot) handleExportCommand(message *tgbotapi.Message, args string) {
+	userID := senderID(message)
+	settings, hasSettings := gb.store.LookupUserSettings(userID)
+	history := gb.store.History(userID)
+
+	if !hasSettings && len(history) == 0 {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "I don't have any data stored about you."))
+		return
+	}
+
+	export := userExport{
+		UserID:     userID,
+		ExportedAt: time.Now().UTC(),
+		History:    history,
+	}
+	if hasSettings {
+		export.Settings = &settings
+	}
+	if export.History == nil {
+		export.History = []HistoryEntry{}
+	}
+
+	data, err := buildExport(export)
+	if err != nil {
+		log.Printf("Error building export: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't prepare your export. Please try again later."))
+		return
+	}
+
+	doc := tgbotapi.NewDocument(message.Chat.ID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("grammar-bot-export-%d.json", userID),
+		Bytes: data,
+	})
+	doc.Caption = "Here is everything I have stored about you."
+	if _, err := gb.send(doc); err != nil {
+		log.Printf("Error sending export: %v", err)
+	}
+}
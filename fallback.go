@@ -0,0 +1,117 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// commonTypos maps frequent misspellings to their correction.
+var commonTypos = map[string]string{
+	"teh":         "the",
+	"adn":         "and",
+	"alot":        "a lot",
+	"recieve":     "receive",
+	"definately":  "definitely",
+	"seperate":    "separate",
+	"occured":     "occurred",
+	"untill":      "until",
+	"wich":        "which",
+	"becuase":     "because",
+	"thier":       "their",
+	"tommorow":    "tomorrow",
+	"accomodate":  "accommodate",
+	"goverment":   "government",
+	"beleive":     "believe",
+	"wierd":       "weird",
+	"freind":      "friend",
+	"truely":      "truly",
+	"neccessary":  "necessary",
+	"occassion":   "occasion",
+	"existance":   "existence",
+	"independant": "independent",
+}
+
+var (
+	multiSpaceRe = regexp.MustCompile(` {2,}`)
+	// Combining marks belong to the word so accents stay with their letter
+	basicWordRe = regexp.MustCompile(`[\p{L}\p{M}\p{N}']+`)
+)
+
+// basicCorrect applies a few rule-based fixes to text: it collapses repeated
+// spaces, capitalizes sentence starts, fixes common typos and strikes through
+// repeated words. It returns the result formatted as MarkdownV2 with the same
+// strikethrough/bold convention as AI corrections, and whether anything changed.
+func basicCorrect(text string) (string, bool) {
+	collapsed := multiSpaceRe.ReplaceAllString(text, " ")
+	changed := collapsed != text
+	text = collapsed
+
+	var b strings.Builder
+	sentenceStart := true
+	prevWord := ""
+	last := 0
+
+	for _, loc := range basicWordRe.FindAllStringIndex(text, -1) {
+		gap := text[last:loc[0]]
+		word := text[loc[0]:loc[1]]
+		last = loc[1]
+
+		b.WriteString(escapeMarkdownV2(gap))
+		if strings.ContainsAny(gap, ".!?") {
+			sentenceStart = true
+		}
+		if strings.TrimSpace(gap) != "" {
+			prevWord = ""
+		}
+
+		// Repeated word: "the the" -> "the ~the~"
+		if prevWord != "" && strings.EqualFold(prevWord, word) {
+			b.WriteString("~" + escapeMarkdownV2(word) + "~")
+			changed = true
+			continue
+		}
+
+		fixed := word
+		if typo, ok := commonTypos[strings.ToLower(word)]; ok {
+			fixed = matchCase(word, typo)
+		}
+		if sentenceStart {
+			fixed = capitalize(fixed)
+		}
+
+		if fixed != word {
+			b.WriteString("~" + escapeMarkdownV2(word) + "~ *" + escapeMarkdownV2(fixed) + "*")
+			changed = true
+		} else {
+			b.WriteString(escapeMarkdownV2(word))
+		}
+
+		sentenceStart = false
+		prevWord = word
+	}
+	b.WriteString(escapeMarkdownV2(text[last:]))
+
+	return b.String(), changed
+}
+
+// matchCase returns replacement capitalized like original ("Teh" -> "The").
+func matchCase(original, replacement string) string {
+	if strings.ToUpper(original) == original && len(original) > 1 {
+		return strings.ToUpper(replacement)
+	}
+	if r, _ := utf8.DecodeRuneInString(original); unicode.IsUpper(r) {
+		return capitalize(replacement)
+	}
+	return replacement
+}
+
+// capitalize upper-cases the first letter of word.
+func capitalize(word string) string {
+	r, size := utf8.DecodeRuneInString(word)
+	if r == utf8.RuneError || !unicode.IsLower(r) {
+		return word
+	}
+	return string(unicode.ToUpper(r)) + word[size:]
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBasicCorrect(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		changed bool
+	}{
+		{"no mistakes", "She went home.", `She went home\.`, false},
+		{"double spaces", "She  went   home", "She went home", true},
+		{"sentence starts", "she went home. they stayed", `~she~ *She* went home\. ~they~ *They* stayed`, true},
+		{"typo", "I saw teh cat", "I saw ~teh~ *the* cat", true},
+		{"typo capitalized", "Teh cat", "~Teh~ *The* cat", true},
+		{"typo in capitals", "TEH CAT", "~TEH~ *THE* CAT", true},
+		{"typo into two words", "Alot of people", "~Alot~ *A lot* of people", true},
+		{"repeated word", "I saw the the cat", "I saw the ~the~ cat", true},
+		{"repeated word across case", "The the cat", "The ~the~ cat", true},
+		{"repeat across punctuation", "I saw the. The cat", `I saw the\. The cat`, false},
+		{"repeat across emoji", "I saw the 😀 the cat", "I saw the 😀 the cat", false},
+		{"accented words", "Ich  bin müde", "Ich bin müde", true},
+		{"accented repeat", "Visit the café café", "Visit the café ~café~", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := basicCorrect(tt.text)
+			if got != tt.want || changed != tt.changed {
+				t.Errorf("basicCorrect(%q) = %q, %v, want %q, %v", tt.text, got, changed, tt.want, tt.changed)
+			}
+		})
+	}
+}
+
+func TestMatchCase(t *testing.T) {
+	tests := []struct{ original, replacement, want string }{
+		{"teh", "the", "the"},
+		{"Teh", "the", "The"},
+		{"TEH", "the", "THE"},
+		{"I", "i", "I"},
+	}
+	for _, tt := range tests {
+		if got := matchCase(tt.original, tt.replacement); got != tt.want {
+			t.Errorf("matchCase(%q, %q) = %q, want %q", tt.original, tt.replacement, got, tt.want)
+		}
+	}
+}
+
+// TestOfflineFallbackWhenEngineFails checks that with OFFLINE_FALLBACK on a
+// failed check is answered with labelled basic corrections.
+func TestOfflineFallbackWhenEngineFails(t *testing.T) {
+	cfg := testConfig(t, map[string]string{"OFFLINE_FALLBACK": "true", "RETRY_MAX_ATTEMPTS": "1"})
+	gb, tg := newTestBot(t, cfg, &fakeEngine{correct: func(ctx context.Context, text string, opts CorrectOptions) (string, error) {
+		return "", errors.New("backend unavailable")
+	}})
+
+	gb.handleMessage(privateMessage(7, "i saw teh cat today"))
+
+	waitFor(t, "the reply", func() bool { return len(tg.callsTo("sendMessage")) > 0 })
+	text := tg.callsTo("sendMessage")[0].Get("text")
+	if !strings.Contains(text, "basic offline corrections") || !strings.Contains(text, "~teh~ *the*") {
+		t.Errorf("reply = %q, want labelled basic corrections", text)
+	}
+}
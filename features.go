@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Features are the optional features of a deployment. All are on unless
+// listed in DISABLED_FEATURES; a feature that is off behaves as if it didn't
+// exist, commands included.
+type Features struct {
+	// Inline answers inline queries.
+	Inline bool
+	// Photos checks the text in screenshots.
+	Photos bool
+	// Practice offers exercises with /practice.
+	Practice bool
+	// Reports offers formal writing reports with /report.
+	Reports bool
+	// Polls offers checking polls with /polls.
+	Polls bool
+	// Streaks counts daily check streaks for /streak.
+	Streaks bool
+}
+
+// flags lists the features by the name DISABLED_FEATURES uses for them.
+func (f *Features) flags() []featureFlag {
+	return []featureFlag{
+		{"inline", &f.Inline},
+		{"photos", &f.Photos},
+		{"practice", &f.Practice},
+		{"reports", &f.Reports},
+		{"polls", &f.Polls},
+		{"streaks", &f.Streaks},
+	}
+}
+
+type featureFlag struct {
+	name    string
+	enabled *bool
+}
+
+// loadFeatures turns on every feature except those in the comma-separated
+// DISABLED_FEATURES.
+func loadFeatures() (Features, error) {
+	var features Features
+	flags := features.flags()
+	for _, flag := range flags {
+		*flag.enabled = true
+	}
+
+	for _, name := range strings.Split(os.Getenv("DISABLED_FEATURES"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, flag := range flags {
+			if flag.name == name {
+				*flag.enabled = false
+				found = true
+			}
+		}
+		if !found {
+			return features, fmt.Errorf("unknown DISABLED_FEATURES entry %q", name)
+		}
+	}
+	return features, nil
+}
+
+// featureSwitches holds the features currently on. They start as
+// configured and can be switched from the dashboard until the next restart.
+type featureSwitches struct {
+	mu      sync.RWMutex
+	current Features
+}
+
+func (s *featureSwitches) get() Features {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.current
+}
+
+// enabled reports whether the feature called name is on. The empty name,
+// which belongs to no feature, is always on.
+func (s *featureSwitches) enabled(name string) bool {
+	if name == "" {
+		return true
+	}
+	features := s.get()
+	for _, flag := range features.flags() {
+		if flag.name == name {
+			return *flag.enabled
+		}
+	}
+	return false
+}
+
+// set turns the feature called name on or off, reporting false for an
+// unknown name.
+func (s *featureSwitches) set(name string, on bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, flag := range s.current.flags() {
+		if flag.name == name {
+			*flag.enabled = on
+			return true
+		}
+	}
+	return false
+}
+
+// handleFeaturesCommand shows admins which optional features are on.
+func (gb *GrammarBot) handleFeaturesCommand(message *tgbotapi.Message, args string) {
+	features := gb.features.get()
+	var b strings.Builder
+	b.WriteString("🧩 Features")
+	for _, flag := range features.flags() {
+		state := "✅ on"
+		if !*flag.enabled {
+			state = "❌ off"
+		}
+		fmt.Fprintf(&b, "\n%s: %s", flag.name, state)
+	}
+	b.WriteString("\n\nTurn features off with DISABLED_FEATURES.")
+	gb.send(tgbotapi.NewMessage(message.Chat.ID, b.String()))
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// feedbackCallbackPrefix marks the rating buttons under a correction. The
+// data continues with the vote, "+" or "-" or a number of stars from 1 to 5,
+// and the variant the correction came from.
+const feedbackCallbackPrefix = "feedback:"
+
+// maxCallbackData is the most bytes of data Telegram allows on a button.
+const maxCallbackData = 64
+
+// feedbackVariant names the model and prompt version a correction was made
+// with, so ratings can be compared across them.
+func feedbackVariant(model string) string {
+	return model + "@" + promptVersion
+}
+
+// resolveModel returns the model a check of text with opts runs on.
+func (gb *GrammarBot) resolveModel(text string, opts CorrectOptions) string {
+	if opts.Model != "" {
+		return opts.Model
+	}
+	return gb.selectModel(text)
+}
+
+// feedbackButtons returns the row of rating buttons for a correction, 👍 and
+// 👎 or 1 to 5 stars, or nil when the variant doesn't fit in the button data.
+func feedbackButtons(variant string, stars bool) []tgbotapi.InlineKeyboardButton {
+	if len(feedbackCallbackPrefix+"+:"+variant) > maxCallbackData {
+		return nil
+	}
+	if stars {
+		var row []tgbotapi.InlineKeyboardButton
+		for n := 1; n <= 5; n++ {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d⭐", n), fmt.Sprintf("%s%d:%s", feedbackCallbackPrefix, n, variant)))
+		}
+		return row
+	}
+	return tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("👍 Helpful", feedbackCallbackPrefix+"+:"+variant),
+		tgbotapi.NewInlineKeyboardButtonData("👎 Not helpful", feedbackCallbackPrefix+"-:"+variant),
+	)
+}
+
+// correctionKeyboard combines the re-check, use-correction and rating
+// buttons of the correction of text in chat. Chats with a language lock get
+// no re-check buttons.
+// It returns nil when there is nothing to offer.
+func (gb *GrammarBot) correctionKeyboard(chat *tgbotapi.Chat, userID int64, language, variant, text, correctedMarkup string) *tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if gb.chatLanguage(chat) == "" {
+		if keyboard := gb.languageKeyboard(userID, language); keyboard != nil {
+			rows = keyboard.InlineKeyboard
+		}
+	}
+	if row := gb.useCorrectionButton(text, correctedMarkup); row != nil {
+		rows = append(rows, row)
+	}
+	if row := feedbackButtons(variant, gb.store.GetUserSettings(userID).StarRatings); row != nil {
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return &tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// recordSent counts a correction offered for rating, if keyboard has the
+// rating buttons.
+func (gb *GrammarBot) recordSent(keyboard *tgbotapi.InlineKeyboardMarkup, variant string) {
+	if keyboard == nil || !hasFeedbackButtons(keyboard.InlineKeyboard) {
+		return
+	}
+	if err := gb.store.RecordSent(variant); err != nil {
+		log.Printf("Error saving feedback: %v", err)
+	}
+}
+
+func hasFeedbackButtons(rows [][]tgbotapi.InlineKeyboardButton) bool {
+	for _, row := range rows {
+		for _, button := range row {
+			if button.CallbackData != nil && strings.HasPrefix(*button.CallbackData, feedbackCallbackPrefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// handleFeedbackCallback records a rating of a correction and removes the
+// rating buttons, so each correction is rated once. Taps that arrive before
+// the buttons are gone are ignored.
+func (gb *GrammarBot) handleFeedbackCallback(query *tgbotapi.CallbackQuery, data string) {
+	vote, variant, ok := strings.Cut(data, ":")
+	stars, starErr := strconv.Atoi(vote)
+	if !ok || variant == "" || (vote != "+" && vote != "-" && (starErr != nil || stars < 1 || stars > 5)) {
+		gb.answerCallback(tgbotapi.NewCallback(query.ID, ""))
+		return
+	}
+
+	message := query.Message
+	if original := message.ReplyToMessage; original != nil && senderID(original) != query.From.ID {
+		gb.answerCallback(tgbotapi.NewCallback(query.ID, "Only the author of the message can rate this correction."))
+		return
+	}
+	rated := fmt.Sprintf("rate:%d:%d", message.Chat.ID, message.MessageID)
+	if message.ReplyMarkup == nil || !hasFeedbackButtons(message.ReplyMarkup.InlineKeyboard) || !gb.debounce.allow(query.From.ID, rated, "", time.Now()) {
+		gb.answerCallback(tgbotapi.NewCallback(query.ID, "You've already rated this correction."))
+		return
+	}
+
+	var err error
+	if starErr == nil {
+		err = gb.store.RecordStars(variant, stars)
+	} else {
+		err = gb.store.RecordFeedback(variant, vote == "+")
+	}
+	if err != nil {
+		log.Printf("Error saving feedback: %v", err)
+		gb.answerCallback(tgbotapi.NewCallback(query.ID, "Sorry, I couldn't save your rating. Please try again later."))
+		return
+	}
+	gb.answerCallback(tgbotapi.NewCallback(query.ID, "Thanks for the feedback!"))
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if message.ReplyMarkup != nil {
+		for _, row := range message.ReplyMarkup.InlineKeyboard {
+			if !hasFeedbackButtons([][]tgbotapi.InlineKeyboardButton{row}) {
+				rows = append(rows, row)
+			}
+		}
+	}
+	if rows == nil {
+		rows = [][]tgbotapi.InlineKeyboardButton{}
+	}
+	edit := tgbotapi.NewEditMessageReplyMarkup(message.Chat.ID, message.MessageID, tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows})
+	if _, err := gb.request(edit); err != nil {
+		log.Printf("Error removing rating buttons: %v", err)
+	}
+}
+
+// handleStatsCommand shows the acceptance rate of corrections by model and
+// prompt version with "/stats global".
+func (gb *GrammarBot) handleStatsCommand(message *tgbotapi.Message, args string) {
+	if !strings.EqualFold(args, "global") {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /stats global"))
+		return
+	}
+
+	feedback := gb.store.Feedback()
+	if len(feedback) == 0 {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "No corrections have been offered for rating yet."))
+		return
+	}
+
+	variants := make([]string, 0, len(feedback))
+	for variant := range feedback {
+		variants = append(variants, variant)
+	}
+	sort.Strings(variants)
+
+	var b strings.Builder
+	b.WriteString("📈 Correction feedback")
+	for _, variant := range variants {
+		tally := feedback[variant]
+		model, version, _ := strings.Cut(variant, "@")
+
+		rated := tally.Accepted + tally.Rejected + tally.StarRatings
+		rate := "no ratings"
+		if votes := tally.Accepted + tally.Rejected; votes > 0 {
+			rate = fmt.Sprintf("%.0f%% accepted", float64(tally.Accepted)/float64(votes)*100)
+		}
+		fmt.Fprintf(&b, "\n\n%s, prompt %s (since %s)\n%s: %d accepted, %d rejected, %d unrated of %d sent",
+			model, version, tally.Since.Format("2006-01-02"),
+			rate, tally.Accepted, tally.Rejected, max(tally.Sent-rated, 0), tally.Sent)
+		if tally.StarRatings > 0 {
+			fmt.Fprintf(&b, "\nAverage %.1f⭐ from %d star ratings", float64(tally.Stars)/float64(tally.StarRatings), tally.StarRatings)
+		}
+	}
+	gb.send(tgbotapi.NewMessage(message.Chat.ID, b.String()))
+}
+
+// handleFeedbackCommand shows or chooses the rating buttons under the
+// user's corrections with "/feedback thumbs|stars".
+func (gb *GrammarBot) handleFeedbackCommand(message *tgbotapi.Message, args string) {
+	userID := senderID(message)
+	var stars bool
+	switch strings.ToLower(args) {
+	case "":
+		reply := "You rate corrections with 👍 or 👎. Use /feedback stars to rate them from 1 to 5 stars instead."
+		if gb.store.GetUserSettings(userID).StarRatings {
+			reply = "You rate corrections from 1 to 5 stars. Use /feedback thumbs to rate them with 👍 or 👎 instead."
+		}
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, reply))
+		return
+	case "stars":
+		stars = true
+	case "thumbs":
+	default:
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /feedback [thumbs|stars]"))
+		return
+	}
+
+	if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) { stored.StarRatings = stars }); err != nil {
+		log.Printf("Error saving settings: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	reply := "Got it, you'll rate my corrections with 👍 or 👎."
+	if stars {
+		reply = "Got it, you'll rate my corrections from 1 to 5 stars."
+	}
+	gb.replyWithSettings(message, reply)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// ErrFloodWait is returned for requests still held back by a flood wait
+// when the bot shuts down. Sending them anyway would only extend the ban.
+var ErrFloodWait = errors.New("paused by telegram flood wait")
+
+// maxFloodRetries bounds how often a request that ran into a flood wait is
+// retried once the wait is over.
+const maxFloodRetries = 3
+
+// floodWait pauses outbound requests while Telegram's flood control is in
+// effect. It is safe for concurrent use.
+type floodWait struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+// pause holds back requests for d from now, unless an earlier flood wait
+// already lasts longer.
+func (f *floodWait) pause(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(f.until) {
+		f.until = until
+	}
+}
+
+// remaining returns how long requests are still held back.
+func (f *floodWait) remaining() time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return max(time.Until(f.until), 0)
+}
+
+// retryAfter returns how long Telegram asked the bot to wait, if err is a
+// flood-wait error.
+func retryAfter(err error) (time.Duration, bool) {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter <= 0 {
+		return 0, false
+	}
+	return time.Duration(apiErr.RetryAfter) * time.Second, true
+}
+
+// noteFloodWait pauses outbound requests if err is a flood-wait error and
+// reports whether it was.
+func (gb *GrammarBot) noteFloodWait(err error) bool {
+	wait, ok := retryAfter(err)
+	if !ok {
+		return false
+	}
+	gb.flood.pause(wait)
+	gb.metrics.floodWaits.Add(1)
+	log.Printf("Telegram flood wait: pausing outbound requests for %s", wait)
+	return true
+}
+
+// awaitFlood holds the caller until any flood wait is over, or returns an
+// error wrapping ErrFloodWait if the bot shuts down first.
+func (gb *GrammarBot) awaitFlood() error {
+	for {
+		wait := gb.flood.remaining()
+		if wait == 0 {
+			return nil
+		}
+		select {
+		case <-gb.ctx.Done():
+			return fmt.Errorf("%w for another %s", ErrFloodWait, wait.Round(time.Second))
+		case <-time.After(wait):
+		}
+	}
+}
+
+// outbound makes a Telegram request with do once any flood wait is over.
+// When Telegram answers with a flood wait, it starts one for every request
+// and retries do after it. All requests but polling go through outbound.
+func (gb *GrammarBot) outbound(do func() error) error {
+	for attempt := 0; ; attempt++ {
+		if err := gb.awaitFlood(); err != nil {
+			return err
+		}
+		err := do()
+		if err == nil || !gb.noteFloodWait(err) || attempt == maxFloodRetries {
+			return err
+		}
+	}
+}
+
+// botSend sends c through outbound.
+func (gb *GrammarBot) botSend(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	s := gb.sendSpan(c)
+	var sent tgbotapi.Message
+	err := gb.outbound(func() (err error) {
+		sent, err = gb.bot.Send(c)
+		return err
+	})
+	s.end(err)
+	return sent, err
+}
+
+// request makes a request that doesn't return a message, such as a callback
+// answer or a deletion, through outbound.
+func (gb *GrammarBot) request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	var resp *tgbotapi.APIResponse
+	err := gb.outbound(func() (err error) {
+		resp, err = gb.bot.Request(c)
+		return err
+	})
+	return resp, err
+}
+
+// makeRequest calls a method the library has no config for through
+// outbound.
+func (gb *GrammarBot) makeRequest(method string, params tgbotapi.Params) (*tgbotapi.APIResponse, error) {
+	var resp *tgbotapi.APIResponse
+	err := gb.outbound(func() (err error) {
+		resp, err = gb.bot.MakeRequest(method, params)
+		return err
+	})
+	return resp, err
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TestSendRetriedAfterFloodWait checks that a message Telegram answers with
+// a flood wait is sent again once the wait is over, and that other requests
+// made meanwhile are held instead of failing.
+func TestSendRetriedAfterFloodWait(t *testing.T) {
+	gb, tg := newTestBot(t, testConfig(t, nil), nil)
+	var floods atomic.Int32
+	tg.mu.Lock()
+	tg.fail = map[string]func(url.Values) (int, string, int){
+		"sendMessage": func(url.Values) (int, string, int) {
+			if floods.Add(1) == 1 {
+				return 429, "Too Many Requests: retry after 1", 1
+			}
+			return 0, "", 0
+		},
+	}
+	tg.mu.Unlock()
+
+	start := time.Now()
+	answered := make(chan error, 1)
+	go func() {
+		waitFor(t, "the flood wait to start", func() bool { return gb.flood.remaining() > 0 })
+		_, err := gb.request(tgbotapi.NewCallback("query", ""))
+		answered <- err
+	}()
+
+	if _, err := gb.send(tgbotapi.NewMessage(7, "Hello there.")); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("message resent after %s, before the flood wait was over", elapsed)
+	}
+	if got := len(tg.callsTo("sendMessage")); got != 2 {
+		t.Errorf("sendMessage called %d times, want 2", got)
+	}
+
+	if err := <-answered; err != nil {
+		t.Errorf("callback answer during the flood wait failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("callback answered after %s, during the flood wait", elapsed)
+	}
+	if got := gb.metrics.floodWaits.Load(); got != 1 {
+		t.Errorf("floodWaits = %d, want 1", got)
+	}
+}
+
+// TestFloodWaitAbortedByShutdown checks that a request held by a flood wait
+// gives up with ErrFloodWait when the bot shuts down.
+func TestFloodWaitAbortedByShutdown(t *testing.T) {
+	gb, tg := newTestBot(t, testConfig(t, nil), nil)
+	gb.flood.pause(time.Minute)
+	time.AfterFunc(50*time.Millisecond, gb.stopCalls)
+
+	start := time.Now()
+	_, err := gb.makeRequest("setMessageReaction", tgbotapi.Params{"chat_id": "7"})
+	if !errors.Is(err, ErrFloodWait) {
+		t.Fatalf("err = %v, want ErrFloodWait", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("returned after %s, want promptly after shutdown", elapsed)
+	}
+	if calls := tg.callsTo("setMessageReaction"); len(calls) != 0 {
+		t.Errorf("sent %d requests during the flood wait", len(calls))
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// geminiEngine corrects text with Google's Gemini API.
+type geminiEngine struct {
+	client *genai.Client
+	model  string
+	safety []*genai.SafetySetting
+}
+
+// geminiSafetyCategories are the harm categories whose thresholds can be
+// set, by the suffix of their environment variable.
+var geminiSafetyCategories = []struct {
+	env      string
+	category genai.HarmCategory
+}{
+	{"HARASSMENT", genai.HarmCategoryHarassment},
+	{"HATE_SPEECH", genai.HarmCategoryHateSpeech},
+	{"SEXUALLY_EXPLICIT", genai.HarmCategorySexuallyExplicit},
+	{"DANGEROUS_CONTENT", genai.HarmCategoryDangerousContent},
+}
+
+var geminiSafetyThresholds = []genai.HarmBlockThreshold{
+	genai.HarmBlockThresholdBlockLowAndAbove,
+	genai.HarmBlockThresholdBlockMediumAndAbove,
+	genai.HarmBlockThresholdBlockOnlyHigh,
+	genai.HarmBlockThresholdBlockNone,
+	genai.HarmBlockThresholdOff,
+}
+
+// loadGeminiSafety reads the safety thresholds: GEMINI_SAFETY_THRESHOLD for
+// every category, overridden per category by GEMINI_SAFETY_<CATEGORY>.
+// Categories without a threshold keep the API's default.
+func loadGeminiSafety() ([]*genai.SafetySetting, error) {
+	all, err := parseSafetyThreshold("GEMINI_SAFETY_THRESHOLD")
+	if err != nil {
+		return nil, err
+	}
+
+	var settings []*genai.SafetySetting
+	for _, c := range geminiSafetyCategories {
+		threshold, err := parseSafetyThreshold("GEMINI_SAFETY_" + c.env)
+		if err != nil {
+			return nil, err
+		}
+		if threshold == "" {
+			threshold = all
+		}
+		if threshold != "" {
+			settings = append(settings, &genai.SafetySetting{Category: c.category, Threshold: threshold})
+		}
+	}
+	return settings, nil
+}
+
+func parseSafetyThreshold(name string) (genai.HarmBlockThreshold, error) {
+	value := genai.HarmBlockThreshold(strings.ToUpper(strings.TrimSpace(os.Getenv(name))))
+	if value == "" || slices.Contains(geminiSafetyThresholds, value) {
+		return value, nil
+	}
+	return "", fmt.Errorf("unknown %s %q, expected one of %v", name, value, geminiSafetyThresholds)
+}
+
+func newGeminiEngine(ctx context.Context, apiKey, model string, safety []*genai.SafetySetting) (*geminiEngine, error) {
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:  apiKey,
+		Backend: genai.BackendGeminiAPI,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create genai client: %w", err)
+	}
+
+	return &geminiEngine{client: client, model: model, safety: safety}, nil
+}
+
+func (e *geminiEngine) Correct(ctx context.Context, text string, opts CorrectOptions) (string, error) {
+	return correctWith(ctx, e, text, opts)
+}
+
+func (e *geminiEngine) Complete(ctx context.Context, prompt Prompt, text string) (string, error) {
+	model := e.model
+	if prompt.Model != "" {
+		model = prompt.Model
+	}
+
+	contents, config := geminiRequest(prompt, text)
+	config.SafetySettings = e.safety
+	result, err := e.client.Models.GenerateContent(ctx, model, contents, config)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate content: %w", err)
+	}
+
+	return responseText(result)
+}
+
+// geminiRequest builds a request that passes the instructions as the system
+// instruction, each example as a user and a model turn, and text as the
+// final user turn, so instructions and user text never mix.
+func geminiRequest(prompt Prompt, text string) ([]*genai.Content, *genai.GenerateContentConfig) {
+	var contents []*genai.Content
+	for _, ex := range prompt.Examples {
+		contents = append(contents,
+			genai.NewContentFromText(ex.Input, genai.RoleUser),
+			genai.NewContentFromText(ex.Output, genai.RoleModel),
+		)
+	}
+	contents = append(contents, genai.NewContentFromText(text, genai.RoleUser))
+
+	config := &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText(prompt.Instructions, genai.RoleUser),
+	}
+	if prompt.Schema != nil {
+		config.ResponseMIMEType = "application/json"
+		config.ResponseSchema = prompt.Schema
+	}
+	return contents, config
+}
+
+// safetyFinishReasons are the reasons a candidate stops at because its
+// content was blocked.
+var safetyFinishReasons = map[genai.FinishReason]bool{
+	genai.FinishReasonSafety:            true,
+	genai.FinishReasonBlocklist:         true,
+	genai.FinishReasonProhibitedContent: true,
+	genai.FinishReasonSPII:              true,
+	genai.FinishReasonImageSafety:       true,
+}
+
+// responseText extracts the text of the first candidate. It checks the result
+// structure first, since Text() assumes a non-nil response.
+func responseText(result *genai.GenerateContentResponse) (string, error) {
+	if result != nil && result.PromptFeedback != nil && result.PromptFeedback.BlockReason != "" {
+		return "", fmt.Errorf("%w: prompt blocked (%s)", ErrSafetyBlocked, result.PromptFeedback.BlockReason)
+	}
+	if result == nil || len(result.Candidates) == 0 {
+		return "", ErrEmptyResponse
+	}
+
+	candidate := result.Candidates[0]
+	if candidate != nil && safetyFinishReasons[candidate.FinishReason] {
+		return "", fmt.Errorf("%w: answer blocked (%s)", ErrSafetyBlocked, candidate.FinishReason)
+	}
+	if candidate == nil || candidate.Content == nil || len(candidate.Content.Parts) == 0 {
+		return "", ErrEmptyResponse
+	}
+
+	text := result.Text()
+	if text == "" {
+		return "", ErrEmptyResponse
+	}
+	return text, nil
+}
+
+func (e *geminiEngine) ExtractText(ctx context.Context, image []byte, mimeType string) (string, error) {
+	contents := []*genai.Content{
+		genai.NewContentFromParts([]*genai.Part{
+			genai.NewPartFromText(extractTextPrompt),
+			genai.NewPartFromBytes(image, mimeType),
+		}, genai.RoleUser),
+	}
+
+	result, err := e.client.Models.GenerateContent(ctx, e.model, contents, &genai.GenerateContentConfig{SafetySettings: e.safety})
+	if err != nil {
+		return "", fmt.Errorf("failed to extract text: %w", err)
+	}
+
+	return responseText(result)
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// newTestGeminiEngine returns an engine sending its requests to handler.
+func newTestGeminiEngine(t *testing.T, handler http.HandlerFunc, safety []*genai.SafetySetting) *geminiEngine {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test-key",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: server.URL},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &geminiEngine{client: client, model: "test-model", safety: safety}
+}
+
+// hangingHandler never answers, until the client goes away.
+func hangingHandler(w http.ResponseWriter, r *http.Request) {
+	// The server only notices the client leaving once the body is read
+	io.Copy(io.Discard, r.Body)
+	select {
+	case <-r.Context().Done():
+	case <-time.After(10 * time.Second):
+	}
+}
+
+// TestGeminiHonorsCancellation checks that cancelling the context of a
+// GenerateContent call that hangs aborts it promptly.
+func TestGeminiHonorsCancellation(t *testing.T) {
+	engine := newTestGeminiEngine(t, hangingHandler, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := engine.Correct(ctx, "She go home.", CorrectOptions{Language: defaultLanguage})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("returned after %s, want promptly after cancellation", elapsed)
+	}
+}
+
+// TestCheckGrammarAbortedByShutdown checks that stopping AI calls makes a
+// running check return with a context error instead of waiting it out.
+func TestCheckGrammarAbortedByShutdown(t *testing.T) {
+	engine := newTestGeminiEngine(t, hangingHandler, nil)
+	gb, _ := newTestBot(t, testConfig(t, nil), engine)
+
+	time.AfterFunc(50*time.Millisecond, gb.stopCalls)
+
+	start := time.Now()
+	_, err := gb.checkGrammar("She go home.", CorrectOptions{Language: defaultLanguage})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("returned after %s, want promptly after cancellation", elapsed)
+	}
+}
+
+func TestResponseTextEmpty(t *testing.T) {
+	tests := []struct {
+		name   string
+		result *genai.GenerateContentResponse
+	}{
+		{"nil response", nil},
+		{"no candidates", &genai.GenerateContentResponse{}},
+		{"nil candidate", &genai.GenerateContentResponse{Candidates: []*genai.Candidate{nil}}},
+		{"no content", &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{}}}},
+		{"no parts", &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{Content: &genai.Content{}}}}},
+		{"empty text", &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{Content: genai.NewContentFromText("", genai.RoleModel)}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := responseText(tt.result); !errors.Is(err, ErrEmptyResponse) {
+				t.Errorf("err = %v, want ErrEmptyResponse", err)
+			}
+		})
+	}
+
+	result := &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{Content: genai.NewContentFromText("She goes home.", genai.RoleModel)}}}
+	if text, err := responseText(result); err != nil || text != "She goes home." {
+		t.Errorf("responseText() = %q, %v, want the candidate's text", text, err)
+	}
+}
+
+// TestGeminiEmptyCandidates checks that a successful answer without any
+// candidates is ErrEmptyResponse rather than a panic or an empty correction.
+func TestGeminiEmptyCandidates(t *testing.T) {
+	engine := newTestGeminiEngine(t, jsonHandler(`{"candidates": []}`), nil)
+
+	if _, err := engine.Correct(context.Background(), "She go home.", CorrectOptions{Language: defaultLanguage}); !errors.Is(err, ErrEmptyResponse) {
+		t.Errorf("err = %v, want ErrEmptyResponse", err)
+	}
+}
+
+// answeringHandler answers every request with answer, storing the last
+// request body in body.
+func answeringHandler(body *[]byte, answer string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*body, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"candidates": []any{map[string]any{
+			"content": map[string]any{"role": "model", "parts": []any{map[string]any{"text": answer}}},
+		}}})
+	}
+}
+
+func TestGeminiRequestStructure(t *testing.T) {
+	prompt := Prompt{
+		Instructions: "Correct the text.",
+		Examples:     []PromptExample{{Input: "i go", Output: "~i~ **I** go"}, {Input: "ok", Output: "ok"}},
+	}
+	contents, config := geminiRequest(prompt, "She go home.")
+
+	if got := config.SystemInstruction.Parts[0].Text; got != prompt.Instructions {
+		t.Errorf("system instruction = %q, want the prompt's instructions", got)
+	}
+	want := []struct{ role, text string }{
+		{genai.RoleUser, "i go"}, {genai.RoleModel, "~i~ **I** go"},
+		{genai.RoleUser, "ok"}, {genai.RoleModel, "ok"},
+		{genai.RoleUser, "She go home."},
+	}
+	if len(contents) != len(want) {
+		t.Fatalf("got %d turns, want %d", len(contents), len(want))
+	}
+	for i, w := range want {
+		if c := contents[i]; c.Role != w.role || len(c.Parts) != 1 || c.Parts[0].Text != w.text {
+			t.Errorf("turn %d = %s %q, want %s %q", i, c.Role, c.Parts[0].Text, w.role, w.text)
+		}
+	}
+	if config.ResponseMIMEType != "" || config.ResponseSchema != nil {
+		t.Error("a prompt without a schema asked for JSON")
+	}
+
+	prompt.Schema = structuredSchema
+	if _, config := geminiRequest(prompt, "She go home."); config.ResponseMIMEType != "application/json" || config.ResponseSchema != structuredSchema {
+		t.Error("a prompt with a schema didn't ask for JSON of that shape")
+	}
+}
+
+// TestGeminiSendsStructuredContent checks that a correction reaches the API
+// with the instructions as the system instruction and the user's text as a
+// separate user turn, and that the answer comes back unchanged.
+func TestGeminiSendsStructuredContent(t *testing.T) {
+	var body []byte
+	engine := newTestGeminiEngine(t, answeringHandler(&body, "She ~go~ **goes** home\\."), nil)
+
+	corrected, err := engine.Correct(context.Background(), "She go home.", CorrectOptions{Language: defaultLanguage})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corrected != "She ~go~ **goes** home\\." {
+		t.Errorf("corrected = %q, want the model's answer", corrected)
+	}
+
+	var req struct {
+		Contents          []*genai.Content `json:"contents"`
+		SystemInstruction *genai.Content   `json:"systemInstruction"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.SystemInstruction == nil || !strings.Contains(req.SystemInstruction.Parts[0].Text, "English language assistant") {
+		t.Error("instructions weren't sent as the system instruction")
+	}
+	last := req.Contents[len(req.Contents)-1]
+	if last.Role != genai.RoleUser || last.Parts[0].Text != guardInput("She go home.") {
+		t.Errorf("last turn = %s %q, want the guarded user text", last.Role, last.Parts[0].Text)
+	}
+	if len(req.Contents) != 2*len(correctionExamples)+1 {
+		t.Errorf("sent %d turns, want the %d examples and the text", len(req.Contents), len(correctionExamples))
+	}
+}
+
+// jsonHandler answers every request with body.
+func jsonHandler(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, body)
+	}
+}
+
+func TestGeminiSafetyBlocked(t *testing.T) {
+	responses := map[string]string{
+		"prompt blocked": `{"promptFeedback": {"blockReason": "SAFETY"}}`,
+		"answer blocked": `{"candidates": [{"finishReason": "SAFETY"}]}`,
+		"prohibited":     `{"candidates": [{"finishReason": "PROHIBITED_CONTENT", "content": {"role": "model", "parts": [{"text": "..."}]}}]}`,
+	}
+	for name, response := range responses {
+		t.Run(name, func(t *testing.T) {
+			engine := newTestGeminiEngine(t, jsonHandler(response), nil)
+			_, err := engine.Correct(context.Background(), "She go home.", CorrectOptions{Language: defaultLanguage})
+			if !errors.Is(err, ErrSafetyBlocked) {
+				t.Errorf("err = %v, want ErrSafetyBlocked", err)
+			}
+		})
+	}
+}
+
+// TestSafetyBlockedReply checks that a blocked check is answered with the
+// configured message and counted as a safety block.
+func TestSafetyBlockedReply(t *testing.T) {
+	engine := newTestGeminiEngine(t, jsonHandler(`{"promptFeedback": {"blockReason": "SAFETY"}}`), nil)
+	gb, tg := newTestBot(t, testConfig(t, map[string]string{"SAFETY_BLOCK_MESSAGE": "Not with that content."}), engine)
+
+	gb.handleMessage(privateMessage(7, "She go home today."))
+
+	waitFor(t, "the reply", func() bool { return len(tg.callsTo("sendMessage")) > 0 })
+	if text := tg.callsTo("sendMessage")[0].Get("text"); text != "Not with that content." {
+		t.Errorf("reply = %q, want the safety block message", text)
+	}
+	if got := gb.Snapshot().ErrorsByType[errorSafety]; got != 1 {
+		t.Errorf("safety blocks = %d, want 1", got)
+	}
+}
+
+// TestGeminiSafetySettingsSent checks that every kind of call, text and
+// image, sends the configured safety settings.
+func TestGeminiSafetySettingsSent(t *testing.T) {
+	safety := []*genai.SafetySetting{{Category: genai.HarmCategoryHarassment, Threshold: genai.HarmBlockThresholdBlockOnlyHigh}}
+	var body []byte
+	engine := newTestGeminiEngine(t, answeringHandler(&body, "She go home."), safety)
+
+	calls := map[string]func() error{
+		"Correct": func() error {
+			_, err := engine.Correct(context.Background(), "She go home.", CorrectOptions{Language: defaultLanguage})
+			return err
+		},
+		"ExtractText": func() error {
+			_, err := engine.ExtractText(context.Background(), []byte("\x89PNG"), "image/png")
+			return err
+		},
+	}
+	for name, call := range calls {
+		if err := call(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var req struct {
+			SafetySettings []*genai.SafetySetting `json:"safetySettings"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatal(err)
+		}
+		if len(req.SafetySettings) != 1 || *req.SafetySettings[0] != *safety[0] {
+			t.Errorf("%s sent safety settings %+v, want the configured ones", name, req.SafetySettings)
+		}
+	}
+}
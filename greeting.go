@@ -0,0 +1,95 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// greetingCooldowns spaces out greetings of new members per chat, so a wave
+// of joins gets one greeting instead of one each.
+type greetingCooldowns struct {
+	mu   sync.Mutex
+	last map[int64]time.Time
+}
+
+// allow records a greeting in chatID at now and reports whether it may be
+// sent, given the cooldown.
+func (g *greetingCooldowns) allow(chatID int64, now time.Time, cooldown time.Duration) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.last == nil {
+		g.last = make(map[int64]time.Time)
+	}
+	if last, ok := g.last[chatID]; ok && now.Sub(last) < cooldown {
+		return false
+	}
+	g.last[chatID] = now
+	return true
+}
+
+// handleNewChatMembers thanks whoever added the bot to a group, and greets
+// other new members with an intro if the chat's admins turned greetings on.
+func (gb *GrammarBot) handleNewChatMembers(message *tgbotapi.Message) {
+	var names []string
+	for _, member := range message.NewChatMembers {
+		if member.ID == gb.bot.Self.ID {
+			gb.send(tgbotapi.NewMessage(message.Chat.ID, `👋 Thanks for adding me! I'll check the grammar of messages sent here and reply with corrections.
+
+Use /check <text>, or reply /check to a message, to check anything. Admins can turn on a short intro for new members with /greeting on.`))
+			return
+		}
+		if !member.IsBot {
+			names = append(names, member.FirstName)
+		}
+	}
+
+	if len(names) == 0 || !gb.store.GetChatSettings(message.Chat.ID).Greet {
+		return
+	}
+	if !gb.greetings.allow(message.Chat.ID, time.Now(), gb.cfg.GreetingCooldown) {
+		return
+	}
+	gb.send(tgbotapi.NewMessage(message.Chat.ID, "👋 Welcome, "+strings.Join(names, ", ")+`! I'm the grammar checker of this chat: I reply to messages with mistakes with a corrected version.
+
+Use /check <text>, or reply /check to a message, to check something on purpose, and /help to see what else I can do.`))
+}
+
+// handleGreetingCommand shows or sets whether new members of the chat are
+// greeted with an intro.
+func (gb *GrammarBot) handleGreetingCommand(message *tgbotapi.Message, args string) {
+	chatID := message.Chat.ID
+	if message.Chat.IsPrivate() {
+		gb.send(tgbotapi.NewMessage(chatID, "/greeting works in groups, where I can greet new members."))
+		return
+	}
+	if !gb.canManageChat(message) {
+		gb.send(tgbotapi.NewMessage(chatID, "Only chat admins can change greetings."))
+		return
+	}
+
+	if _, ok := parseToggle(args, false); !ok {
+		gb.send(tgbotapi.NewMessage(chatID, "Usage: /greeting [on|off]"))
+		return
+	}
+	// No argument flips the stored value
+	var enabled bool
+	if err := gb.store.UpdateChatSettings(chatID, func(stored *ChatSettings) {
+		enabled, _ = parseToggle(args, stored.Greet)
+		stored.Greet = enabled
+	}); err != nil {
+		log.Printf("Error saving chat settings: %v", err)
+		gb.send(tgbotapi.NewMessage(chatID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	reply := "Greetings are off. I won't introduce myself to new members."
+	if enabled {
+		reply = "Greetings are on. I'll introduce myself to new members of this chat."
+	}
+	gb.send(tgbotapi.NewMessage(chatID, reply))
+}
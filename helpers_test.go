@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// telegramCall is one request the bot made to the fake Telegram API.
+type telegramCall struct {
+	method string
+	params url.Values
+}
+
+// fakeTelegram serves the Bot API methods the bot uses. getUpdates behaves
+// like Telegram's: polling from an offset confirms, and forgets, every
+// update before it.
+type fakeTelegram struct {
+	server *httptest.Server
+
+	mu        sync.Mutex
+	calls     []telegramCall
+	updates   []json.RawMessage
+	confirmed int
+	messageID int
+	// fail, when set, answers a method with an API error instead
+	fail map[string]func(url.Values) (code int, description string, retryAfter int)
+	// arrived is signalled whenever an update is added
+	arrived chan struct{}
+	// redeliver makes getUpdates return confirmed updates again, as
+	// Telegram occasionally does after a restart
+	redeliver bool
+}
+
+func newFakeTelegram(t *testing.T) *fakeTelegram {
+	t.Helper()
+	tg := &fakeTelegram{arrived: make(chan struct{}, 1)}
+	tg.server = httptest.NewServer(http.HandlerFunc(tg.serve))
+	t.Cleanup(tg.server.Close)
+	return tg
+}
+
+func (tg *fakeTelegram) serve(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+	tg.mu.Lock()
+	tg.calls = append(tg.calls, telegramCall{method: method, params: r.PostForm})
+	fail := tg.fail[method]
+	tg.mu.Unlock()
+
+	if fail != nil {
+		if code, description, retryAfter := fail(r.PostForm); code != 0 {
+			resp := map[string]any{"ok": false, "error_code": code, "description": description}
+			if retryAfter > 0 {
+				resp["parameters"] = map[string]any{"retry_after": retryAfter}
+			}
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+	}
+
+	var result any = true
+	switch method {
+	case "getMe":
+		result = map[string]any{"id": 1, "is_bot": true, "first_name": "Grammar", "username": "grammar_bot"}
+	case "getUpdates":
+		result = tg.pollUpdates(r.Context(), r.PostForm)
+	case "sendMessage", "editMessageText":
+		tg.mu.Lock()
+		tg.messageID++
+		id := tg.messageID
+		tg.mu.Unlock()
+		chatID, _ := strconv.ParseInt(r.PostForm.Get("chat_id"), 10, 64)
+		result = map[string]any{
+			"message_id": id,
+			"date":       time.Now().Unix(),
+			"chat":       map[string]any{"id": chatID, "type": "private"},
+			"text":       r.PostForm.Get("text"),
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+}
+
+// pollUpdates answers getUpdates, waiting briefly for updates when there
+// are none, like a short long poll.
+func (tg *fakeTelegram) pollUpdates(ctx context.Context, params url.Values) []json.RawMessage {
+	offset, _ := strconv.Atoi(params.Get("offset"))
+	deadline := time.After(50 * time.Millisecond)
+	for {
+		tg.mu.Lock()
+		tg.confirmed = max(tg.confirmed, offset)
+		var pending []json.RawMessage
+		kept := tg.updates[:0]
+		for _, raw := range tg.updates {
+			var u struct {
+				UpdateID int `json:"update_id"`
+			}
+			json.Unmarshal(raw, &u)
+			if u.UpdateID >= tg.confirmed {
+				kept = append(kept, raw)
+				pending = append(pending, raw)
+			}
+		}
+		tg.updates = kept
+		tg.mu.Unlock()
+
+		if len(pending) > 0 {
+			return pending
+		}
+		select {
+		case <-tg.arrived:
+		case <-deadline:
+			return []json.RawMessage{}
+		case <-ctx.Done():
+			return []json.RawMessage{}
+		}
+	}
+}
+
+// addMessage queues an update with a private text message from userID.
+func (tg *fakeTelegram) addMessage(updateID int, userID int64, text string) {
+	raw, _ := json.Marshal(map[string]any{
+		"update_id": updateID,
+		"message": map[string]any{
+			"message_id": updateID,
+			"date":       time.Now().Unix(),
+			"from":       map[string]any{"id": userID, "first_name": "Ann"},
+			"chat":       map[string]any{"id": userID, "type": "private"},
+			"text":       text,
+		},
+	})
+	tg.mu.Lock()
+	tg.updates = append(tg.updates, raw)
+	tg.mu.Unlock()
+	select {
+	case tg.arrived <- struct{}{}:
+	default:
+	}
+}
+
+// callsTo returns the requests made to method, oldest first.
+func (tg *fakeTelegram) callsTo(method string) []url.Values {
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+
+	var params []url.Values
+	for _, call := range tg.calls {
+		if call.method == method {
+			params = append(params, call.params)
+		}
+	}
+	return params
+}
+
+// confirmedOffset returns the highest offset the bot polled from.
+func (tg *fakeTelegram) confirmedOffset() int {
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+
+	return tg.confirmed
+}
+
+// fakeEngine answers AI calls with the given functions. Unset functions
+// echo the text back unchanged.
+type fakeEngine struct {
+	correct  func(ctx context.Context, text string, opts CorrectOptions) (string, error)
+	complete func(ctx context.Context, prompt Prompt, text string) (string, error)
+}
+
+func (e *fakeEngine) Correct(ctx context.Context, text string, opts CorrectOptions) (string, error) {
+	if e.correct == nil {
+		return text, nil
+	}
+	return e.correct(ctx, text, opts)
+}
+
+func (e *fakeEngine) Complete(ctx context.Context, prompt Prompt, text string) (string, error) {
+	if e.complete == nil {
+		return text, nil
+	}
+	return e.complete(ctx, prompt, text)
+}
+
+// testConfig returns the default configuration, with env applied on top.
+func testConfig(t *testing.T, env map[string]string) Config {
+	t.Helper()
+	t.Setenv("TELEGRAM_BOT_TOKEN", "test-token")
+	t.Setenv("GEMINI_API_KEY", "test-key")
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// newTestBot returns a bot talking to a fake Telegram and engine, with an
+// in-memory store unless cfg.StorePath is set.
+func newTestBot(t *testing.T, cfg Config, engine GrammarEngine) (*GrammarBot, *fakeTelegram) {
+	t.Helper()
+	tg := newFakeTelegram(t)
+	return newTestBotOn(t, tg, cfg, engine), tg
+}
+
+// newTestBotOn is newTestBot talking to an existing fake Telegram, as a bot
+// restarted against the same account.
+func newTestBotOn(t *testing.T, tg *fakeTelegram, cfg Config, engine GrammarEngine) *GrammarBot {
+	t.Helper()
+	bot, err := tgbotapi.NewBotAPIWithClient(cfg.TelegramToken, tg.server.URL+"/bot%s/%s", tg.server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewStore(cfg.StorePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if engine == nil {
+		engine = &fakeEngine{}
+	}
+
+	ctx, stopCalls := context.WithCancel(context.Background())
+	t.Cleanup(stopCalls)
+	gb := newGrammarBot(ctx, stopCalls, cfg, bot, engine, store)
+	t.Cleanup(gb.deletions.stop)
+	return gb
+}
+
+// privateMessage returns a text message userID sent the bot.
+func privateMessage(userID int64, text string) *tgbotapi.Message {
+	return &tgbotapi.Message{
+		MessageID: 1,
+		From:      &tgbotapi.User{ID: userID, FirstName: "Ann"},
+		Chat:      &tgbotapi.Chat{ID: userID, Type: "private"},
+		Text:      text,
+	}
+}
+
+// command returns a message with a bot command such as "/style arrows".
+func command(userID int64, text string) *tgbotapi.Message {
+	message := privateMessage(userID, text)
+	name := strings.Fields(text)[0]
+	message.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(name)}}
+	return message
+}
+
+// waitFor polls cond until it holds, failing the test after a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// run starts gb, waits for until to return and shuts gb down again.
+func run(t *testing.T, gb *GrammarBot, until func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		gb.Start(ctx)
+		close(done)
+	}()
+
+	until()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start didn't return after shutdown")
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// limitHighlights keeps markup on at most max of the most significant changes
+// and applies the rest silently, returning the edits to render and how many
+// changes were hidden. A max of 0 means no limit.
+func limitHighlights(edits []Edit, max int) ([]Edit, int) {
+	var changed []int
+	for i, e := range edits {
+		if e.Changed() {
+			changed = append(changed, i)
+		}
+	}
+	if max <= 0 || len(changed) <= max {
+		return edits, 0
+	}
+
+	sort.SliceStable(changed, func(a, b int) bool {
+		return editSignificance(edits[changed[a]]) > editSignificance(edits[changed[b]])
+	})
+	highlighted := make(map[int]bool, max)
+	for _, i := range changed[:max] {
+		highlighted[i] = true
+	}
+
+	// Fold each run of plain text and hidden changes into one unchanged
+	// edit holding the corrected text of the run
+	var (
+		limited []Edit
+		run     []Edit
+	)
+	flush := func() {
+		if len(run) > 0 {
+			text := correctedText(run)
+			limited = append(limited, Edit{Original: text, Corrected: text})
+			run = nil
+		}
+	}
+	for i, e := range edits {
+		if highlighted[i] {
+			flush()
+			limited = append(limited, e)
+			continue
+		}
+		run = append(run, e)
+	}
+	flush()
+
+	return limited, len(changed) - max
+}
+
+// editSignificance ranks a change for highlighting. Changes that only touch
+// case, punctuation or spacing rank below anything that changes a word, and
+// larger rewrites rank above smaller ones.
+func editSignificance(e Edit) int {
+	size := utf8.RuneCountInString(e.Original) + utf8.RuneCountInString(e.Corrected)
+	if lettersOnly(e.Original) == lettersOnly(e.Corrected) {
+		return size
+	}
+	return size + 1000
+}
+
+// lettersOnly lowercases text and drops everything but letters and digits.
+func lettersOnly(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, text)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	_ "time/tzdata" // the container image may not ship a zoneinfo database
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rivo/uniseg"
+)
+
+// historyPageSize is how many checks /history shows.
+const historyPageSize = 10
+
+// userLocation returns the time zone the user chose, defaulting to UTC.
+func (gb *GrammarBot) userLocation(userID int64) *time.Location {
+	return settingsLocation(gb.store.GetUserSettings(userID))
+}
+
+// settingsLocation returns the time zone of settings, for use inside
+// UpdateUserSettings where the store can't be read again.
+func settingsLocation(settings UserSettings) *time.Location {
+	name := settings.Timezone
+	if name == "" {
+		return time.UTC
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("Error loading stored timezone %q: %v", name, err)
+		return time.UTC
+	}
+	return loc
+}
+
+// formatTimestamp renders t in the user's time zone.
+func (gb *GrammarBot) formatTimestamp(userID int64, t time.Time) string {
+	return t.In(gb.userLocation(userID)).Format("2006-01-02 15:04 MST")
+}
+
+// handleTimezoneCommand shows or sets the user's time zone.
+func (gb *GrammarBot) handleTimezoneCommand(message *tgbotapi.Message, name string) {
+	userID := senderID(message)
+
+	if name == "" {
+		current := gb.userLocation(userID).String()
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Your timezone is %s. Use /timezone <IANA name>, for example /timezone Europe/Berlin, to change it.", current)))
+		return
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "Local" {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("%q is not a valid IANA timezone name. Try something like Europe/Berlin or America/New_York.", name)))
+		return
+	}
+
+	// The next daily exercise moves with the timezone, decided on the
+	// stored time so a concurrent /dailypractice isn't undone
+	if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) {
+		stored.Timezone = loc.String()
+		if stored.DailyPractice != "" {
+			stored.DailyPracticeNext = nextDailyPractice(stored.DailyPractice, loc, time.Now())
+		}
+	}); err != nil {
+		log.Printf("Error saving settings: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	gb.replyWithSettings(message, fmt.Sprintf("Timezone set to %s. It's %s there now.", loc, time.Now().In(loc).Format("15:04")))
+}
+
+// handleHistoryCommand lists the user's most recent checks.
+func (gb *GrammarBot) handleHistoryCommand(message *tgbotapi.Message, args string) {
+	userID := senderID(message)
+	history := gb.store.History(userID)
+	if len(history) == 0 {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "You have no checks in your history yet."))
+		return
+	}
+	if len(history) > historyPageSize {
+		history = history[len(history)-historyPageSize:]
+	}
+
+	var b strings.Builder
+	b.WriteString(escapeMarkdownV2("🕒 Your recent checks:"))
+	for i := len(history) - 1; i >= 0; i-- {
+		entry := history[i]
+		b.WriteString("\n\n*" + escapeMarkdownV2(gb.formatTimestamp(userID, entry.Time)) + "*\n")
+		b.WriteString(escapeMarkdownV2(truncateGraphemes(entry.Original, 200)) + "\n")
+		b.WriteString(escapeMarkdownV2("→ ") + entry.Corrected)
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, b.String())
+	msg.ParseMode = "MarkdownV2"
+	if _, err := gb.send(msg); err != nil {
+		log.Printf("Error sending history: %v", err)
+	}
+}
+
+// truncateGraphemes shortens text to at most n grapheme clusters, adding an
+// ellipsis if cut. Cutting between clusters keeps accents and emoji intact.
+func truncateGraphemes(text string, n int) string {
+	g := uniseg.NewGraphemes(text)
+	for i := 0; i < n; i++ {
+		if !g.Next() {
+			return text
+		}
+	}
+	_, end := g.Positions()
+	if !g.Next() {
+		return text
+	}
+	return text[:end] + "…"
+}
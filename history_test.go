@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTimezoneMovesDailyPractice checks that changing the timezone moves
+// the next daily exercise to the chosen time in the new zone.
+func TestTimezoneMovesDailyPractice(t *testing.T) {
+	gb, _ := newTestBot(t, testConfig(t, nil), nil)
+
+	gb.handleCommand(command(7, "/dailypractice on 09:00"))
+	before := gb.store.GetUserSettings(7).DailyPracticeNext
+	if before.IsZero() {
+		t.Fatal("/dailypractice on didn't schedule an exercise")
+	}
+
+	gb.handleCommand(command(7, "/timezone Asia/Tokyo"))
+	settings := gb.store.GetUserSettings(7)
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	next := settings.DailyPracticeNext.In(tokyo)
+	if settings.Timezone != "Asia/Tokyo" || settings.DailyPractice != "09:00" {
+		t.Fatalf("settings = %+v, want the new timezone and the same daily practice", settings)
+	}
+	if next.Hour() != 9 || next.Minute() != 0 || !next.After(time.Now()) || next.Sub(time.Now()) > 24*time.Hour {
+		t.Errorf("next exercise at %s, want the coming 09:00 in Tokyo", next)
+	}
+}
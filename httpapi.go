@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxAPIRequestBytes bounds the body of an API request.
+const maxAPIRequestBytes = 64 << 10
+
+// apiClientID is the usage-tracking identity shared by all API clients, so
+// they count against the soft limit like a single user.
+const apiClientID int64 = 0
+
+type correctRequest struct {
+	Text     string `json:"text"`
+	Language string `json:"language"`
+}
+
+type correctResponse struct {
+	// Formatted is the MarkdownV2 correction, as sent to Telegram users.
+	Formatted string `json:"formatted"`
+	// Plain is the corrected text without any markup.
+	Plain string `json:"plain"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// apiHandler returns the HTTP API: POST /correct and GET /metrics, and the
+// dashboard when it is on, all requiring the token.
+func (gb *GrammarBot) apiHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /correct", gb.handleAPICorrect)
+	mux.HandleFunc("GET /metrics", gb.handleAPIMetrics)
+	if gb.cfg.Dashboard {
+		gb.registerDashboard(mux)
+	}
+	return gb.requireToken(mux)
+}
+
+// requireToken rejects requests without the configured token, given as a
+// bearer token or, for browsers, as the Basic auth password.
+func (gb *GrammarBot) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			_, token, ok = r.BasicAuth()
+		}
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(gb.cfg.HTTPAPIToken)) != 1 {
+			if strings.HasPrefix(r.URL.Path, "/dashboard") {
+				w.Header().Set("WWW-Authenticate", `Basic realm="dashboard"`)
+			}
+			writeJSON(w, http.StatusUnauthorized, apiError{Error: "missing or invalid token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (gb *GrammarBot) handleAPICorrect(w http.ResponseWriter, r *http.Request) {
+	// The same gates as for Telegram updates: /block 0 cuts off API clients
+	if gb.isBlocked(apiClientID) {
+		writeJSON(w, http.StatusForbidden, apiError{Error: "API access is blocked"})
+		return
+	}
+	if gb.store.Maintenance() {
+		w.Header().Set("Retry-After", "300")
+		writeJSON(w, http.StatusServiceUnavailable, apiError{Error: gb.cfg.MaintenanceMessage})
+		return
+	}
+
+	var req correctRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIRequestBytes)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid JSON body"})
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: "text is required"})
+		return
+	}
+
+	// The options are those of a Telegram user without settings, resolved
+	// the same way, so CORRECTION_FORMAT and the other defaults apply
+	opts := gb.checkOptions(apiClientID, nil, nil)
+	if req.Language != "" {
+		language, ok := normalizeLanguage(req.Language)
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid language"})
+			return
+		}
+		opts.Language = language
+	}
+
+	// API clients share the soft usage limit with Telegram users
+	if delay := gb.throttleDelay(apiClientID); delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	// The check is for this request only: it stops when the client goes
+	// away, or when AI calls are stopped for shutdown
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	defer context.AfterFunc(gb.ctx, cancel)()
+
+	formatted, err := gb.checkGrammarContext(ctx, req.Text, opts)
+	if err != nil {
+		// Nobody is left to answer
+		if r.Context().Err() != nil {
+			return
+		}
+		gb.logError("Error checking grammar for API request: %v", err)
+		writeJSON(w, http.StatusBadGateway, apiError{Error: "grammar check failed"})
+		return
+	}
+
+	plain := stripMarkdownV2(formatted)
+	if edits, err := parseInlineEdits(formatted); err == nil {
+		plain = correctedText(edits)
+	}
+	writeJSON(w, http.StatusOK, correctResponse{Formatted: formatted, Plain: plain})
+}
+
+func (gb *GrammarBot) handleAPIMetrics(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, gb.Snapshot())
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error writing API response: %v", err)
+	}
+}
+
+// serveAPI runs the HTTP API on HTTP_API_ADDR until ctx is cancelled.
+func (gb *GrammarBot) serveAPI(ctx context.Context) {
+	server := &http.Server{
+		Addr:              gb.cfg.HTTPAPIAddr,
+		Handler:           gb.apiHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error shutting down HTTP API: %v", err)
+		}
+	}()
+
+	log.Printf("HTTP API listening on %s", gb.cfg.HTTPAPIAddr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("Error serving HTTP API: %v", err)
+	}
+}
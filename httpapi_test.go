@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// apiCorrect posts body to /correct with the test token.
+func apiCorrect(handler http.Handler, ctx context.Context, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/correct", strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+// TestAPICorrectOptions checks that API checks resolve their options like
+// Telegram checks, with CORRECTION_FORMAT, and the request's language.
+func TestAPICorrectOptions(t *testing.T) {
+	var got CorrectOptions
+	engine := &fakeEngine{correct: func(ctx context.Context, text string, opts CorrectOptions) (string, error) {
+		got = opts
+		return text, nil
+	}}
+	gb, _ := newTestBot(t, testConfig(t, map[string]string{"HTTP_API_TOKEN": "secret", "CORRECTION_FORMAT": formatJSON}), engine)
+
+	if w := apiCorrect(gb.apiHandler(), context.Background(), `{"text": "Sie gehen nach Hause.", "language": "german"}`); w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	want := gb.checkOptions(apiClientID, nil, nil)
+	want.Language = "German"
+	// The engine gets the options as normalized for the cache
+	if want = gb.cacheKey("Sie gehen nach Hause.", want).opts; got != want {
+		t.Errorf("options = %+v, want %+v", got, want)
+	}
+	if got.Format != formatJSON {
+		t.Errorf("format = %q, want CORRECTION_FORMAT", got.Format)
+	}
+}
+
+// TestAPICorrectCancelledWithRequest checks that a check stops when its
+// client goes away, not only at shutdown.
+func TestAPICorrectCancelledWithRequest(t *testing.T) {
+	cancelled := make(chan error, 1)
+	engine := &fakeEngine{correct: func(ctx context.Context, text string, opts CorrectOptions) (string, error) {
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+		return "", ctx.Err()
+	}}
+	gb, _ := newTestBot(t, testConfig(t, map[string]string{"HTTP_API_TOKEN": "secret"}), engine)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	apiCorrect(gb.apiHandler(), ctx, `{"text": "She go to school."}`)
+
+	select {
+	case err := <-cancelled:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("check ended with %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("check kept running after the client went away")
+	}
+	if gb.ctx.Err() != nil {
+		t.Error("a client going away stopped every AI call")
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// inlineResultCacheTime is how long Telegram may reuse a correction for the
+// same query, in seconds. Placeholders must not be reused, so they get one
+// second.
+const inlineResultCacheTime = 300
+
+// inlineResultTTL is how long a user's last inline correction is kept for
+// them repeating the query, as long as Telegram may reuse it too.
+const inlineResultTTL = inlineResultCacheTime * time.Second
+
+// maxInlineQueryLength is the longest inline query Telegram accepts.
+const maxInlineQueryLength = 256
+
+// inlineResult is the last correction made for a user's inline query.
+type inlineResult struct {
+	text      string
+	opts      CorrectOptions
+	corrected string
+}
+
+// inlineQueries debounces inline queries, which arrive on every keystroke:
+// only the latest query of a user is checked, once they pause typing.
+type inlineQueries struct {
+	mu      sync.Mutex
+	pending map[int64]*tgbotapi.InlineQuery
+	last    map[int64]*inlineResult
+}
+
+// wait makes query the user's pending query and returns the one it
+// replaces, if any.
+func (q *inlineQueries) wait(userID int64, query *tgbotapi.InlineQuery) *tgbotapi.InlineQuery {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.pending == nil {
+		q.pending = make(map[int64]*tgbotapi.InlineQuery)
+	}
+	replaced := q.pending[userID]
+	q.pending[userID] = query
+	return replaced
+}
+
+// take reports whether query is still the user's pending query, and stops
+// it being pending.
+func (q *inlineQueries) take(userID int64, query *tgbotapi.InlineQuery) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.pending[userID] != query {
+		return false
+	}
+	delete(q.pending, userID)
+	return true
+}
+
+// lastResult returns the user's last correction if it was for the same text
+// and options.
+func (q *inlineQueries) lastResult(userID int64, text string, opts CorrectOptions) (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	last, ok := q.last[userID]
+	if !ok || last.text != text || last.opts != opts {
+		return "", false
+	}
+	return last.corrected, true
+}
+
+// remember makes result the user's last correction, for lastResult to
+// return until ttl has passed or another result replaces it.
+func (q *inlineQueries) remember(userID int64, result inlineResult, ttl time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.last == nil {
+		q.last = make(map[int64]*inlineResult)
+	}
+	remembered := &result
+	q.last[userID] = remembered
+	time.AfterFunc(ttl, func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+
+		if q.last[userID] == remembered {
+			delete(q.last, userID)
+		}
+	})
+}
+
+// size returns how many users have a pending query or remembered result.
+func (q *inlineQueries) size() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.pending) + len(q.last)
+}
+
+// handleInlineQuery answers an inline query with the correction of its
+// text. A query is only checked once the user has paused typing for
+// cfg.InlineDebounce; until then, and for text too short to check, the
+// answer is a "type more" placeholder.
+func (gb *GrammarBot) handleInlineQuery(query *tgbotapi.InlineQuery) {
+	userID := query.From.ID
+	text := strings.TrimSpace(query.Query)
+	if text == "" {
+		gb.answerInline(query.ID, 1)
+		return
+	}
+	if countWords(text) < max(gb.cfg.MinWords, 1) {
+		gb.answerInline(query.ID, 1, inlinePlaceholder(query, "Type more…", "Write a sentence and I'll check it as you type."))
+		return
+	}
+
+	// Marked parts are checked on their own, as in messages
+	if scoped, ok := scopedText(text, gb.cfg.ScopeMarker); ok {
+		text = scoped
+	}
+	opts := gb.checkOptions(userID, query.From, nil)
+	if corrected, ok := gb.inline.lastResult(userID, text, opts); ok {
+		gb.answerCorrection(query, corrected, opts)
+		return
+	}
+
+	// Superseded queries are stale on the user's side, so a placeholder will do
+	if replaced := gb.inline.wait(userID, query); replaced != nil {
+		gb.answerInline(replaced.ID, 1, inlinePlaceholder(replaced, "Type more…", "I'll check your text when you pause typing."))
+	}
+
+	time.AfterFunc(gb.cfg.InlineDebounce, func() {
+		if !gb.inline.take(userID, query) {
+			return
+		}
+
+		// Inline queries can't ask which language is meant, so an unsure
+		// detection goes with the fallback language
+		checkOpts := opts
+		if settings := gb.store.GetUserSettings(userID); settings.AutoLanguage {
+			language, sure, err := gb.detectedLanguage(userID, text)
+			if err != nil {
+				log.Printf("Error detecting language: %v", err)
+			}
+			if !sure {
+				language = gb.fallbackLanguage(settings)
+			}
+			checkOpts.Language = language
+		}
+
+		corrected, err := gb.checkGrammar(text, checkOpts)
+		if err != nil {
+			gb.logError("Error checking inline query: %v", err)
+			if gb.ctx.Err() != nil {
+				return
+			}
+			gb.answerInline(query.ID, 1, inlinePlaceholder(query, "Couldn't check right now", "Sorry, the grammar checker is unavailable. Please try again later."))
+			return
+		}
+		gb.inline.remember(userID, inlineResult{text: text, opts: opts, corrected: corrected}, inlineResultTTL)
+		gb.answerCorrection(query, corrected, checkOpts)
+	})
+}
+
+// correctedPlainText returns the corrected text of a correction, without
+// markup.
+func correctedPlainText(correctedMarkup string) string {
+	if edits, err := parseInlineEdits(correctedMarkup); err == nil {
+		return correctedText(edits)
+	}
+	return stripMarkdownV2(correctedMarkup)
+}
+
+// useCorrectionButton offers putting the corrected text of text into the
+// message field, as an inline query of the bot. Choosing the inline result
+// sends it, and editing it first re-checks it the same way the message was
+// checked. It returns nil when inline queries are off, there is nothing
+// corrected, or the text is too long for an inline query.
+func (gb *GrammarBot) useCorrectionButton(text, correctedMarkup string) []tgbotapi.InlineKeyboardButton {
+	if !gb.features.get().Inline || !gb.bot.Self.SupportsInlineQueries || !hasCorrections(text, correctedMarkup) {
+		return nil
+	}
+	plain := correctedPlainText(correctedMarkup)
+	if utf16Len(plain) > maxInlineQueryLength {
+		return nil
+	}
+	return []tgbotapi.InlineKeyboardButton{{Text: "✏️ Use the corrected text", SwitchInlineQueryCurrentChat: &plain}}
+}
+
+// answerCorrection offers sending the corrected text, plain or with the
+// corrections marked up.
+func (gb *GrammarBot) answerCorrection(query *tgbotapi.InlineQuery, correctedMarkup string, opts CorrectOptions) {
+	plain := correctedPlainText(correctedMarkup)
+
+	clean := tgbotapi.NewInlineQueryResultArticle("corrected", "✅ Send the corrected text", plain)
+	clean.Description = plain
+
+	marked, parseMode := validateParseMode(renderCorrection(correctedMarkup, opts, gb.userStyle(query.From.ID), gb.cfg.MaxHighlights), "MarkdownV2")
+	annotated := tgbotapi.NewInlineQueryResultArticle("annotated", "📝 Send with the corrections marked", marked)
+	annotated.InputMessageContent = tgbotapi.InputTextMessageContent{Text: marked, ParseMode: parseMode}
+	if parseMode == "MarkdownV2" {
+		annotated.Description = stripMarkdownV2(marked)
+	} else {
+		annotated.Description = marked
+	}
+
+	gb.answerInline(query.ID, inlineResultCacheTime, clean, annotated)
+}
+
+// inlinePlaceholder is a result that sends the query's text unchanged.
+func inlinePlaceholder(query *tgbotapi.InlineQuery, title, description string) tgbotapi.InlineQueryResultArticle {
+	result := tgbotapi.NewInlineQueryResultArticle("placeholder", title, query.Query)
+	result.Description = description
+	return result
+}
+
+// answerInline answers an inline query with results. The messages they send
+// are prepared like those of send.
+func (gb *GrammarBot) answerInline(queryID string, cacheTime int, results ...tgbotapi.InlineQueryResultArticle) {
+	answer := tgbotapi.InlineConfig{
+		InlineQueryID: queryID,
+		Results:       make([]interface{}, 0, len(results)),
+		CacheTime:     cacheTime,
+		IsPersonal:    true,
+	}
+	for _, result := range results {
+		if content, ok := result.InputMessageContent.(tgbotapi.InputTextMessageContent); ok {
+			content.Text, content.ParseMode = gb.outgoingText(content.Text, content.ParseMode)
+			result.InputMessageContent = content
+		}
+		answer.Results = append(answer.Results, result)
+	}
+	if _, err := gb.request(answer); err != nil {
+		log.Printf("Error answering inline query: %v", err)
+	}
+}
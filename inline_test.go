@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TestInlineAndDirectCheckAlike checks the same text as a private message
+// and as an inline query, as after using the corrected text button, and
+// checks both reach the engine with the same options and show the same
+// correction.
+func TestInlineAndDirectCheckAlike(t *testing.T) {
+	var mu sync.Mutex
+	var seen []CorrectOptions
+	engine := &fakeEngine{correct: func(ctx context.Context, text string, opts CorrectOptions) (string, error) {
+		mu.Lock()
+		seen = append(seen, opts)
+		mu.Unlock()
+		return "She ~go~ **goes** home today\\.", nil
+	}}
+	gb, tg := newTestBot(t, testConfig(t, map[string]string{"INLINE_DEBOUNCE": "1ms"}), engine)
+	for _, text := range []string{"/language German", "/strictness high", "/categories grammar", "/style arrows"} {
+		gb.handleCommand(command(7, text))
+	}
+	replies := len(tg.callsTo("sendMessage"))
+
+	const text = "She go home today."
+	gb.handleMessage(privateMessage(7, text))
+	gb.handleInlineQuery(&tgbotapi.InlineQuery{ID: "q1", From: &tgbotapi.User{ID: 7, FirstName: "Ann"}, Query: text})
+	waitFor(t, "the inline answer", func() bool { return len(tg.callsTo("answerInlineQuery")) > 0 })
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != 2 {
+		t.Fatalf("engine called %d times, want once for each entry point", len(seen))
+	}
+	if seen[0] != seen[1] {
+		t.Errorf("direct options %+v, inline options %+v, want the same", seen[0], seen[1])
+	}
+	if seen[0].Language != "German" || seen[0].Strictness != strictnessHigh || seen[0].Categories != "grammar" {
+		t.Errorf("options %+v don't follow the user's settings", seen[0])
+	}
+
+	direct := tg.callsTo("sendMessage")[replies].Get("text")
+	var results []struct {
+		ID                  string `json:"id"`
+		InputMessageContent struct {
+			MessageText string `json:"message_text"`
+		} `json:"input_message_content"`
+	}
+	if err := json.Unmarshal([]byte(tg.callsTo("answerInlineQuery")[0].Get("results")), &results); err != nil {
+		t.Fatal(err)
+	}
+	annotated := ""
+	for _, result := range results {
+		if result.ID == "annotated" {
+			annotated = result.InputMessageContent.MessageText
+		}
+	}
+	if annotated != direct {
+		t.Errorf("inline correction %q, direct correction %q, want the same", annotated, direct)
+	}
+}
+
+// TestInlineResultsForgotten checks that remembered inline corrections are
+// dropped once they expire, but not while a newer one replaces them.
+func TestInlineResultsForgotten(t *testing.T) {
+	var queries inlineQueries
+	opts := CorrectOptions{Language: defaultLanguage}
+
+	queries.remember(7, inlineResult{text: "She go home.", opts: opts, corrected: "She goes home."}, 20*time.Millisecond)
+	queries.remember(8, inlineResult{text: "He go home.", opts: opts, corrected: "He goes home."}, 20*time.Millisecond)
+	queries.remember(8, inlineResult{text: "He go out.", opts: opts, corrected: "He goes out."}, time.Hour)
+	if corrected, ok := queries.lastResult(7, "She go home.", opts); !ok || corrected != "She goes home." {
+		t.Errorf("lastResult() = %q, %v before expiry, want the correction", corrected, ok)
+	}
+
+	waitFor(t, "the first results to expire", func() bool { return queries.size() == 1 })
+	if _, ok := queries.lastResult(7, "She go home.", opts); ok {
+		t.Error("an expired result was returned")
+	}
+	if corrected, ok := queries.lastResult(8, "He go out.", opts); !ok || corrected != "He goes out." {
+		t.Errorf("the replacing result was dropped with the one it replaced")
+	}
+}
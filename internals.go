@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"runtime"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxInlineInternals is the longest dump sent as a message; longer ones are
+// sent as a file.
+const maxInlineInternals = 3500
+
+// internalsSnapshot is the runtime state /internals reports.
+type internalsSnapshot struct {
+	Time       time.Time `json:"time"`
+	Uptime     string    `json:"uptime"`
+	Goroutines int       `json:"goroutines"`
+
+	Queue struct {
+		Depth    int `json:"depth"`
+		Capacity int `json:"capacity"`
+	} `json:"queue"`
+	Workers struct {
+		Active      int64   `json:"active"`
+		Total       int     `json:"total"`
+		Utilization float64 `json:"utilization"`
+	} `json:"workers"`
+	Polling struct {
+		CommittedOffset int `json:"committed_offset"`
+		SeenUpdates     int `json:"seen_updates"`
+	} `json:"polling"`
+	Stats Stats `json:"stats"`
+
+	// Sizes of the in-memory per-user state
+	SoftLimitUsers   int `json:"soft_limit_users"`
+	DebounceEntries  int `json:"debounce_entries"`
+	PracticeSessions int `json:"practice_sessions"`
+	InlineUsers      int `json:"inline_users"`
+	LinkedChats      int `json:"linked_chats"`
+	DeferredChecks   int `json:"deferred_checks"`
+
+	Store storeStats `json:"store"`
+}
+
+// internals captures the bot's current runtime state.
+func (gb *GrammarBot) internals() internalsSnapshot {
+	var s internalsSnapshot
+	s.Time = time.Now().UTC()
+	s.Uptime = time.Since(gb.started).Round(time.Second).String()
+	s.Goroutines = runtime.NumGoroutine()
+
+	s.Queue.Depth, s.Queue.Capacity = len(gb.queue), cap(gb.queue)
+
+	s.Stats = gb.Snapshot()
+
+	s.Workers.Active, s.Workers.Total = s.Stats.ActiveWorkers, gb.cfg.Workers
+	s.Workers.Utilization = float64(s.Workers.Active) / float64(s.Workers.Total)
+
+	s.Polling.CommittedOffset = gb.store.Offset()
+	s.Polling.SeenUpdates = gb.seen.size()
+
+	s.SoftLimitUsers = gb.usage.size()
+	s.DebounceEntries = gb.debounce.size()
+	s.PracticeSessions = gb.practice.size()
+	s.InlineUsers = gb.inline.size()
+	s.LinkedChats = gb.linked.size()
+	s.DeferredChecks = len(gb.store.DeferredChecks())
+	s.Store = gb.store.Stats()
+
+	return s
+}
+
+// handleInternalsCommand sends admins a JSON dump of the runtime state, as a
+// code block or, when too long for a message, as a file.
+func (gb *GrammarBot) handleInternalsCommand(message *tgbotapi.Message, args string) {
+	data, err := json.MarshalIndent(gb.internals(), "", "  ")
+	if err != nil {
+		log.Printf("Error encoding internals: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't encode the internals."))
+		return
+	}
+
+	if len(data) <= maxInlineInternals {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "```json\n"+escapeCodeBlock(string(data))+"\n```")
+		msg.ParseMode = "MarkdownV2"
+		gb.send(msg)
+		return
+	}
+
+	doc := tgbotapi.NewDocument(message.Chat.ID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("internals-%s.json", time.Now().UTC().Format("20060102-150405")),
+		Bytes: data,
+	})
+	if _, err := gb.send(doc); err != nil {
+		log.Printf("Error sending internals: %v", err)
+	}
+}
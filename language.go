@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// maxRecentLanguages is how many recently used languages are remembered.
+	maxRecentLanguages = 4
+	// maxLanguageLength bounds language names so they fit in callback data.
+	maxLanguageLength = 32
+
+	recheckCallbackPrefix = "recheck:"
+)
+
+// normalizeLanguage validates a user-supplied language name and capitalizes it.
+func normalizeLanguage(name string) (string, bool) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" || len(name) > maxLanguageLength {
+		return "", false
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && r != ' ' && r != '-' {
+			return "", false
+		}
+	}
+	return capitalize(name), true
+}
+
+// effectiveLanguage returns the correction language of settings.
+func effectiveLanguage(settings UserSettings) string {
+	if settings.Language == "" {
+		return defaultLanguage
+	}
+	return settings.Language
+}
+
+// rememberLanguage moves language to the front of the user's recent languages.
+func (gb *GrammarBot) rememberLanguage(userID int64, language string) {
+	err := gb.store.UpdateUserSettings(userID, func(settings *UserSettings) {
+		recent := []string{language}
+		for _, l := range settings.RecentLanguages {
+			if !strings.EqualFold(l, language) && len(recent) < maxRecentLanguages {
+				recent = append(recent, l)
+			}
+		}
+		settings.RecentLanguages = recent
+	})
+	if err != nil {
+		log.Printf("Error saving recent languages: %v", err)
+	}
+}
+
+// languageKeyboard offers re-checking a correction in the user's other recent
+// languages. It returns nil when there is nothing to offer.
+func (gb *GrammarBot) languageKeyboard(userID int64, current string) *tgbotapi.InlineKeyboardMarkup {
+	var buttons []tgbotapi.InlineKeyboardButton
+	for _, language := range gb.store.GetUserSettings(userID).RecentLanguages {
+		if strings.EqualFold(language, current) {
+			continue
+		}
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData("🔁 "+language, recheckCallbackPrefix+language))
+	}
+	if len(buttons) == 0 {
+		return nil
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(buttons)
+	return &keyboard
+}
+
+// handleLanguageCommand shows or sets the user's correction language.
+func (gb *GrammarBot) handleLanguageCommand(message *tgbotapi.Message, arg string) {
+	userID := senderID(message)
+	settings := gb.store.GetUserSettings(userID)
+
+	if arg == "" {
+		reply := fmt.Sprintf("I'm correcting your messages in %s. Use /language <name>, for example /language German, to change it, or /language auto to have me detect it.", effectiveLanguage(settings))
+		if settings.AutoLanguage {
+			reply = "I detect the language of each of your messages and ask when I'm not sure. Use /language <name> to correct everything in one language."
+		}
+		if locked := gb.chatLanguage(message.Chat); locked != "" {
+			reply = fmt.Sprintf("This chat's admins have set all corrections here to %s. Elsewhere I correct your messages in %s.", locked, effectiveLanguage(settings))
+		}
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, reply))
+		return
+	}
+
+	if strings.EqualFold(arg, "auto") {
+		if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) { stored.AutoLanguage = true }); err != nil {
+			log.Printf("Error saving settings: %v", err)
+			gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save your settings. Please try again later."))
+			return
+		}
+		gb.replyWithSettings(message, "Got it, I'll detect the language of each message, and ask you when I'm not sure.")
+		return
+	}
+
+	language, ok := normalizeLanguage(arg)
+	if !ok {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Please give a language name like German or Brazilian Portuguese."))
+		return
+	}
+
+	if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) {
+		stored.Language = language
+		stored.AutoLanguage = false
+	}); err != nil {
+		log.Printf("Error saving settings: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+	gb.rememberLanguage(userID, language)
+
+	gb.replyWithSettings(message, fmt.Sprintf("Got it, I'll correct your messages in %s.", language))
+}
+
+// handleRecheckCallback re-runs the correction of a result in another
+// language and edits the bot's message in place. Only the author of the
+// original message may re-check it.
+func (gb *GrammarBot) handleRecheckCallback(query *tgbotapi.CallbackQuery, language string) {
+	language, ok := normalizeLanguage(language)
+	if !ok {
+		gb.answerCallback(tgbotapi.NewCallback(query.ID, ""))
+		return
+	}
+
+	message := query.Message
+	original := message.ReplyToMessage
+	if original == nil {
+		gb.answerCallback(tgbotapi.NewCallback(query.ID, "The original message is no longer available."))
+		return
+	}
+	// The correction is the author's, checked with their settings
+	authorID := senderID(original)
+	if authorID != query.From.ID {
+		gb.answerCallback(tgbotapi.NewCallback(query.ID, "Only the author of the message can re-check it."))
+		return
+	}
+
+	text := original.Text
+	if parsed, ok := parseCommand(original); ok {
+		text = parsed.Args
+	}
+	if text == "" {
+		gb.answerCallback(tgbotapi.NewCallback(query.ID, "The original message is no longer available."))
+		return
+	}
+
+	if locked := gb.chatLanguage(message.Chat); locked != "" {
+		gb.answerCallback(tgbotapi.NewCallback(query.ID, fmt.Sprintf("Corrections in this chat are always in %s.", locked)))
+		return
+	}
+
+	gb.answerCallback(tgbotapi.NewCallback(query.ID, fmt.Sprintf("Re-checking in %s…", language)))
+
+	opts := gb.checkOptions(authorID, original.From, message.Chat)
+	opts.Language = language
+
+	correctedText, err := gb.checkGrammar(text, opts)
+	if err != nil {
+		gb.logError("Error re-checking grammar: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I encountered an error while checking your grammar. Please try again later."))
+		return
+	}
+	gb.rememberLanguage(authorID, language)
+
+	edit := tgbotapi.NewEditMessageText(message.Chat.ID, message.MessageID, renderCorrection(correctedText, opts, gb.userStyle(authorID), gb.cfg.MaxHighlights))
+	edit.ParseMode = "MarkdownV2"
+	variant := feedbackVariant(gb.resolveModel(text, opts))
+	edit.ReplyMarkup = gb.correctionKeyboard(message.Chat, authorID, language, variant, text, correctedText)
+	if _, err := gb.send(edit); err != nil {
+		log.Printf("Error editing message: %v", err)
+		return
+	}
+	gb.recordSent(edit.ReplyMarkup, variant)
+}
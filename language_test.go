@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TestRecheckOnlyByAuthor checks that a re-check button tapped by someone
+// else leaves the correction alone, and that the author's tap re-checks with
+// the author's settings and history.
+func TestRecheckOnlyByAuthor(t *testing.T) {
+	var checks []CorrectOptions
+	engine := &fakeEngine{correct: func(ctx context.Context, text string, opts CorrectOptions) (string, error) {
+		checks = append(checks, opts)
+		return text, nil
+	}}
+	gb, tg := newTestBot(t, testConfig(t, nil), engine)
+	gb.handleCommand(command(7, "/strictness high"))
+
+	group := &tgbotapi.Chat{ID: -100, Type: "supergroup"}
+	original := privateMessage(7, "Sie gehen nach Hause.")
+	original.Chat = group
+	tap := func(userID int64) *tgbotapi.CallbackQuery {
+		return &tgbotapi.CallbackQuery{
+			ID:      "query",
+			From:    &tgbotapi.User{ID: userID, FirstName: "Bob"},
+			Message: &tgbotapi.Message{MessageID: 2, Chat: group, ReplyToMessage: original},
+			Data:    recheckCallbackPrefix + "German",
+		}
+	}
+
+	gb.handleRecheckCallback(tap(8), "German")
+	if len(checks) != 0 || len(tg.callsTo("editMessageText")) != 0 {
+		t.Fatal("a tap by someone else re-checked the correction")
+	}
+	if got := tg.callsTo("answerCallbackQuery")[0].Get("text"); got != "Only the author of the message can re-check it." {
+		t.Errorf("answer = %q, want the author-only notice", got)
+	}
+	if recent := gb.store.GetUserSettings(8).RecentLanguages; len(recent) != 0 {
+		t.Errorf("the tapper's history got %q", recent)
+	}
+
+	gb.handleRecheckCallback(tap(7), "German")
+	if len(checks) != 1 || checks[0].Strictness != strictnessHigh || checks[0].Language != "German" {
+		t.Fatalf("author's re-check made with %+v, want their settings in German", checks)
+	}
+	if recent := gb.store.GetUserSettings(7).RecentLanguages; len(recent) == 0 || recent[0] != "German" {
+		t.Errorf("author's recent languages = %q, want German first", recent)
+	}
+}
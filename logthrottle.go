@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// repeatedLog is a message logged recently, with how many times it
+// recurred since.
+type repeatedLog struct {
+	repeats int
+}
+
+// logLimiter logs each distinct message at most once per interval, then
+// how many times it recurred during the interval, so an outage failing
+// every check doesn't flood the log. It is safe for concurrent use.
+type logLimiter struct {
+	mu     sync.Mutex
+	recent map[string]*repeatedLog
+}
+
+// printf logs the formatted message unless it was logged less than interval
+// ago. A zero interval logs every message.
+func (l *logLimiter) printf(interval time.Duration, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	if interval <= 0 {
+		log.Print(message)
+		return
+	}
+
+	l.mu.Lock()
+	if l.recent == nil {
+		l.recent = make(map[string]*repeatedLog)
+	}
+	if entry, ok := l.recent[message]; ok {
+		entry.repeats++
+		l.mu.Unlock()
+		return
+	}
+	entry := &repeatedLog{}
+	l.recent[message] = entry
+	l.mu.Unlock()
+
+	log.Print(message)
+	time.AfterFunc(interval, func() {
+		l.mu.Lock()
+		delete(l.recent, message)
+		repeats := entry.repeats
+		l.mu.Unlock()
+
+		if repeats > 0 {
+			log.Printf("%s (repeated %d more times in %s)", message, repeats, interval)
+		}
+	})
+}
+
+// logError logs an error from a path that fails for every message during an
+// outage, such as checking grammar. Repeats are counted, not logged, for
+// cfg.LogRepeatInterval.
+func (gb *GrammarBot) logError(format string, args ...any) {
+	gb.errorLogs.printf(gb.cfg.LogRepeatInterval, format, args...)
+}
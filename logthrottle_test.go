@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// logBuffer collects log output, which the limiter also writes from timers.
+type logBuffer struct {
+	mu    sync.Mutex
+	lines strings.Builder
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lines.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lines.String()
+}
+
+// captureLogs sends the log to a buffer for the rest of the test.
+func captureLogs(t *testing.T) *logBuffer {
+	t.Helper()
+	logs := &logBuffer{}
+	log.SetOutput(logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return logs
+}
+
+// TestLogLimiterSuppressesBurst logs a burst of identical errors and checks
+// that only the first is logged, followed by one summary with the count.
+func TestLogLimiterSuppressesBurst(t *testing.T) {
+	logs := captureLogs(t)
+	var limiter logLimiter
+
+	outage := errors.New("503 Service Unavailable")
+	for range 50 {
+		limiter.printf(50*time.Millisecond, "Error checking grammar: %v", outage)
+	}
+	limiter.printf(50*time.Millisecond, "Error fetching updates: %v", outage)
+
+	waitFor(t, "the repeat summary", func() bool { return strings.Contains(logs.String(), "repeated") })
+	got := logs.String()
+	if n := strings.Count(got, "Error checking grammar: 503 Service Unavailable\n"); n != 1 {
+		t.Errorf("the repeated error was logged %d times, want once:\n%s", n, got)
+	}
+	if !strings.Contains(got, "Error checking grammar: 503 Service Unavailable (repeated 49 more times in 50ms)") {
+		t.Errorf("no summary counting the 49 suppressed repeats:\n%s", got)
+	}
+	if !strings.Contains(got, "Error fetching updates: 503 Service Unavailable\n") {
+		t.Errorf("a different error was suppressed too:\n%s", got)
+	}
+	if strings.Contains(got, "Error fetching updates: 503 Service Unavailable (repeated") {
+		t.Errorf("an error logged once got a repeat summary:\n%s", got)
+	}
+
+	// Once the interval is over the error is logged again
+	time.Sleep(20 * time.Millisecond)
+	limiter.printf(50*time.Millisecond, "Error checking grammar: %v", outage)
+	if n := strings.Count(logs.String(), "Error checking grammar: 503 Service Unavailable\n"); n != 2 {
+		t.Errorf("the error was logged %d times after the interval, want twice", n)
+	}
+}
+
+func TestLogLimiterZeroIntervalLogsEverything(t *testing.T) {
+	logs := captureLogs(t)
+	var limiter logLimiter
+
+	for range 3 {
+		limiter.printf(0, "Error checking grammar: %v", "timeout")
+	}
+	if n := strings.Count(logs.String(), "Error checking grammar: timeout"); n != 3 {
+		t.Errorf("logged %d times with a zero interval, want 3", n)
+	}
+}
@@ -2,67 +2,251 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"google.golang.org/genai"
 )
 
 type GrammarBot struct {
-	bot   *tgbotapi.BotAPI
-	genAI *genai.Client
-	ctx   context.Context
+	bot    *tgbotapi.BotAPI
+	engine GrammarEngine
+	store  *Store
+	// ctx is passed to AI calls and cancelled by stopCalls on shutdown
+	ctx       context.Context
+	stopCalls context.CancelFunc
+	cfg       Config
+
+	// queue buffers updates between polling and the worker pool
+	queue   chan tgbotapi.Update
+	metrics *Metrics
+	cache   *correctionCache
+	// synonyms caches /synonyms lookups by phrase and language
+	synonyms *correctionCache
+	linked   linkedChats
+	// telemetry is nil unless OTEL_EXPORTER_OTLP_ENDPOINT is set
+	telemetry *telemetry
+
+	debounce  debouncer
+	usage     usageTracker
+	commands  commandRegistry
+	seen      seenUpdates
+	practice  practiceSessions
+	started   time.Time
+	deletions deleteScheduler
+	typing    typingCoalescer
+	business  businessConnections
+	extras    updateExtras
+	inline    inlineQueries
+	greetings greetingCooldowns
+	offsets   offsetTracker
+	replies   trackedReplies
+	features  featureSwitches
+	flood     floodWait
+	errorLogs logLimiter
+
+	languageSessions languageSessions
+	editChecks       editChecks
+	digests          digests
+	chatCooldowns    chatCooldowns
+	previews         previews
 }
 
-func NewGrammarBot(telegramToken, geminiAPIKey string) (*GrammarBot, error) {
+func NewGrammarBot(cfg Config) (*GrammarBot, error) {
 	// Initialize Telegram bot
-	bot, err := tgbotapi.NewBotAPI(telegramToken)
+	bot, err := tgbotapi.NewBotAPI(cfg.TelegramToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create telegram bot: %w", err)
 	}
 
-	// Initialize Gemini AI client
-	ctx := context.Background()
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  geminiAPIKey,
-		Backend: genai.BackendGeminiAPI,
-	})
+	// Initialize the AI backend
+	ctx, stopCalls := context.WithCancel(context.Background())
+	engine, err := newEngine(ctx, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create genai client: %w", err)
+		stopCalls()
+		return nil, err
+	}
+
+	// Load persisted user settings
+	store, err := NewStore(cfg.StorePath)
+	if err != nil {
+		stopCalls()
+		return nil, err
+	}
+
+	gb := newGrammarBot(ctx, stopCalls, cfg, bot, engine, store)
+
+	if cfg.Maintenance && !store.Maintenance() {
+		if err := store.SetMaintenance(true); err != nil {
+			stopCalls()
+			return nil, fmt.Errorf("failed to enable maintenance mode: %w", err)
+		}
+		log.Println("Maintenance mode turned on by MAINTENANCE")
+	}
+
+	return gb, nil
+}
+
+// newGrammarBot assembles a bot from its parts. AI calls are made under
+// ctx, which stopCalls cancels.
+func newGrammarBot(ctx context.Context, stopCalls context.CancelFunc, cfg Config, bot *tgbotapi.BotAPI, engine GrammarEngine, store *Store) *GrammarBot {
+	gb := &GrammarBot{
+		bot:       bot,
+		engine:    engine,
+		store:     store,
+		ctx:       ctx,
+		stopCalls: stopCalls,
+		cfg:       cfg,
+		queue:     make(chan tgbotapi.Update, cfg.QueueSize),
+		metrics:   &Metrics{},
+		cache:     newCorrectionCache(cfg.CacheSize, cfg.CacheTTL),
+		synonyms:  newCorrectionCache(synonymsCacheSize, synonymsCacheTTL),
+		started:   time.Now(),
+		features:  featureSwitches{current: cfg.Features},
+	}
+	if cfg.OTLPEndpoint != "" {
+		gb.telemetry = newTelemetry(cfg.OTLPEndpoint, cfg.OTelServiceName)
 	}
+	gb.registerCommandHandlers()
+	return gb
+}
 
-	return &GrammarBot{
-		bot:   bot,
-		genAI: client,
-		ctx:   ctx,
-	}, nil
+func (gb *GrammarBot) checkGrammar(text string, opts CorrectOptions) (string, error) {
+	return gb.checkGrammarContext(gb.ctx, text, opts)
 }
 
-func (gb *GrammarBot) checkGrammar(text string) (string, error) {
-	result, err := gb.genAI.Models.GenerateContent(
-		gb.ctx,
-		"gemini-2.5-flash-preview-05-20",
-		genai.Text(fmt.Sprintf(`System:
-You are a world-class English language assistant specializing in grammar and vocabulary correction for Telegram messages using MarkdownV2. When given a user’s sentence, you must:
+// checkGrammarContext is checkGrammar with AI calls made under ctx, so a
+// check that is no longer wanted can be cancelled.
+func (gb *GrammarBot) checkGrammarContext(ctx context.Context, text string, opts CorrectOptions) (string, error) {
+	key := gb.cacheKey(text, opts)
+	if correctedText, ok := gb.cache.get(key); ok {
+		return correctedText, nil
+	}
+	gb.logModel(key.opts.Model, text)
 
-1. Identify all grammar, spelling, punctuation or word-choice mistakes.  
-2. Escape every special MarkdownV2 character (_ * [ ] ( ) ~  > # + - = | { } . !) by prefixing it with a backslash.  
-3. Wrap each original mistake in ~strikethrough~ and each correction in **bold**, using valid MarkdownV2 syntax.  
-4. Preserve the original meaning, tone and style.  
-5. Return exactly the single corrected sentence with those inline edits—no explanations, comments or extra text.
+	correctedText, err := gb.correct(ctx, text, key.opts)
+	if err == nil {
+		gb.cache.put(key, correctedText)
+	}
+	return correctedText, err
+}
 
-User:
-%s`, text)),
-		nil,
-	)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate content: %w", err)
+// correct runs a check on the AI backend, in batches of sentences when the
+// text is long enough for cfg.SentenceSplitChars. Explanations are listed
+// after the whole correction, so explain mode always corrects in one go.
+func (gb *GrammarBot) correct(ctx context.Context, text string, opts CorrectOptions) (string, error) {
+	if gb.cfg.SentenceSplitChars > 0 && !opts.Explain && utf8.RuneCountInString(text) >= gb.cfg.SentenceSplitChars {
+		return gb.correctInBatches(ctx, text, opts)
+	}
+	return gb.correctOnce(ctx, text, opts)
+}
+
+// correctOnce sends text to the AI backend in a single request.
+func (gb *GrammarBot) correctOnce(ctx context.Context, text string, opts CorrectOptions) (string, error) {
+	ctx = contextWithMetrics(ctx, gb.metrics)
+	var correctedText string
+	err := gb.retry(ctx, func() error {
+		s := spanFromContext(ctx).child("ai.correct", spanKindClient)
+		s.set("ai.model", opts.Model)
+		s.set("ai.format", opts.Format)
+		done := gb.metrics.beginCall()
+		var err error
+		correctedText, err = gb.engine.Correct(ctx, text, opts)
+		done(err)
+		s.end(err)
+		return err
+	})
+	return correctedText, err
+}
+
+// cacheKey resolves the model for a check and returns its cache key.
+func (gb *GrammarBot) cacheKey(text string, opts CorrectOptions) cacheKey {
+	opts.Model = gb.resolveModel(text, opts)
+	// Verbosity only changes rendering, so every level shares a correction
+	opts.Verbosity = ""
+	return cacheKey{text: text, opts: opts}
+}
+
+// complete runs a non-correction task on the AI backend with the standard
+// model.
+func (gb *GrammarBot) complete(instructions, text string) (string, error) {
+	model := gb.modelName(modelStandard)
+	gb.logModel(model, text)
+
+	var answer string
+	err := gb.retry(gb.ctx, func() error {
+		done := gb.metrics.beginCall()
+		var err error
+		answer, err = gb.engine.Complete(gb.ctx, Prompt{Instructions: instructions, Model: model}, text)
+		done(err)
+		return err
+	})
+	return answer, err
+}
+
+// correctOptions resolves the correction options for the sender of message.
+func (gb *GrammarBot) correctOptions(message *tgbotapi.Message) CorrectOptions {
+	return gb.checkOptions(senderID(message), message.From, message.Chat)
+}
+
+// checkOptions resolves the correction options of userID checking a text in
+// chat, or in an inline query when chat is nil. Messages, /check and inline
+// queries all go through it, so a text is corrected the same wherever it is
+// checked. A group's language lock takes precedence over the user's
+// language.
+func (gb *GrammarBot) checkOptions(userID int64, user *tgbotapi.User, chat *tgbotapi.Chat) CorrectOptions {
+	opts := gb.optionsForUser(userID, user)
+	if language := gb.chatLanguage(chat); language != "" {
+		opts.Language = language
+	}
+	return opts
+}
+
+// optionsForUser resolves the correction options from a user's settings.
+// user provides the Telegram language for native explanations and may be nil.
+func (gb *GrammarBot) optionsForUser(userID int64, user *tgbotapi.User) CorrectOptions {
+	settings := gb.store.GetUserSettings(userID)
+	opts := CorrectOptions{
+		Model:           gb.preferredModel(userID, settings),
+		Language:        effectiveLanguage(settings),
+		FlagOnly:        settings.FlagOnly,
+		Explain:         settings.Explain,
+		ExplainLanguage: explanationLanguage(settings, user),
+		Mixed:           settings.Mixed,
+		Strictness:      effectiveStrictness(settings),
+		Format:          gb.cfg.CorrectionFormat,
+		Verbosity:       effectiveVerbosity(settings),
+		Categories:      strings.Join(settings.Categories, ","),
+	}
+	// Minimal replies have no room for explanations, and detailed ones
+	// always have them
+	switch opts.Verbosity {
+	case verbosityMinimal:
+		opts.Explain = false
+	case verbosityDetailed:
+		opts.Explain = true
 	}
+	return opts
+}
 
-	return result.Text(), nil
+// senderID identifies whose settings apply to message. Messages sent on
+// behalf of a chat, such as channel posts, use that chat's ID.
+func senderID(message *tgbotapi.Message) int64 {
+	if message.SenderChat != nil {
+		return message.SenderChat.ID
+	}
+	if message.From != nil {
+		return message.From.ID
+	}
+	return message.Chat.ID
 }
 
 func (gb *GrammarBot) handleMessage(message *tgbotapi.Message) {
@@ -71,121 +255,436 @@ func (gb *GrammarBot) handleMessage(message *tgbotapi.Message) {
 		return
 	}
 
-	// Send "typing" action to show bot is processing
-	typingAction := tgbotapi.NewChatAction(message.Chat.ID, tgbotapi.ChatTyping)
-	gb.bot.Send(typingAction)
+	if gb.features.get().Practice && gb.handlePracticeAnswer(message) {
+		return
+	}
+
+	// Text sent from the bot's inline results was checked as it was typed
+	if message.ViaBot != nil && message.ViaBot.ID == gb.bot.Self.ID {
+		return
+	}
+
+	// In mention-only groups, check only what mentions of the bot come with
+	text := message.Text
+	if !message.Chat.IsPrivate() && gb.store.GetChatSettings(message.Chat.ID).MentionOnly {
+		mentioned, ok := gb.mentionedText(message)
+		if !ok {
+			return
+		}
+		text = mentioned
+	}
+
+	// Check only the marked parts of a message, if it has any
+	if scoped, ok := scopedText(text, gb.cfg.ScopeMarker); ok {
+		gb.autoCheck(message, scoped)
+		return
+	}
+
+	// Skip trivial private messages like "ok" or "thanks"; /check still works
+	if message.Chat.IsPrivate() && countWords(text) < gb.cfg.MinWords {
+		return
+	}
+
+	gb.autoCheck(message, text)
+}
+
+// checkAndReply checks text and replies to message with the correction.
+func (gb *GrammarBot) checkAndReply(message *tgbotapi.Message, text string) {
+	opts := gb.correctOptions(message)
+	if gb.resolveDetectedLanguage(message, text, &opts) {
+		return
+	}
 
-	// Check grammar using Gemini AI
-	correctedText, err := gb.checkGrammar(message.Text)
+	// Show the bot is processing, unless the answer is cached and instant
+	stopTyping := func() {}
+	if _, cached := gb.cache.peek(gb.cacheKey(text, opts)); !cached {
+		stopTyping = gb.startTyping(message.Chat.ID)
+	}
+
+	// Check grammar using the AI backend
+	correctedText, err := gb.checkGrammarContext(gb.messageContext(message), text, opts)
+	stopTyping()
 	if err != nil {
-		log.Printf("Error checking grammar: %v", err)
+		gb.logError("Error checking grammar: %v", err)
+
+		// The message is checked again after the restart
+		if gb.ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, ErrSafetyBlocked) {
+			blockedMsg := tgbotapi.NewMessage(message.Chat.ID, gb.cfg.SafetyBlockMessage)
+			blockedMsg.ReplyToMessageID = message.MessageID
+			gb.send(blockedMsg)
+			return
+		}
+		if gb.deferIfRateLimited(message, text, err) {
+			return
+		}
+		if gb.cfg.OfflineFallback {
+			gb.replyWithBasicCorrections(message, text)
+			return
+		}
 
 		errorMsg := tgbotapi.NewMessage(message.Chat.ID, "Sorry, I encountered an error while checking your grammar. Please try again later.")
 		errorMsg.ReplyToMessageID = message.MessageID
-		gb.bot.Send(errorMsg)
+		gb.send(errorMsg)
+		return
+	}
+
+	gb.replyWithCorrection(message, text, opts, correctedText)
+}
+
+// replyWithCorrection records a successful check and replies to message
+// with the correction.
+func (gb *GrammarBot) replyWithCorrection(message *tgbotapi.Message, text string, opts CorrectOptions, correctedText string) {
+	userID := senderID(message)
+	gb.rememberLanguage(userID, opts.Language)
+	versionDiff := gb.versionDiff(userID, text)
+	tip, hasTip := gb.mistakeTip(userID, opts, correctedText)
+	if err := gb.store.AppendHistory(userID, HistoryEntry{
+		Time:      time.Now().UTC(),
+		Original:  text,
+		Corrected: correctedText,
+	}); err != nil {
+		log.Printf("Error saving history: %v", err)
+	}
+	streakNote := gb.streakNote(userID)
+
+	// Digest mode sends corrections together later, and nothing for
+	// messages without mistakes
+	if gb.store.GetUserSettings(userID).Digest {
+		if hasCorrections(text, correctedText) {
+			gb.addToDigest(message, opts, correctedText)
+		}
+		return
+	}
+
+	if gb.reactIfClean(message, text, correctedText) {
 		return
 	}
 
 	// Prepare response message
-	responseText := fmt.Sprintf("📝 Grammar check for your message:\n\n%s", correctedText)
+	msg := tgbotapi.NewMessage(message.Chat.ID, renderCorrection(correctedText, opts, gb.userStyle(userID), gb.cfg.MaxHighlights))
+	msg.ReplyToMessageID = message.MessageID
+	msg.ParseMode = "MarkdownV2"
+	variant := feedbackVariant(gb.resolveModel(text, opts))
+	keyboard := gb.correctionKeyboard(message.Chat, userID, opts.Language, variant, text, correctedText)
+	if keyboard != nil {
+		msg.ReplyMarkup = keyboard
+	}
+
+	// React instead of sending the chat the same correction again
+	if gb.isRepeatReply(message.Chat.ID, msg.Text) {
+		gb.acknowledgeRepeat(message.Chat.ID, message.MessageID)
+		return
+	}
+	if versionDiff != "" {
+		msg.Text += "\n\n" + versionDiff
+	}
+	if streakNote != "" {
+		msg.Text += "\n\n" + streakNote
+	}
+	if hasTip {
+		msg.Text += "\n\n" + gb.tipNote(userID, tip)
+	}
+
+	// Send the corrected text, held back for users over the soft usage limit
+	gb.deliverAfter(message.Chat.ID, gb.throttleDelay(userID), func() {
+		sent, err := gb.send(msg)
+		if err != nil {
+			log.Printf("Error sending message: %v", err)
+			return
+		}
+		gb.recordSent(keyboard, variant)
+		gb.replies.track(messageKey{message.Chat.ID, message.MessageID}, sent.MessageID)
+		gb.scheduleAutoDelete(message.Chat.ID, sent.MessageID)
+	})
+}
+
+// replyWithBasicCorrections replies with rule-based corrections, used as a last
+// resort when the AI backend is unavailable.
+func (gb *GrammarBot) replyWithBasicCorrections(message *tgbotapi.Message, text string) {
+	correctedText, changed := basicCorrect(text)
+
+	responseText := escapeMarkdownV2("⚠️ The AI checker is unavailable right now, so these are only basic offline corrections:") + "\n\n" + correctedText
+	if !changed {
+		responseText = escapeMarkdownV2("⚠️ The AI checker is unavailable right now. Basic offline corrections found no obvious mistakes.")
+	}
 
 	msg := tgbotapi.NewMessage(message.Chat.ID, responseText)
 	msg.ReplyToMessageID = message.MessageID
 	msg.ParseMode = "MarkdownV2"
 
-	// Send the corrected text
-	if _, err := gb.bot.Send(msg); err != nil {
-		log.Printf("Error sending message: %v", err)
+	if _, err := gb.send(msg); err != nil {
+		log.Printf("Error sending basic corrections: %v", err)
 	}
 }
 
-func (gb *GrammarBot) handleCommand(message *tgbotapi.Message) {
-	switch message.Command() {
-	case "start":
-		welcomeText := `👋 Welcome to Grammar Check Bot!
+// countWords returns the number of whitespace-separated words in text.
+func countWords(text string) int {
+	return len(strings.Fields(text))
+}
+
+// userStyle returns the reply style the user chose, defaulting to inline.
+func (gb *GrammarBot) userStyle(userID int64) string {
+	if style := gb.store.GetUserSettings(userID).Style; validStyle(style) {
+		return style
+	}
+	return styleInline
+}
 
-Send me any text message and I'll check it for grammar, spelling, and punctuation errors.
+// handleStyleCommand shows or sets the user's reply style.
+func (gb *GrammarBot) handleStyleCommand(message *tgbotapi.Message, args string) {
+	userID := senderID(message)
+	style := strings.ToLower(args)
 
-I'll show corrections with:
-- ~strikethrough~ for original mistakes
-- **bold** for corrections
+	if style == "" {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Your style is %s. Use /style inline, /style arrows or /style minimal to change it.", gb.userStyle(userID))))
+		return
+	}
+	if !validStyle(style) {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Unknown style. Choose inline, arrows or minimal."))
+		return
+	}
 
-Commands:
-/start - Show this welcome message
-/help - Show help information`
+	if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) { stored.Style = style }); err != nil {
+		log.Printf("Error saving settings: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
 
-		msg := tgbotapi.NewMessage(message.Chat.ID, welcomeText)
-		msg.ParseMode = "MarkdownV2"
-		gb.bot.Send(msg)
+	gb.replyWithSettings(message, fmt.Sprintf("Style set to %s.", style))
+}
 
-	case "help":
-		helpText := `🔍 How to use Grammar Check Bot:
+// parseToggle interprets the argument of an on/off command. No argument
+// flips current. It reports false for anything else.
+func parseToggle(arg string, current bool) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(arg)) {
+	case "":
+		return !current, true
+	case "on":
+		return true, true
+	case "off":
+		return false, true
+	default:
+		return false, false
+	}
+}
 
-1. Simply send me any text message
-2. I'll analyze it for grammar, spelling, and punctuation errors
-3. You'll receive a corrected version with highlighted changes
+// handleFlagCommand toggles flag mode, or sets it with "/flag on|off".
+func (gb *GrammarBot) handleFlagCommand(message *tgbotapi.Message, args string) {
+	userID := senderID(message)
+	if _, ok := parseToggle(args, false); !ok {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /flag [on|off]"))
+		return
+	}
 
-📝 Example:
-Your text: "I goes to store yesterday"
-My response: "I ~goes~ **went** to ~store~ **the store** yesterday"
+	// No argument flips the stored value
+	var settings UserSettings
+	if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) {
+		stored.FlagOnly, _ = parseToggle(args, stored.FlagOnly)
+		settings = stored.clone()
+	}); err != nil {
+		log.Printf("Error saving settings: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
 
-💡 This helps you verify that your message conveys what you intended before sending it elsewhere!`
+	reply := "Flag mode is off. I'll correct your mistakes again."
+	if settings.FlagOnly {
+		reply = "Flag mode is on. I'll mark your mistakes and name the issue, and leave the fixing to you."
+	}
+	gb.replyWithSettings(message, reply)
+}
 
-		msg := tgbotapi.NewMessage(message.Chat.ID, helpText)
-		msg.ParseMode = "MarkdownV2"
-		gb.bot.Send(msg)
+// handleCallback dispatches inline keyboard button presses.
+func (gb *GrammarBot) handleCallback(query *tgbotapi.CallbackQuery) {
+	if query.Message == nil {
+		gb.answerCallback(tgbotapi.NewCallback(query.ID, ""))
+		return
+	}
 
+	switch {
+	case strings.HasPrefix(query.Data, recheckCallbackPrefix):
+		gb.handleRecheckCallback(query, strings.TrimPrefix(query.Data, recheckCallbackPrefix))
+	case strings.HasPrefix(query.Data, detectLanguageCallbackPrefix):
+		gb.handleDetectLanguageCallback(query, strings.TrimPrefix(query.Data, detectLanguageCallbackPrefix))
+	case strings.HasPrefix(query.Data, feedbackCallbackPrefix):
+		gb.handleFeedbackCallback(query, strings.TrimPrefix(query.Data, feedbackCallbackPrefix))
+	case strings.HasPrefix(query.Data, previewCallbackPrefix):
+		gb.handlePreviewCallback(query, strings.TrimPrefix(query.Data, previewCallbackPrefix))
 	default:
-		msg := tgbotapi.NewMessage(message.Chat.ID, "Unknown command. Use /help to see available commands.")
-		gb.bot.Send(msg)
+		gb.answerCallback(tgbotapi.NewCallback(query.ID, ""))
 	}
 }
 
-func (gb *GrammarBot) Start() error {
+// Start processes updates until ctx is cancelled. It then cancels the AI
+// calls still running and waits for the workers; updates they didn't finish
+// are left uncommitted, so Telegram delivers them again after a restart.
+func (gb *GrammarBot) Start(ctx context.Context) error {
 	log.Printf("Bot authorized on account %s", gb.bot.Self.UserName)
 
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+	gb.registerCommands()
+
+	// Process updates in a worker pool so slow AI calls don't block polling
+	var wg sync.WaitGroup
+	for i := 0; i < gb.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for update := range gb.queue {
+				// Updates left over at shutdown, or whose checks were
+				// aborted by it, are not marked done. Neither the
+				// committed offset nor the offset polled from passes
+				// them, so Telegram delivers them again after a restart
+				if gb.ctx.Err() != nil {
+					continue
+				}
+				gb.metrics.activeWorkers.Add(1)
+				root := gb.telemetry.beginUpdate(update)
+				handle := root.child("worker.handle", spanKindInternal)
+				gb.safeHandleUpdate(update)
+				handle.end(nil)
+				gb.telemetry.endUpdate(update, root)
+				gb.metrics.activeWorkers.Add(-1)
+				if gb.ctx.Err() != nil {
+					continue
+				}
+
+				gb.offsets.done(update.UpdateID)
+				if gb.cfg.OffsetCommitInterval == 0 {
+					gb.commitOffset()
+				}
+			}
+		}()
+	}
+	if gb.cfg.OffsetCommitInterval > 0 {
+		go gb.commitOffsets(ctx)
+	}
 
-	updates := gb.bot.GetUpdatesChan(u)
+	if gb.cfg.HTTPAPIAddr != "" {
+		go gb.serveAPI(ctx)
+	}
+	if gb.cfg.RateLimitQueue {
+		go gb.processDeferredChecks(ctx)
+	}
+	go gb.runDailyPractice(ctx)
+	if gb.telemetry != nil {
+		go gb.runTelemetry(ctx)
+	}
 
-	for update := range updates {
-		if update.Message == nil {
-			continue
-		}
+	gb.pollUpdates(ctx)
 
-		// Handle commands
-		if update.Message.IsCommand() {
-			gb.handleCommand(update.Message)
-		} else {
-			// Handle regular text messages
-			gb.handleMessage(update.Message)
+	log.Println("Shutting down, aborting in-flight AI calls...")
+	gb.stopCalls()
+	close(gb.queue)
+	wg.Wait()
+	gb.commitOffset()
+	gb.flushDigests()
+	gb.exportTelemetry()
+	gb.deletions.stop()
+	return nil
+}
+
+// handleUpdate routes a single update to its handler.
+func (gb *GrammarBot) handleUpdate(update tgbotapi.Update) {
+	business, isBusiness := gb.extras.takeBusiness(update.UpdateID)
+	if update.Message != nil {
+		defer gb.extras.forgetQuote(update.Message)
+	}
+
+	// Blocked users and chats outside the allowlist get no response at all
+	userID, chatID, ok := updateOrigin(update)
+	if isBusiness {
+		userID, chatID, ok = senderID(business.message), business.message.Chat.ID, true
+	}
+	if ok && gb.isIgnored(userID, chatID) {
+		return
+	}
+	// Business messages aren't checked during maintenance either, silently
+	if gb.holdForMaintenance(update) {
+		return
+	}
+
+	if isBusiness {
+		gb.handleBusinessMessage(business)
+		return
+	}
+
+	if update.CallbackQuery != nil {
+		gb.handleCallback(update.CallbackQuery)
+		return
+	}
+
+	if update.InlineQuery != nil {
+		if gb.features.get().Inline {
+			gb.handleInlineQuery(update.InlineQuery)
 		}
+		return
 	}
 
-	return nil
-}
+	if update.ChannelPost != nil {
+		gb.handleChannelPost(update.ChannelPost)
+		return
+	}
 
-func main() {
-	// Get tokens from environment variables
-	telegramToken := os.Getenv("TELEGRAM_BOT_TOKEN")
-	geminiAPIKey := os.Getenv("GEMINI_API_KEY")
+	if update.EditedMessage != nil {
+		gb.handleEditedMessage(update.EditedMessage)
+		return
+	}
+
+	if update.Message == nil {
+		return
+	}
 
-	if telegramToken == "" {
-		log.Fatal("TELEGRAM_BOT_TOKEN environment variable is required")
+	// Channel posts forwarded into their discussion group
+	if update.Message.IsAutomaticForward {
+		gb.handleAutomaticForward(update.Message)
+		return
 	}
-	if geminiAPIKey == "" {
-		log.Fatal("GEMINI_API_KEY environment variable is required")
+
+	if len(update.Message.NewChatMembers) > 0 {
+		gb.handleNewChatMembers(update.Message)
+		return
+	}
+
+	gb.notifyUpdates(update.Message)
+
+	// Handle commands
+	if update.Message.IsCommand() {
+		gb.handleCommand(update.Message)
+	} else if len(update.Message.Photo) > 0 && update.Message.Chat.IsPrivate() && gb.features.get().Photos {
+		// Check text in screenshots; in groups only on an explicit /check
+		gb.handlePhoto(update.Message)
+	} else if update.Message.Poll != nil && gb.features.get().Polls {
+		gb.handlePoll(update.Message)
+	} else if update.Message.Text == "" {
+		gb.handleNonTextMessage(update.Message)
+	} else {
+		// Handle regular text messages
+		gb.handleMessage(update.Message)
+	}
+}
+
+func main() {
+	// Read configuration from environment variables
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	// Create and start the bot
-	bot, err := NewGrammarBot(telegramToken, geminiAPIKey)
+	bot, err := NewGrammarBot(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	// Stop gracefully on Ctrl+C or when the container is stopped
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	log.Println("Starting Grammar Check Bot...")
-	if err := bot.Start(); err != nil {
+	if err := bot.Start(ctx); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingEngine corrects texts with "slow" in them only once its context
+// is cancelled, and everything else at once, counting the calls.
+func blockingEngine(started chan<- string, calls *atomic.Int32) *fakeEngine {
+	return &fakeEngine{correct: func(ctx context.Context, text string, opts CorrectOptions) (string, error) {
+		calls.Add(1)
+		started <- text
+		if strings.Contains(text, "slow") {
+			<-ctx.Done()
+			return "", ctx.Err()
+		}
+		return text, nil
+	}}
+}
+
+// TestShutdownLeavesUnfinishedUpdates checks that updates aborted or still
+// queued at shutdown are neither committed nor confirmed to Telegram, so
+// they are delivered again.
+func TestShutdownLeavesUnfinishedUpdates(t *testing.T) {
+	started := make(chan string, 10)
+	var calls atomic.Int32
+	gb, tg := newTestBot(t, testConfig(t, map[string]string{"WORKERS": "1"}), blockingEngine(started, &calls))
+
+	tg.addMessage(1, 7, "This one is fine.")
+	tg.addMessage(2, 7, "This one is slow.")
+	tg.addMessage(3, 7, "This one is queued.")
+
+	run(t, gb, func() {
+		for text := range started {
+			if strings.Contains(text, "slow") {
+				break
+			}
+		}
+		// Let polling run while update 2 is being handled
+		waitFor(t, "update 3 to be queued", func() bool { return len(gb.queue) == 1 })
+		time.Sleep(50 * time.Millisecond)
+	})
+
+	if got := gb.store.Offset(); got != 2 {
+		t.Errorf("committed offset = %d, want 2, the first unfinished update", got)
+	}
+	if got := tg.confirmedOffset(); got > 2 {
+		t.Errorf("Telegram was polled from offset %d, confirming unfinished updates", got)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("engine called %d times, want 2: update 3 should be left for redelivery", got)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// holdForMaintenance answers update with the maintenance message and reports
+// true when maintenance mode is on and the update isn't from an admin. In
+// groups only commands are answered, so ordinary chatter isn't met with a
+// notice each time.
+func (gb *GrammarBot) holdForMaintenance(update tgbotapi.Update) bool {
+	if !gb.store.Maintenance() {
+		return false
+	}
+	userID, _, ok := updateOrigin(update)
+	if ok && gb.isAdmin(userID) {
+		return false
+	}
+
+	switch {
+	case update.CallbackQuery != nil:
+		gb.answerCallback(tgbotapi.NewCallback(update.CallbackQuery.ID, gb.cfg.MaintenanceMessage))
+	case update.InlineQuery != nil:
+		gb.answerInline(update.InlineQuery.ID, 1, inlinePlaceholder(update.InlineQuery, "Under maintenance", gb.cfg.MaintenanceMessage))
+	case update.Message != nil && (update.Message.Chat.IsPrivate() || update.Message.IsCommand()):
+		if update.Message.IsCommand() && addressedToOtherBot(update.Message, gb.bot.Self.UserName) {
+			break
+		}
+		msg := tgbotapi.NewMessage(update.Message.Chat.ID, gb.cfg.MaintenanceMessage)
+		msg.ReplyToMessageID = update.Message.MessageID
+		gb.send(msg)
+	}
+	return true
+}
+
+// handleMaintenanceCommand toggles maintenance mode, or sets it with
+// "/maintenance on|off".
+func (gb *GrammarBot) handleMaintenanceCommand(message *tgbotapi.Message, args string) {
+	on, ok := parseToggle(args, gb.store.Maintenance())
+	if !ok {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /maintenance [on|off]"))
+		return
+	}
+
+	if err := gb.store.SetMaintenance(on); err != nil {
+		log.Printf("Error saving maintenance mode: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save the maintenance mode. Please try again later."))
+		return
+	}
+
+	reply := "Maintenance mode is off. Checks are running again."
+	if on {
+		reply = "Maintenance mode is on. Users get the maintenance message instead of checks; admins can still use the bot."
+	}
+	log.Printf("Admin %d: %s", message.From.ID, reply)
+	gb.send(tgbotapi.NewMessage(message.Chat.ID, reply))
+}
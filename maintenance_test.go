@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// countingEngine counts the texts it is asked to correct.
+func countingEngine(calls *atomic.Int32) *fakeEngine {
+	return &fakeEngine{correct: func(ctx context.Context, text string, opts CorrectOptions) (string, error) {
+		calls.Add(1)
+		return text, nil
+	}}
+}
+
+// TestGatesHoldBusinessMessages checks that business messages pass the
+// maintenance and ignore gates before anything about them is looked up.
+func TestGatesHoldBusinessMessages(t *testing.T) {
+	var calls atomic.Int32
+	gb, tg := newTestBot(t, testConfig(t, nil), countingEngine(&calls))
+	business := func(updateID int, senderID int64) tgbotapi.Update {
+		message := privateMessage(senderID, "She go to school every day.")
+		gb.extras.putBusiness(updateID, businessMessage{message: message, connectionID: "connection"})
+		return tgbotapi.Update{UpdateID: updateID}
+	}
+
+	if err := gb.store.SetMaintenance(true); err != nil {
+		t.Fatal(err)
+	}
+	gb.handleUpdate(business(1, 7))
+	if err := gb.store.SetMaintenance(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := gb.store.SetBlocked(8, true); err != nil {
+		t.Fatal(err)
+	}
+	gb.handleUpdate(business(2, 8))
+
+	if got := calls.Load(); got != 0 {
+		t.Errorf("engine called %d times, want none", got)
+	}
+	if got := len(tg.callsTo("getBusinessConnection")); got != 0 {
+		t.Errorf("looked up the business connection %d times, want none", got)
+	}
+	if _, ok := gb.extras.takeBusiness(1); ok {
+		t.Error("business message of a held update was left behind")
+	}
+}
+
+// TestAPICorrectGates checks that the HTTP API answers without a check
+// during maintenance and when API clients are blocked.
+func TestAPICorrectGates(t *testing.T) {
+	var calls atomic.Int32
+	gb, _ := newTestBot(t, testConfig(t, map[string]string{"HTTP_API_TOKEN": "secret"}), countingEngine(&calls))
+	handler := gb.apiHandler()
+	correct := func() int {
+		r := httptest.NewRequest(http.MethodPost, "/correct", strings.NewReader(`{"text": "She go to school."}`))
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if err := gb.store.SetMaintenance(true); err != nil {
+		t.Fatal(err)
+	}
+	if code := correct(); code != http.StatusServiceUnavailable {
+		t.Errorf("status during maintenance = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+	if err := gb.store.SetMaintenance(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := gb.store.SetBlocked(apiClientID, true); err != nil {
+		t.Fatal(err)
+	}
+	if code := correct(); code != http.StatusForbidden {
+		t.Errorf("status for blocked API clients = %d, want %d", code, http.StatusForbidden)
+	}
+	if got := calls.Load(); got != 0 {
+		t.Errorf("engine called %d times, want none", got)
+	}
+
+	if err := gb.store.SetBlocked(apiClientID, false); err != nil {
+		t.Fatal(err)
+	}
+	if code := correct(); code != http.StatusOK {
+		t.Errorf("status = %d, want %d", code, http.StatusOK)
+	}
+}
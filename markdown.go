@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// markdownV2Reserved lists the characters Telegram requires to be escaped in
+// MarkdownV2 text outside of entities.
+const markdownV2Reserved = "_*[]()~`>#+-=|{}.!\\"
+
+// escapeMarkdownV2 escapes text so Telegram renders it literally in MarkdownV2.
+func escapeMarkdownV2(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	for _, r := range text {
+		if strings.ContainsRune(markdownV2Reserved, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// escapeCodeBlock escapes text for a MarkdownV2 code block, where only ` and
+// \ need escaping.
+func escapeCodeBlock(text string) string {
+	return strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(text)
+}
+
+// mdSpan is a MarkdownV2 entity found by parseMarkdownV2. Start and End are
+// byte offsets covering the opening and closing markers.
+type mdSpan struct {
+	Marker string
+	Start  int
+	End    int
+}
+
+// parseMarkdownV2 checks that text is valid MarkdownV2 — every reserved
+// character is escaped and every entity is closed in the right order — and
+// returns the entities it contains.
+func parseMarkdownV2(text string) ([]mdSpan, error) {
+	type open struct {
+		marker string
+		start  int
+	}
+
+	var (
+		spans []mdSpan
+		stack []open
+	)
+
+	toggle := func(marker string, at int) error {
+		if n := len(stack); n > 0 && stack[n-1].marker == marker {
+			spans = append(spans, mdSpan{Marker: marker, Start: stack[n-1].start, End: at + len(marker)})
+			stack = stack[:n-1]
+			return nil
+		}
+		for _, o := range stack {
+			if o.marker == marker {
+				return fmt.Errorf("entity %q at offset %d closes out of order", marker, at)
+			}
+		}
+		stack = append(stack, open{marker: marker, start: at})
+		return nil
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\':
+			if i+1 >= len(text) {
+				return nil, fmt.Errorf("dangling backslash at offset %d", i)
+			}
+			_, size := utf8.DecodeRuneInString(text[i+1:])
+			i += 1 + size
+
+		case c == '`':
+			marker := "`"
+			if strings.HasPrefix(text[i:], "```") {
+				marker = "```"
+			}
+			end := findUnescaped(text, i+len(marker), marker)
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed code entity at offset %d", i)
+			}
+			spans = append(spans, mdSpan{Marker: marker, Start: i, End: end + len(marker)})
+			i = end + len(marker)
+
+		case c == '[' || (c == '!' && strings.HasPrefix(text[i:], "![")):
+			// Links, and custom emoji written as ![👍](tg://emoji?id=...)
+			start := i
+			if c == '!' {
+				i++
+			}
+			stack = append(stack, open{marker: "[", start: start})
+			i++
+
+		case c == ']':
+			n := len(stack)
+			if n == 0 || stack[n-1].marker != "[" {
+				return nil, fmt.Errorf("unescaped ']' at offset %d", i)
+			}
+			if !strings.HasPrefix(text[i:], "](") {
+				return nil, fmt.Errorf("link at offset %d has no URL", stack[n-1].start)
+			}
+			end := findUnescaped(text, i+2, ")")
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed link URL at offset %d", i)
+			}
+			spans = append(spans, mdSpan{Marker: "[", Start: stack[n-1].start, End: end + 1})
+			stack = stack[:n-1]
+			i = end + 1
+
+		case c == '*' || c == '~':
+			if err := toggle(string(c), i); err != nil {
+				return nil, err
+			}
+			i++
+
+		case c == '_':
+			marker := "_"
+			if strings.HasPrefix(text[i:], "__") {
+				marker = "__"
+			}
+			if err := toggle(marker, i); err != nil {
+				return nil, err
+			}
+			i += len(marker)
+
+		case c == '|' && strings.HasPrefix(text[i:], "||"):
+			if err := toggle("||", i); err != nil {
+				return nil, err
+			}
+			i += 2
+
+		case c == '>' && (i == 0 || text[i-1] == '\n'):
+			// Block quotation at the start of a line
+			i++
+
+		case strings.IndexByte(markdownV2Reserved, c) >= 0:
+			return nil, fmt.Errorf("unescaped %q at offset %d", c, i)
+
+		default:
+			_, size := utf8.DecodeRuneInString(text[i:])
+			i += size
+		}
+	}
+
+	if len(stack) > 0 {
+		top := stack[len(stack)-1]
+		return nil, fmt.Errorf("unclosed entity %q at offset %d", top.marker, top.start)
+	}
+	return spans, nil
+}
+
+// findUnescaped returns the offset of the first marker at or after from that
+// isn't preceded by a backslash escape, or -1.
+func findUnescaped(text string, from int, marker string) int {
+	for i := from; i < len(text); i++ {
+		if text[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(text[i:], marker) {
+			return i
+		}
+	}
+	return -1
+}
+
+// stripMarkdownV2 turns MarkdownV2 into plain text by dropping entity markers
+// and escapes. It accepts invalid input, which is when it's needed most.
+func stripMarkdownV2(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text):
+			i++
+			b.WriteByte(text[i])
+		case c == '*' || c == '~' || c == '_' || c == '`':
+		case c == '|' && i+1 < len(text) && text[i+1] == '|':
+			i++
+		case c == '!' && i+1 < len(text) && text[i+1] == '[':
+		case c == '[':
+		case c == ']' && i+1 < len(text) && text[i+1] == '(':
+			// Drop the link target, keeping only its text
+			if end := findUnescaped(text, i+2, ")"); end >= 0 {
+				i = end
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
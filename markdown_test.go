@@ -0,0 +1,76 @@
+package main
+
+import "testing"
+
+func TestEscapeMarkdownV2(t *testing.T) {
+	tests := map[string]string{
+		"She goes home.":           `She goes home\.`,
+		"||not a spoiler||":        `\|\|not a spoiler\|\|`,
+		"a|b":                      `a\|b`,
+		"![👍](tg://emoji?id=5368)": `\!\[👍\]\(tg://emoji?id\=5368\)`,
+		"_*[]()~`>#+-=|{}.!\\":     "\\_\\*\\[\\]\\(\\)\\~\\`\\>\\#\\+\\-\\=\\|\\{\\}\\.\\!\\\\",
+		"Grüße 😀 👩‍💻":              "Grüße 😀 👩‍💻",
+	}
+	for text, want := range tests {
+		got := escapeMarkdownV2(text)
+		if got != want {
+			t.Errorf("escapeMarkdownV2(%q) = %q, want %q", text, got, want)
+		}
+		if _, err := parseMarkdownV2(got); err != nil {
+			t.Errorf("escaped %q isn't valid MarkdownV2: %v", text, err)
+		}
+		if plain := stripMarkdownV2(got); plain != text {
+			t.Errorf("stripMarkdownV2(%q) = %q, want %q back", got, plain, text)
+		}
+	}
+}
+
+func TestParseMarkdownV2Spoilers(t *testing.T) {
+	valid := map[string][]mdSpan{
+		"The ||ending|| is sad\\.":                     {{Marker: "||", Start: 4, End: 14}},
+		"*bold ||spoiler||*":                           {{Marker: "||", Start: 6, End: 17}, {Marker: "*", Start: 0, End: 18}},
+		"Nice ![👍](tg://emoji?id=5368617782021818417)": {{Marker: "[", Start: 5, End: 47}},
+		"||![👍](tg://emoji?id=1)||":                    {{Marker: "[", Start: 2, End: 26}, {Marker: "||", Start: 0, End: 28}},
+	}
+	for text, want := range valid {
+		spans, err := parseMarkdownV2(text)
+		if err != nil {
+			t.Errorf("parseMarkdownV2(%q) failed: %v", text, err)
+			continue
+		}
+		if len(spans) != len(want) {
+			t.Errorf("parseMarkdownV2(%q) = %+v, want %+v", text, spans, want)
+			continue
+		}
+		for i := range want {
+			if spans[i] != want[i] {
+				t.Errorf("parseMarkdownV2(%q) = %+v, want %+v", text, spans, want)
+				break
+			}
+		}
+	}
+
+	invalid := []string{
+		"The ||ending is sad",
+		"a | b",
+		"*bold ||spoiler*||",
+		"![👍](tg://emoji?id=1",
+	}
+	for _, text := range invalid {
+		if _, err := parseMarkdownV2(text); err == nil {
+			t.Errorf("parseMarkdownV2(%q) accepted invalid MarkdownV2", text)
+		}
+	}
+}
+
+func TestStripMarkdownV2CustomEmoji(t *testing.T) {
+	tests := map[string]string{
+		"Nice ![👍](tg://emoji?id=5368617782021818417)\\!": "Nice 👍!",
+		"The ||ending|| is sad\\.":                        "The ending is sad.",
+	}
+	for text, want := range tests {
+		if got := stripMarkdownV2(text); got != want {
+			t.Errorf("stripMarkdownV2(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
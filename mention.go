@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// mentionHints answer a mention with nothing to check, keyed by Telegram
+// language code. %s is the bot's username.
+var mentionHints = map[string]string{
+	"en": "👋 Mention me together with the text to check, like “@%s I has a question”, or reply to a message with /check.",
+	"de": "👋 Erwähne mich zusammen mit dem Text, den ich prüfen soll, etwa „@%s Ich habe eine Frage“, oder antworte auf eine Nachricht mit /check.",
+	"es": "👋 Menciónme junto con el texto que quieres revisar, como «@%s Yo tener una pregunta», o responde a un mensaje con /check.",
+	"ru": "👋 Упомяните меня вместе с текстом для проверки, например «@%s Я иметь вопрос», или ответьте на сообщение командой /check.",
+}
+
+// stripMentions removes the mentions of username from text and reports
+// whether there were any.
+func stripMentions(text string, entities []tgbotapi.MessageEntity, username string) (string, bool) {
+	var b strings.Builder
+	pos := 0
+	mentioned := false
+	for _, entity := range entities {
+		if entity.Type != "mention" {
+			continue
+		}
+		start := limitOffset(text, entity.Offset)
+		end := limitOffset(text, entity.Offset+entity.Length)
+		if start < pos || !strings.EqualFold(text[start:end], "@"+username) {
+			continue
+		}
+		b.WriteString(text[pos:start])
+		pos = end
+		mentioned = true
+	}
+	b.WriteString(text[pos:])
+	return strings.TrimSpace(b.String()), mentioned
+}
+
+// checkable reports whether text has anything to check, as opposed to a
+// lone "?" or emoji.
+func checkable(text string) bool {
+	return strings.IndexFunc(text, unicode.IsLetter) >= 0
+}
+
+// mentionHint is the reply to a mention with nothing to check: MENTION_HINT
+// when set, otherwise the built-in hint in the sender's language.
+func (gb *GrammarBot) mentionHint(message *tgbotapi.Message) string {
+	if gb.cfg.MentionHint != "" {
+		return gb.cfg.MentionHint
+	}
+	hint := mentionHints["en"]
+	if message.From != nil {
+		code, _, _ := strings.Cut(strings.ToLower(message.From.LanguageCode), "-")
+		if translated, ok := mentionHints[code]; ok {
+			hint = translated
+		}
+	}
+	return fmt.Sprintf(hint, gb.bot.Self.UserName)
+}
+
+// mentionedText returns the text to check of a message in a chat where only
+// mentions are checked, with the mentions removed. It returns false when the
+// message doesn't mention the bot, and answers a mention with nothing to
+// check with a hint.
+func (gb *GrammarBot) mentionedText(message *tgbotapi.Message) (string, bool) {
+	text, mentioned := stripMentions(message.Text, message.Entities, gb.bot.Self.UserName)
+	if !mentioned {
+		return "", false
+	}
+	if !checkable(text) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, gb.mentionHint(message))
+		msg.ReplyToMessageID = message.MessageID
+		gb.send(msg)
+		return "", false
+	}
+	return text, true
+}
+
+// handleMentionOnlyCommand shows or sets whether the bot checks only
+// messages that mention it in a group.
+func (gb *GrammarBot) handleMentionOnlyCommand(message *tgbotapi.Message, args string) {
+	chatID := message.Chat.ID
+	if message.Chat.IsPrivate() {
+		gb.send(tgbotapi.NewMessage(chatID, "Mention-only mode is for groups. Here I check every message you send."))
+		return
+	}
+	if !gb.canManageChat(message) {
+		gb.send(tgbotapi.NewMessage(chatID, "Only chat admins can change mention-only mode."))
+		return
+	}
+
+	if _, ok := parseToggle(args, false); !ok {
+		gb.send(tgbotapi.NewMessage(chatID, "Usage: /mentiononly [on|off]"))
+		return
+	}
+	// No argument flips the stored value
+	var enabled bool
+	if err := gb.store.UpdateChatSettings(chatID, func(stored *ChatSettings) {
+		enabled, _ = parseToggle(args, stored.MentionOnly)
+		stored.MentionOnly = enabled
+	}); err != nil {
+		log.Printf("Error saving chat settings: %v", err)
+		gb.send(tgbotapi.NewMessage(chatID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	reply := "Mention-only mode is off. I'll check every message sent here."
+	if enabled {
+		reply = fmt.Sprintf("Mention-only mode is on. I'll check only messages that mention @%s.", gb.bot.Self.UserName)
+	}
+	gb.send(tgbotapi.NewMessage(chatID, reply))
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// menuLanguages returns every language with translated menu descriptions.
+func (gb *GrammarBot) menuLanguages() []string {
+	seen := make(map[string]bool)
+	var languages []string
+	for _, c := range gb.commands.ordered {
+		if !gb.features.enabled(c.Feature) {
+			continue
+		}
+		for lang := range c.Translations {
+			if !seen[lang] {
+				seen[lang] = true
+				languages = append(languages, lang)
+			}
+		}
+	}
+	return languages
+}
+
+// botCommandsFor returns the menu in language, falling back to English.
+// Admin commands, commands of features turned off and commands without a
+// menu description are left out.
+func (gb *GrammarBot) botCommandsFor(language string) []tgbotapi.BotCommand {
+	var commands []tgbotapi.BotCommand
+	for _, c := range gb.commands.ordered {
+		if c.AdminOnly || c.MenuDescription == "" || !gb.features.enabled(c.Feature) {
+			continue
+		}
+		description := c.MenuDescription
+		if translated, ok := c.Translations[language]; ok {
+			description = translated
+		}
+		commands = append(commands, tgbotapi.BotCommand{Command: c.Name, Description: description})
+	}
+	return commands
+}
+
+// registerCommands publishes the command menu so users can discover the
+// commands from Telegram's UI. Failures are logged but not fatal.
+func (gb *GrammarBot) registerCommands() {
+	if _, err := gb.request(tgbotapi.NewSetMyCommands(gb.botCommandsFor("")...)); err != nil {
+		log.Printf("Error registering bot commands: %v", err)
+		return
+	}
+
+	for _, lang := range gb.menuLanguages() {
+		config := tgbotapi.NewSetMyCommandsWithScopeAndLanguage(tgbotapi.NewBotCommandScopeDefault(), lang, gb.botCommandsFor(lang)...)
+		if _, err := gb.request(config); err != nil {
+			log.Printf("Error registering %s bot commands: %v", lang, err)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// errorWindowSize is how many recent AI calls the error rate and latency
+// percentiles are computed over.
+const errorWindowSize = 100
+
+// maxRecentErrors is how many of the latest failed AI calls are kept for the
+// dashboard.
+const maxRecentErrors = 20
+
+// recentError is a failed AI call.
+type recentError struct {
+	Time    time.Time
+	Type    string
+	Message string
+}
+
+// Metrics holds the bot's operational counters. It is safe for concurrent use.
+type Metrics struct {
+	checks        atomic.Int64
+	checkErrors   atomic.Int64
+	inFlight      atomic.Int64
+	activeWorkers atomic.Int64
+	panics        atomic.Int64
+	reconnects    atomic.Int64
+	truncations   atomic.Int64
+	floodWaits    atomic.Int64
+	// structured corrections made, and how many needed asking again
+	structuredAttempts  atomic.Int64
+	structuredReprompts atomic.Int64
+
+	mu      sync.Mutex
+	outcome [errorWindowSize]bool // true means the call failed
+	latency [errorWindowSize]time.Duration
+	next    int
+	filled  int
+	models  map[string]int64
+	errors  map[string]int64
+	recent  []recentError
+}
+
+type metricsContextKey struct{}
+
+// contextWithMetrics returns ctx carrying m, so engines count what happens
+// in the AI calls made under ctx.
+func contextWithMetrics(ctx context.Context, m *Metrics) context.Context {
+	return context.WithValue(ctx, metricsContextKey{}, m)
+}
+
+// metricsFromContext returns the metrics ctx carries, or fresh ones that
+// nothing reads when it carries none.
+func metricsFromContext(ctx context.Context) *Metrics {
+	if m, ok := ctx.Value(metricsContextKey{}).(*Metrics); ok {
+		return m
+	}
+	return &Metrics{}
+}
+
+// recordModel counts a request served by model.
+func (m *Metrics) recordModel(model string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.models == nil {
+		m.models = make(map[string]int64)
+	}
+	m.models[model]++
+}
+
+// modelSplit returns how many requests each model served.
+func (m *Metrics) modelSplit() map[string]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	split := make(map[string]int64, len(m.models))
+	for model, n := range m.models {
+		split[model] = n
+	}
+	return split
+}
+
+// errorTypes returns how many calls failed with each kind of error.
+func (m *Metrics) errorTypes() map[string]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	types := make(map[string]int64, len(m.errors))
+	for kind, n := range m.errors {
+		types[kind] = n
+	}
+	return types
+}
+
+// recentErrors returns the latest failed calls, newest first.
+func (m *Metrics) recentErrors() []recentError {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	errs := make([]recentError, len(m.recent))
+	for i, e := range m.recent {
+		errs[len(m.recent)-1-i] = e
+	}
+	return errs
+}
+
+// beginCall marks the start of an AI call. The returned func records its outcome.
+func (m *Metrics) beginCall() func(err error) {
+	m.inFlight.Add(1)
+	start := time.Now()
+	return func(err error) {
+		elapsed := time.Since(start)
+		m.inFlight.Add(-1)
+		m.checks.Add(1)
+		if err != nil {
+			m.checkErrors.Add(1)
+		}
+
+		m.mu.Lock()
+		if err != nil {
+			if m.errors == nil {
+				m.errors = make(map[string]int64)
+			}
+			kind := classifyError(err)
+			m.errors[kind]++
+			m.recent = append(m.recent, recentError{Time: time.Now().UTC(), Type: kind, Message: err.Error()})
+			if len(m.recent) > maxRecentErrors {
+				m.recent = m.recent[1:]
+			}
+		}
+		m.outcome[m.next] = err != nil
+		m.latency[m.next] = elapsed
+		m.next = (m.next + 1) % errorWindowSize
+		if m.filled < errorWindowSize {
+			m.filled++
+		}
+		m.mu.Unlock()
+	}
+}
+
+// recentErrorRate returns the share of failed calls among the most recent
+// ones, and how many calls that share is based on.
+func (m *Metrics) recentErrorRate() (float64, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.filled == 0 {
+		return 0, 0
+	}
+
+	failed := 0
+	for i := 0; i < m.filled; i++ {
+		if m.outcome[i] {
+			failed++
+		}
+	}
+	return float64(failed) / float64(m.filled), m.filled
+}
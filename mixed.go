@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// mixedPrompt is appended to the correction prompt in mixed-language mode.
+const mixedPrompt = `
+
+The message may intentionally mix several languages. Detect the language of each segment and correct it according to the rules of its own language. Never translate a segment, and never "correct" a foreign word or phrase into the dominant language — code-switching is deliberate.`
+
+// handleMixedCommand toggles mixed-language mode. Its limitations: very short
+// segments may be assigned the wrong language, and words shared between
+// languages are judged by the surrounding segment.
+func (gb *GrammarBot) handleMixedCommand(message *tgbotapi.Message, args string) {
+	userID := senderID(message)
+	if _, ok := parseToggle(args, false); !ok {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /mixed [on|off]"))
+		return
+	}
+
+	// No argument flips the stored value
+	var settings UserSettings
+	if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) {
+		stored.Mixed, _ = parseToggle(args, stored.Mixed)
+		settings = stored.clone()
+	}); err != nil {
+		log.Printf("Error saving settings: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	reply := "Mixed-language mode is off. I'll correct everything in your correction language."
+	if settings.Mixed {
+		reply = "Mixed-language mode is on. I'll correct each part of your message in its own language and leave deliberate code-switching alone.\n\n" +
+			"Note: very short phrases (one or two words) may be mistaken for another language, and words that exist in both languages are judged by their surroundings."
+	}
+	gb.replyWithSettings(message, reply)
+}
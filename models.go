@@ -0,0 +1,54 @@
+package main
+
+import (
+	"log"
+	"unicode/utf8"
+)
+
+// Supported values of MODEL_SELECTION.
+const (
+	// modelStandard always uses the standard (fast, cheap) model.
+	modelStandard = "standard"
+	// modelPro always uses the pro model.
+	modelPro = "pro"
+	// modelAuto picks the pro model for long inputs only.
+	modelAuto = "auto"
+)
+
+// selectModel returns the model to correct text with, based on the
+// operator's MODEL_SELECTION and, in auto mode, the length of the input.
+func (gb *GrammarBot) selectModel(text string) string {
+	tier := gb.cfg.ModelSelection
+	if tier == modelAuto {
+		tier = modelStandard
+		if countWords(text) >= gb.cfg.AutoModelWords || utf8.RuneCountInString(text) >= gb.cfg.AutoModelChars {
+			tier = modelPro
+		}
+	}
+
+	return gb.modelName(tier)
+}
+
+// modelName maps a model tier to the backend's model name.
+func (gb *GrammarBot) modelName(tier string) string {
+	switch gb.cfg.Backend {
+	case backendOpenAI:
+		if tier == modelPro {
+			return gb.cfg.OpenAIProModel
+		}
+		return gb.cfg.OpenAIModel
+	default:
+		if tier == modelPro {
+			return gb.cfg.GeminiProModel
+		}
+		return gb.cfg.GeminiModel
+	}
+}
+
+// logModel records which model served a request.
+func (gb *GrammarBot) logModel(model string, text string) {
+	gb.metrics.recordModel(model)
+	if gb.cfg.ModelSelection == modelAuto {
+		log.Printf("Using model %s for %d words", model, countWords(text))
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Supported values of NON_TEXT_MESSAGES.
+const (
+	// nonTextCheck checks captions and venue names, and acknowledges other
+	// messages without text.
+	nonTextCheck = "check"
+	// nonTextNote acknowledges every message without text.
+	nonTextNote = "note"
+	// nonTextIgnore leaves messages without text unanswered.
+	nonTextIgnore = "ignore"
+)
+
+const nonTextNoteText = "I can only check text. Send me the text you'd like checked, or a screenshot of it."
+
+// nonTextContent returns the checkable text of a message without message
+// text: a media caption or a venue's name. ok reports whether the message
+// has any content a user would expect an answer to.
+func nonTextContent(message *tgbotapi.Message) (text string, ok bool) {
+	switch {
+	case message.Caption != "":
+		return message.Caption, true
+	case message.Venue != nil:
+		return message.Venue.Title, true
+	}
+
+	hasContent := message.Sticker != nil || message.Voice != nil || message.Audio != nil ||
+		message.Video != nil || message.VideoNote != nil || message.Document != nil ||
+		message.Animation != nil || message.Location != nil || message.Contact != nil ||
+		message.Poll != nil || message.Dice != nil
+	return "", hasContent
+}
+
+// handleNonTextMessage answers messages without message text in private
+// chats according to cfg.NonTextMessages, so they aren't left unanswered.
+// Groups are full of media that isn't meant for the bot, so it stays quiet
+// there.
+func (gb *GrammarBot) handleNonTextMessage(message *tgbotapi.Message) {
+	if !message.Chat.IsPrivate() || gb.cfg.NonTextMessages == nonTextIgnore {
+		return
+	}
+	text, ok := nonTextContent(message)
+	if !ok {
+		return
+	}
+
+	text = strings.TrimSpace(text)
+	if gb.cfg.NonTextMessages == nonTextCheck && text != "" {
+		if countWords(text) >= gb.cfg.MinWords {
+			gb.checkAndReply(message, text)
+		}
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, nonTextNoteText)
+	msg.ReplyToMessageID = message.MessageID
+	gb.send(msg)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TextExtractor is implemented by engines that can read text from images.
+type TextExtractor interface {
+	ExtractText(ctx context.Context, image []byte, mimeType string) (string, error)
+}
+
+// noTextMarker is what the model answers when an image contains no text.
+const noTextMarker = "NO_TEXT"
+
+const extractTextPrompt = `Extract all text from this image exactly as written, keeping the original line breaks, spelling and punctuation. Do not correct, translate or describe anything. If the image contains no readable text, reply with exactly ` + noTextMarker + `.`
+
+// handlePhoto extracts the text of a photo and checks its grammar.
+func (gb *GrammarBot) handlePhoto(message *tgbotapi.Message) {
+	extractor, ok := gb.engine.(TextExtractor)
+	if !ok {
+		gb.replyText(message, "Sorry, checking text in images isn't available with the current AI backend.")
+		return
+	}
+
+	photo, ok := largestPhoto(message.Photo, gb.cfg.MaxImageBytes)
+	if !ok {
+		gb.replyText(message, "Sorry, this image is too large for me to check.")
+		return
+	}
+
+	defer gb.startTyping(message.Chat.ID)()
+
+	image, err := gb.downloadFile(photo.FileID, gb.cfg.MaxImageBytes, "image/")
+	if errors.Is(err, errFileTooLarge) {
+		gb.replyText(message, "Sorry, this image is too large for me to check.")
+		return
+	}
+	if errors.Is(err, errUnsupportedContent) {
+		log.Printf("Error downloading photo: %v", err)
+		gb.replyText(message, "Sorry, I can't read this kind of image.")
+		return
+	}
+	if err != nil {
+		log.Printf("Error downloading photo: %v", err)
+		gb.replyText(message, "Sorry, I couldn't download your image. Please try again later.")
+		return
+	}
+
+	done := gb.metrics.beginCall()
+	text, err := extractor.ExtractText(gb.ctx, image, http.DetectContentType(image))
+	done(err)
+	if err != nil {
+		log.Printf("Error extracting text: %v", err)
+		gb.replyText(message, "Sorry, I encountered an error while reading your image. Please try again later.")
+		return
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" || text == noTextMarker {
+		gb.replyText(message, "I couldn't find any text in this image.")
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, escapeMarkdownV2("🖼 Text found in your image:")+"\n\n"+escapeMarkdownV2(text))
+	msg.ReplyToMessageID = message.MessageID
+	msg.ParseMode = "MarkdownV2"
+	if _, err := gb.send(msg); err != nil {
+		log.Printf("Error sending extracted text: %v", err)
+	}
+
+	gb.checkAndReply(message, text)
+}
+
+// replyText replies to message with plain text.
+func (gb *GrammarBot) replyText(message *tgbotapi.Message, text string) {
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	msg.ReplyToMessageID = message.MessageID
+	if _, err := gb.send(msg); err != nil {
+		log.Printf("Error sending message: %v", err)
+	}
+}
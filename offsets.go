@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// offsetTracker works out which update offset is safe to commit while
+// workers finish updates out of order: the offset never passes an update
+// that is still queued or being handled, so a restart neither repeats
+// finished updates nor skips unfinished ones.
+type offsetTracker struct {
+	mu      sync.Mutex
+	pending map[int]bool
+	next    int
+}
+
+// start records that the update with id was received for handling.
+func (t *offsetTracker) start(id int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.pending == nil {
+		t.pending = make(map[int]bool)
+	}
+	t.pending[id] = true
+	t.next = max(t.next, id+1)
+}
+
+// unfinished reports whether the update with id was received and not yet
+// handled.
+func (t *offsetTracker) unfinished(id int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.pending[id]
+}
+
+// done records that the update with id was handled.
+func (t *offsetTracker) done(id int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.pending, id)
+}
+
+// committable returns the offset every earlier update was handled before,
+// or 0 if no update was queued yet.
+func (t *offsetTracker) committable() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	offset := t.next
+	for id := range t.pending {
+		offset = min(offset, id)
+	}
+	return offset
+}
+
+// commitOffset saves the committable offset to the store.
+func (gb *GrammarBot) commitOffset() {
+	if err := gb.store.CommitOffset(gb.offsets.committable()); err != nil {
+		log.Printf("Error committing update offset: %v", err)
+	}
+}
+
+// commitOffsets saves the offset every cfg.OffsetCommitInterval until ctx
+// is cancelled.
+func (gb *GrammarBot) commitOffsets(ctx context.Context) {
+	ticker := time.NewTicker(gb.cfg.OffsetCommitInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			gb.commitOffset()
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestOffsetTrackerCommittable(t *testing.T) {
+	var tracker offsetTracker
+	if got := tracker.committable(); got != 0 {
+		t.Fatalf("committable() = %d before any update, want 0", got)
+	}
+
+	for id := 5; id <= 8; id++ {
+		tracker.start(id)
+	}
+	tracker.done(5)
+	tracker.done(7)
+	if got := tracker.committable(); got != 6 {
+		t.Errorf("committable() = %d with 6 and 8 unfinished, want 6", got)
+	}
+	if !tracker.unfinished(6) || tracker.unfinished(7) {
+		t.Errorf("unfinished(6), unfinished(7) = %v, %v, want true, false", tracker.unfinished(6), tracker.unfinished(7))
+	}
+
+	tracker.done(6)
+	tracker.done(8)
+	if got := tracker.committable(); got != 9 {
+		t.Errorf("committable() = %d with every update done, want 9", got)
+	}
+}
+
+// TestRestartResumesFromCommittedOffset stops a bot while an update is
+// being handled and checks that a bot restarted on the same store polls
+// from the committed offset and handles exactly the unfinished updates.
+func TestRestartResumesFromCommittedOffset(t *testing.T) {
+	cfg := testConfig(t, map[string]string{
+		"WORKERS":    "1",
+		"STORE_PATH": filepath.Join(t.TempDir(), "store.json"),
+	})
+	started := make(chan string, 10)
+	var calls atomic.Int32
+	first, tg := newTestBot(t, cfg, blockingEngine(started, &calls))
+
+	tg.addMessage(1, 7, "This one is fine.")
+	tg.addMessage(2, 7, "This one is slow.")
+	tg.addMessage(3, 7, "This one is after.")
+	run(t, first, func() {
+		for text := range started {
+			if strings.Contains(text, "slow") {
+				return
+			}
+		}
+	})
+	if got := first.store.Offset(); got != 2 {
+		t.Fatalf("committed offset = %d after the first run, want 2", got)
+	}
+
+	polls := len(tg.callsTo("getUpdates"))
+	var handled []string
+	second := newTestBotOn(t, tg, cfg, &fakeEngine{correct: func(ctx context.Context, text string, opts CorrectOptions) (string, error) {
+		handled = append(handled, text)
+		return text, nil
+	}})
+	run(t, second, func() {
+		waitFor(t, "the unfinished updates to be handled", func() bool { return second.store.Offset() == 4 })
+	})
+
+	if offset := tg.callsTo("getUpdates")[polls].Get("offset"); offset != strconv.Itoa(2) {
+		t.Errorf("restarted bot polled from offset %s, want 2", offset)
+	}
+	if len(handled) != 2 || handled[0] != "This one is slow." || handled[1] != "This one is after." {
+		t.Errorf("restarted bot handled %q, want the two unfinished updates", handled)
+	}
+}
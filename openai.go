@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// openAIEngine corrects text with any OpenAI-compatible chat-completions
+// endpoint, such as a self-hosted llama.cpp, vLLM or Ollama server.
+type openAIEngine struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+func newOpenAIEngine(baseURL, apiKey, model string) *openAIEngine {
+	return &openAIEngine{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []chatMessage   `json:"messages"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+// responseFormat asks the endpoint for a particular kind of answer, such as
+// "json_object".
+type responseFormat struct {
+	Type string `json:"type"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// httpStatusError is returned when the endpoint answers with a non-2xx status.
+type httpStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Body)
+}
+
+func (e *openAIEngine) Correct(ctx context.Context, text string, opts CorrectOptions) (string, error) {
+	return correctWith(ctx, e, text, opts)
+}
+
+func (e *openAIEngine) Complete(ctx context.Context, prompt Prompt, text string) (string, error) {
+	model := e.model
+	if prompt.Model != "" {
+		model = prompt.Model
+	}
+
+	messages := []chatMessage{{Role: "system", Content: prompt.Instructions}}
+	for _, ex := range prompt.Examples {
+		messages = append(messages,
+			chatMessage{Role: "user", Content: ex.Input},
+			chatMessage{Role: "assistant", Content: ex.Output},
+		)
+	}
+	messages = append(messages, chatMessage{Role: "user", Content: text})
+
+	request := chatRequest{Model: model, Messages: messages}
+	if prompt.Schema != nil {
+		request.ResponseFormat = &responseFormat{Type: "json_object"}
+	}
+	return e.chat(ctx, request)
+}
+
+func (e *openAIEngine) chat(ctx context.Context, request chatRequest) (string, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode chat request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create chat request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call chat completions: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("failed to call chat completions: %w", &httpStatusError{StatusCode: resp.StatusCode, Body: string(raw)})
+	}
+
+	var parsed chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return "", fmt.Errorf("failed to decode chat response: %w", err)
+	}
+	if len(parsed.Choices) == 0 || parsed.Choices[0].Message.Content == "" {
+		return "", ErrEmptyResponse
+	}
+
+	return parsed.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handlePoll checks the question and each option of a poll, if the chat
+// turned poll checks on, and replies with the fixed versions: the bot can't
+// edit someone else's poll. Nothing is sent when the poll has no mistakes.
+// Polls in chats without poll checks are answered like other messages
+// without text.
+func (gb *GrammarBot) handlePoll(message *tgbotapi.Message) {
+	if !gb.store.GetChatSettings(message.Chat.ID).CheckPolls {
+		gb.handleNonTextMessage(message)
+		return
+	}
+	defer gb.startTyping(message.Chat.ID)()
+
+	poll := message.Poll
+	texts := []string{poll.Question}
+	for _, option := range poll.Options {
+		texts = append(texts, option.Text)
+	}
+
+	opts := gb.correctOptions(message)
+	opts.Explain = false
+	corrected := make([]string, len(texts))
+	errs := make([]error, len(texts))
+	var wg sync.WaitGroup
+	for i, text := range texts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			corrected[i], errs[i] = gb.checkGrammar(text, opts)
+		}()
+	}
+	wg.Wait()
+
+	changed := false
+	for i, err := range errs {
+		if err != nil {
+			gb.logError("Error checking poll: %v", err)
+			return
+		}
+		changed = changed || hasCorrections(texts[i], corrected[i])
+	}
+	if !changed {
+		return
+	}
+
+	style := gb.userStyle(senderID(message))
+	var b strings.Builder
+	b.WriteString("📊 *Grammar check for this poll*\n\n")
+	b.WriteString(renderPollText(corrected[0], opts, style))
+	for _, option := range corrected[1:] {
+		b.WriteString("\n• " + renderPollText(option, opts, style))
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, b.String())
+	msg.ReplyToMessageID = message.MessageID
+	msg.ParseMode = "MarkdownV2"
+	if _, err := gb.send(msg); err != nil {
+		log.Printf("Error sending poll corrections: %v", err)
+	}
+}
+
+// renderPollText renders the correction of one line of a poll in style,
+// without the heading renderCorrection adds.
+func renderPollText(correctedMarkup string, opts CorrectOptions, style string) string {
+	if opts.FlagOnly {
+		return correctedMarkup
+	}
+	edits, err := parseInlineEdits(correctedMarkup)
+	if err != nil {
+		return correctedMarkup
+	}
+	return renderEdits(edits, style)
+}
+
+// handlePollsCommand shows or sets whether polls sent to the chat are
+// checked.
+func (gb *GrammarBot) handlePollsCommand(message *tgbotapi.Message, args string) {
+	chatID := message.Chat.ID
+	if !gb.canManageChat(message) {
+		gb.send(tgbotapi.NewMessage(chatID, "Only chat admins can change poll checks."))
+		return
+	}
+
+	if _, ok := parseToggle(args, false); !ok {
+		gb.send(tgbotapi.NewMessage(chatID, "Usage: /polls [on|off]"))
+		return
+	}
+	// No argument flips the stored value
+	var enabled bool
+	if err := gb.store.UpdateChatSettings(chatID, func(stored *ChatSettings) {
+		enabled, _ = parseToggle(args, stored.CheckPolls)
+		stored.CheckPolls = enabled
+	}); err != nil {
+		log.Printf("Error saving chat settings: %v", err)
+		gb.send(tgbotapi.NewMessage(chatID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	reply := "Poll checks are off. I'll leave polls alone."
+	if enabled {
+		reply = "Poll checks are on. I'll check the question and options of polls sent here and reply with any fixes."
+	}
+	gb.send(tgbotapi.NewMessage(chatID, reply))
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Backoff bounds between attempts to re-establish long polling.
+const (
+	reconnectMinBackoff = time.Second
+	reconnectMaxBackoff = time.Minute
+)
+
+// pollUpdates feeds updates into the worker queue until ctx is cancelled.
+// Polling starts from the committed offset, so Telegram sends again what a
+// previous run received but didn't finish. If the updates channel closes
+// unexpectedly, polling is re-established from the committed offset with
+// exponential backoff instead of giving up; updates handled since the last
+// commit are skipped as duplicates.
+func (gb *GrammarBot) pollUpdates(ctx context.Context) {
+	backoff := reconnectMinBackoff
+
+	for {
+		offset := gb.store.Offset()
+		u := tgbotapi.NewUpdate(offset)
+		u.Timeout = 60
+		updates := gb.updatesChan(ctx, u)
+
+	receive:
+		for {
+			select {
+			case <-ctx.Done():
+				gb.bot.StopReceivingUpdates()
+				return
+			case update, ok := <-updates:
+				if !ok {
+					break receive
+				}
+				backoff = reconnectMinBackoff
+
+				// Skip updates Telegram delivered again
+				if update.UpdateID < gb.store.Offset() || !gb.seen.add(update.UpdateID) {
+					log.Printf("Skipping duplicate update %d", update.UpdateID)
+					gb.offsets.done(update.UpdateID)
+					continue
+				}
+
+				gb.telemetry.receive(update.UpdateID)
+				select {
+				case gb.queue <- update:
+				case <-ctx.Done():
+					gb.bot.StopReceivingUpdates()
+					return
+				}
+			}
+		}
+
+		gb.metrics.reconnects.Add(1)
+		log.Printf("Updates channel closed, reconnecting from offset %d in %s", offset, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > reconnectMaxBackoff {
+			backoff = reconnectMaxBackoff
+		}
+		log.Printf("Reconnecting to Telegram updates from offset %d", gb.store.Offset())
+	}
+}
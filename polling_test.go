@@ -0,0 +1,36 @@
+package main
+
+import (
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// TestRedeliveredUpdateHandledOnce has Telegram deliver an update twice in
+// one batch and again after a restart, and checks it is corrected once.
+func TestRedeliveredUpdateHandledOnce(t *testing.T) {
+	cfg := testConfig(t, map[string]string{"STORE_PATH": filepath.Join(t.TempDir(), "store.json")})
+	var calls atomic.Int32
+	first, tg := newTestBot(t, cfg, countingEngine(&calls))
+
+	tg.addMessage(1, 7, "This one is fine.")
+	tg.addMessage(1, 7, "This one is fine.")
+	run(t, first, func() {
+		waitFor(t, "update 1 to be handled", func() bool { return first.store.Offset() == 2 })
+	})
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("engine called %d times for an update delivered twice, want 1", got)
+	}
+
+	tg.mu.Lock()
+	tg.redeliver = true
+	tg.mu.Unlock()
+	tg.addMessage(2, 7, "This one is new.")
+	second := newTestBotOn(t, tg, cfg, countingEngine(&calls))
+	run(t, second, func() {
+		waitFor(t, "update 2 to be handled", func() bool { return second.store.Offset() == 3 })
+	})
+	if got := calls.Load(); got != 2 {
+		t.Errorf("engine called %d times in all, want 2: update 1 was handled again after the restart", got)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// practiceTimeout is how long an exercise waits for an answer before it is
+// considered abandoned.
+const practiceTimeout = 30 * time.Minute
+
+const practicePrompt = `You are a %s language tutor creating a short grammar exercise. Write one everyday sentence of 6 to 15 words in %s that contains exactly one deliberate grammar mistake, such as a wrong verb tense, article, preposition or agreement. Vary the kind of mistake between exercises.
+
+Answer with JSON only, no code fences or other text, in this form:
+{"exercise": "the sentence with the mistake", "answer": "the same sentence with the mistake fixed and nothing else changed", "mistake": "a short name of the mistake, e.g. subject-verb agreement"}`
+
+// practiceExercise is a generated exercise waiting for the user's answer.
+type practiceExercise struct {
+	Exercise string `json:"exercise"`
+	Answer   string `json:"answer"`
+	Mistake  string `json:"mistake"`
+
+	chatID    int64
+	messageID int
+	started   time.Time
+	// daily exercises were sent by the daily practice scheduler
+	daily bool
+}
+
+// practiceSessions holds each user's pending exercise.
+type practiceSessions struct {
+	mu      sync.Mutex
+	pending map[int64]*practiceExercise
+}
+
+func (p *practiceSessions) start(userID int64, exercise *practiceExercise) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pending == nil {
+		p.pending = make(map[int64]*practiceExercise)
+	}
+	// Forget abandoned exercises so the map doesn't keep every user forever
+	for id, e := range p.pending {
+		if time.Since(e.started) >= practiceTimeout {
+			delete(p.pending, id)
+		}
+	}
+	p.pending[userID] = exercise
+}
+
+// take removes and returns the user's pending exercise, if it hasn't expired.
+func (p *practiceSessions) take(userID int64) (*practiceExercise, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	exercise, ok := p.pending[userID]
+	if !ok {
+		return nil, false
+	}
+	delete(p.pending, userID)
+	if time.Since(exercise.started) >= practiceTimeout {
+		return nil, false
+	}
+	return exercise, true
+}
+
+// peek returns the user's pending exercise without removing it.
+func (p *practiceSessions) peek(userID int64) (*practiceExercise, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	exercise, ok := p.pending[userID]
+	if !ok || time.Since(exercise.started) >= practiceTimeout {
+		return nil, false
+	}
+	return exercise, true
+}
+
+// size returns how many exercises are pending, including expired ones not
+// yet cleaned up.
+func (p *practiceSessions) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.pending)
+}
+
+// trimCodeFence removes a code fence the model put around its JSON answer.
+func trimCodeFence(raw string) string {
+	raw = strings.TrimSpace(raw)
+	raw = strings.TrimPrefix(raw, "```json")
+	raw = strings.TrimPrefix(raw, "```")
+	raw = strings.TrimSuffix(raw, "```")
+	return strings.TrimSpace(raw)
+}
+
+// parseExercise decodes the model's exercise, tolerating a code fence
+// around the JSON.
+func parseExercise(raw string) (*practiceExercise, error) {
+	raw = trimCodeFence(raw)
+
+	var exercise practiceExercise
+	if err := json.Unmarshal([]byte(raw), &exercise); err != nil {
+		return nil, fmt.Errorf("failed to decode exercise: %w", err)
+	}
+	if exercise.Exercise == "" || exercise.Answer == "" || exercise.Exercise == exercise.Answer {
+		return nil, fmt.Errorf("model returned an unusable exercise: %q", raw)
+	}
+	return &exercise, nil
+}
+
+// normalizeAnswer makes answers comparable regardless of case, spacing and
+// final punctuation.
+func normalizeAnswer(text string) string {
+	text = strings.Join(strings.Fields(strings.ToLower(text)), " ")
+	return strings.TrimRight(text, ".!?")
+}
+
+// handlePracticeCommand sends a new exercise, or with "stop" abandons the
+// pending one and reveals its answer.
+func (gb *GrammarBot) handlePracticeCommand(message *tgbotapi.Message, args string) {
+	userID := senderID(message)
+
+	if strings.EqualFold(args, "stop") {
+		exercise, ok := gb.practice.take(userID)
+		if !ok {
+			gb.send(tgbotapi.NewMessage(message.Chat.ID, "You have no exercise in progress. Send /practice to get one."))
+			return
+		}
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Exercise stopped. The answer was:\n\n%s", exercise.Answer)))
+		return
+	}
+
+	defer gb.startTyping(message.Chat.ID)()
+
+	exercise, err := gb.generateExercise(userID)
+	if err != nil {
+		log.Printf("Error generating exercise: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't create an exercise right now. Please try again later."))
+		return
+	}
+	if err := gb.sendExercise(message.Chat.ID, userID, exercise, "✏️ Find and fix the mistake:"); err != nil {
+		log.Printf("Error sending exercise: %v", err)
+	}
+}
+
+// generateExercise asks the model for an exercise in the user's language.
+func (gb *GrammarBot) generateExercise(userID int64) (*practiceExercise, error) {
+	language := effectiveLanguage(gb.store.GetUserSettings(userID))
+	raw, err := gb.complete(fmt.Sprintf(practicePrompt, language, language), "Create a new exercise.")
+	if err != nil {
+		return nil, err
+	}
+	return parseExercise(raw)
+}
+
+// sendExercise sends exercise to chatID under heading and waits for the
+// user's answer.
+func (gb *GrammarBot) sendExercise(chatID, userID int64, exercise *practiceExercise, heading string) error {
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"%s\n\n%s\n\nReply with the corrected sentence within %d minutes, or send /practice stop to see the answer.",
+		heading, exercise.Exercise, int(practiceTimeout.Minutes())))
+	sent, err := gb.send(msg)
+	if err != nil {
+		return err
+	}
+
+	exercise.chatID = chatID
+	exercise.messageID = sent.MessageID
+	exercise.started = time.Now()
+	gb.practice.start(userID, exercise)
+	return nil
+}
+
+// handlePracticeAnswer checks message against the sender's pending exercise
+// and reports whether it was an answer. In groups only replies to the
+// exercise count, so ordinary chatter is still corrected as usual.
+func (gb *GrammarBot) handlePracticeAnswer(message *tgbotapi.Message) bool {
+	userID := senderID(message)
+
+	pending, ok := gb.practice.peek(userID)
+	if !ok || pending.chatID != message.Chat.ID {
+		return false
+	}
+	if !message.Chat.IsPrivate() && (message.ReplyToMessage == nil || message.ReplyToMessage.MessageID != pending.messageID) {
+		return false
+	}
+	exercise, ok := gb.practice.take(userID)
+	if !ok {
+		return false
+	}
+
+	if exercise.daily {
+		if err := gb.store.UpdateUserSettings(userID, func(settings *UserSettings) { settings.DailyPracticeDone++ }); err != nil {
+			log.Printf("Error saving daily practice: %v", err)
+		}
+	}
+
+	reply := fmt.Sprintf("❌ Not quite. The correct sentence is:\n\n%s", exercise.Answer)
+	if normalizeAnswer(message.Text) == normalizeAnswer(exercise.Answer) {
+		reply = "✅ Correct, well done!"
+	}
+	if exercise.Mistake != "" {
+		reply += fmt.Sprintf("\n\nThe mistake: %s.", strings.TrimRight(exercise.Mistake, "."))
+	}
+	reply += "\n\nSend /practice for another one."
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, reply)
+	msg.ReplyToMessageID = message.MessageID
+	gb.send(msg)
+	return true
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Preview modes of a group, chosen with /preview: how the sender of /check
+// sees their correction before anyone else.
+const (
+	// previewAlert shows it in a pop-up only the sender sees.
+	previewAlert = "alert"
+	// previewDM sends it to the sender's private chat with the bot.
+	previewDM = "dm"
+)
+
+// previewCallbackPrefix starts the data of the preview buttons, followed by
+// "show" or "post".
+const previewCallbackPrefix = "preview:"
+
+// maxAlertLength is the longest text a callback alert can show.
+const maxAlertLength = 200
+
+// maxPendingPreviews bounds how many previews wait to be posted; the oldest
+// are forgotten first.
+const maxPendingPreviews = 500
+
+// pendingPreview is a correction shown privately and not yet posted.
+type pendingPreview struct {
+	message   *tgbotapi.Message
+	text      string
+	opts      CorrectOptions
+	corrected string
+}
+
+// previews maps the bot's preview prompts to their corrections.
+type previews struct {
+	mu      sync.Mutex
+	pending map[messageKey]pendingPreview
+	order   []messageKey
+}
+
+func (p *previews) put(key messageKey, preview pendingPreview) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pending == nil {
+		p.pending = make(map[messageKey]pendingPreview)
+	}
+	p.pending[key] = preview
+	p.order = append(p.order, key)
+	for len(p.order) > maxPendingPreviews {
+		delete(p.pending, p.order[0])
+		p.order = p.order[1:]
+	}
+}
+
+func (p *previews) get(key messageKey) (pendingPreview, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	preview, ok := p.pending[key]
+	return preview, ok
+}
+
+// take removes and returns the preview of key, so it is posted once.
+func (p *previews) take(key messageKey) (pendingPreview, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	preview, ok := p.pending[key]
+	delete(p.pending, key)
+	return preview, ok
+}
+
+// previewCorrection checks the text of a /check in a group and shows the
+// correction to its sender alone, in a pop-up or their private chat, with a
+// button to post it to the group. When the private chat can't be reached,
+// as before the sender has started the bot, the pop-up is used.
+func (gb *GrammarBot) previewCorrection(message *tgbotapi.Message, text, mode string) {
+	opts := gb.correctOptions(message)
+	if gb.resolveDetectedLanguage(message, text, &opts) {
+		return
+	}
+
+	stopTyping := gb.startTyping(message.Chat.ID)
+	corrected, err := gb.checkGrammarContext(gb.messageContext(message), text, opts)
+	stopTyping()
+	if err != nil {
+		gb.logError("Error checking grammar: %v", err)
+		if gb.ctx.Err() != nil {
+			return
+		}
+		errorMsg := tgbotapi.NewMessage(message.Chat.ID, "Sorry, I encountered an error while checking your grammar. Please try again later.")
+		errorMsg.ReplyToMessageID = message.MessageID
+		gb.send(errorMsg)
+		return
+	}
+
+	name := "Your"
+	if message.From != nil {
+		name = message.From.FirstName + ", your"
+	}
+	post := tgbotapi.NewInlineKeyboardButtonData("📢 Post it here", previewCallbackPrefix+"post")
+	prompt := tgbotapi.NewMessage(message.Chat.ID, name+" correction is ready. Tap Show to see it privately, or post it here.")
+	prompt.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("👁 Show", previewCallbackPrefix+"show"), post))
+
+	if mode == previewDM {
+		heading := escapeMarkdownV2("🔒 Your correction in " + message.Chat.Title + ":")
+		private := tgbotapi.NewMessage(senderID(message), heading+"\n\n"+renderCorrection(corrected, opts, gb.userStyle(senderID(message)), gb.cfg.MaxHighlights))
+		private.ParseMode = "MarkdownV2"
+		if _, err := gb.send(private); err != nil {
+			log.Printf("Error sending private preview, showing it in a pop-up instead: %v", err)
+		} else {
+			prompt.Text = name + " correction is in our private chat. Tap Post to share it here."
+			prompt.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(post))
+		}
+	}
+
+	prompt.ReplyToMessageID = message.MessageID
+	sent, err := gb.send(prompt)
+	if err != nil {
+		log.Printf("Error sending preview: %v", err)
+		return
+	}
+	gb.previews.put(messageKey{sent.Chat.ID, sent.MessageID}, pendingPreview{message: message, text: text, opts: opts, corrected: corrected})
+}
+
+// previewAlertText is the pop-up showing a correction: the corrected text,
+// cut to fit maxAlertLength.
+func previewAlertText(text, corrected string) string {
+	if !hasCorrections(text, corrected) {
+		return "✅ No mistakes found."
+	}
+	alert := []rune("✏️ " + correctedPlainText(corrected))
+	if len(alert) > maxAlertLength {
+		alert = append(alert[:maxAlertLength-1], '…')
+	}
+	return string(alert)
+}
+
+// handlePreviewCallback shows a preview to its author, or posts it to the
+// group in place of the prompt. Other members' taps are turned away.
+func (gb *GrammarBot) handlePreviewCallback(query *tgbotapi.CallbackQuery, action string) {
+	key := messageKey{query.Message.Chat.ID, query.Message.MessageID}
+	preview, ok := gb.previews.get(key)
+	switch {
+	case !ok:
+		gb.answerCallback(tgbotapi.NewCallback(query.ID, "This preview has expired. Use /check again."))
+		return
+	case senderID(preview.message) != query.From.ID:
+		gb.answerCallback(tgbotapi.NewCallback(query.ID, "Only the author of the message can see this correction."))
+		return
+	}
+
+	if action == "show" {
+		gb.answerCallback(tgbotapi.NewCallbackWithAlert(query.ID, previewAlertText(preview.text, preview.corrected)))
+		return
+	}
+
+	if _, ok := gb.previews.take(key); !ok {
+		gb.answerCallback(tgbotapi.NewCallback(query.ID, ""))
+		return
+	}
+	gb.answerCallback(tgbotapi.NewCallback(query.ID, "Posting your correction…"))
+	if _, err := gb.request(tgbotapi.NewDeleteMessage(key.chatID, key.messageID)); err != nil && !isMessageGone(err) {
+		log.Printf("Error deleting preview: %v", err)
+	}
+	gb.replyWithCorrection(preview.message, preview.text, preview.opts, preview.corrected)
+}
+
+// handlePreviewCommand shows or sets how /check in a group previews the
+// correction to its sender: "/preview alert", "/preview dm" or
+// "/preview off".
+func (gb *GrammarBot) handlePreviewCommand(message *tgbotapi.Message, args string) {
+	chatID := message.Chat.ID
+	if message.Chat.IsPrivate() {
+		gb.send(tgbotapi.NewMessage(chatID, "Previews are for groups. Here only you see my corrections anyway."))
+		return
+	}
+
+	settings := gb.store.GetChatSettings(chatID)
+	mode := strings.ToLower(args)
+	switch mode {
+	case "":
+		reply := "Previews are off: /check posts the correction to everyone."
+		switch settings.Preview {
+		case previewAlert:
+			reply = "/check shows the correction to its sender in a pop-up first."
+		case previewDM:
+			reply = "/check sends the correction to its sender's private chat first."
+		}
+		gb.send(tgbotapi.NewMessage(chatID, reply+" Admins can change this with /preview alert, /preview dm or /preview off."))
+		return
+	case previewAlert, previewDM, "off":
+	default:
+		gb.send(tgbotapi.NewMessage(chatID, "Usage: /preview [alert|dm|off]"))
+		return
+	}
+	if !gb.canManageChat(message) {
+		gb.send(tgbotapi.NewMessage(chatID, "Only chat admins can change previews."))
+		return
+	}
+
+	preview := mode
+	if mode == "off" {
+		preview = ""
+	}
+	if err := gb.store.UpdateChatSettings(chatID, func(stored *ChatSettings) { stored.Preview = preview }); err != nil {
+		log.Printf("Error saving chat settings: %v", err)
+		gb.send(tgbotapi.NewMessage(chatID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	reply := "Previews are off. /check posts the correction to everyone again."
+	switch mode {
+	case previewAlert:
+		reply = "Previews are on. /check now shows the correction to its sender in a pop-up, with a button to post it here."
+	case previewDM:
+		reply = "Previews are on. /check now sends the correction to its sender's private chat, with a button to post it here. Members who haven't started a chat with me get a pop-up instead."
+	}
+	gb.send(tgbotapi.NewMessage(chatID, reply))
+}
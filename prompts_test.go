@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestCorrectionExamples checks that every few-shot example is an answer the
+// bot could use: valid MarkdownV2 that marks its edits and otherwise leaves
+// the input exactly as written.
+func TestCorrectionExamples(t *testing.T) {
+	for _, ex := range correctionExamples {
+		t.Run(ex.Input, func(t *testing.T) {
+			if _, mode := validateParseMode(ex.Output, "MarkdownV2"); mode != "MarkdownV2" {
+				t.Errorf("output %q isn't valid MarkdownV2", ex.Output)
+			}
+			edits, err := parseInlineEdits(ex.Output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := originalText(edits); got != ex.Input {
+				t.Errorf("output changes %q to %q outside its marked edits", ex.Input, got)
+			}
+		})
+	}
+}
+
+// TestPromptGolden compares the prompt sent for tricky inputs with the
+// golden files in testdata/prompts, so changes to the instructions or
+// examples show up in review. Run with -update to rewrite them.
+func TestPromptGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		opts CorrectOptions
+	}{
+		{"casual", "lol ya i seen it, its gr8", CorrectOptions{Language: defaultLanguage}},
+		{"markdown", "Use *stars* and _underscores_ (if u want) - it's fine.", CorrectOptions{Language: defaultLanguage}},
+		{"german", "Ich habe gestern nach Hause gegangen.", CorrectOptions{Language: "German"}},
+		{"flag", "She don't like it.", CorrectOptions{Language: defaultLanguage, FlagOnly: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := recordPrompts(nil)
+			if _, err := correctWith(context.Background(), engine, tt.text, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			got := renderPrompt(engine.prompt, engine.text)
+
+			path := filepath.Join("testdata", "prompts", tt.name+".golden")
+			if *update {
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("prompt differs from %s; run go test -update and review the diff\ngot:\n%s", path, got)
+			}
+		})
+	}
+}
+
+// renderPrompt writes a request out the way the model sees its turns.
+func renderPrompt(prompt Prompt, text string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## system\n%s\n", prompt.Instructions)
+	for _, ex := range prompt.Examples {
+		fmt.Fprintf(&b, "\n## user\n%s\n\n## model\n%s\n", ex.Input, ex.Output)
+	}
+	fmt.Fprintf(&b, "\n## user\n%s\n", text)
+	return b.String()
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// reactionType is a Telegram ReactionTypeEmoji. Only Telegram's fixed set of
+// reaction emoji is accepted.
+type reactionType struct {
+	Type  string `json:"type"`
+	Emoji string `json:"emoji"`
+}
+
+// cleanReaction answers a message without mistakes in reaction mode.
+// Telegram doesn't offer ✅ as a reaction, so a thumbs-up stands in.
+const cleanReaction = "👍"
+
+// reactIfClean reacts to message when the user prefers a reaction over a
+// reply for messages without mistakes, and reports whether it did. On any
+// error, such as hitting the reaction rate limit, the caller replies as usual.
+func (gb *GrammarBot) reactIfClean(message *tgbotapi.Message, text, correctedText string) bool {
+	if !gb.store.GetUserSettings(senderID(message)).ReactWhenClean || hasCorrections(text, correctedText) {
+		return false
+	}
+	if err := gb.react(message.Chat.ID, message.MessageID, cleanReaction); err != nil {
+		log.Printf("Error reacting to clean message, replying instead: %v", err)
+		return false
+	}
+	return true
+}
+
+// handleCleanReplyCommand sets how messages without mistakes are answered.
+func (gb *GrammarBot) handleCleanReplyCommand(message *tgbotapi.Message, args string) {
+	userID := senderID(message)
+	var react bool
+	switch strings.ToLower(args) {
+	case "":
+		mode := "text"
+		if gb.store.GetUserSettings(userID).ReactWhenClean {
+			mode = "reaction"
+		}
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Messages without mistakes get a %s. Use /cleanreply text or /cleanreply reaction to change it.", mode)))
+		return
+	case "text":
+	case "reaction":
+		react = true
+	default:
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /cleanreply <text|reaction>"))
+		return
+	}
+
+	if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) { stored.ReactWhenClean = react }); err != nil {
+		log.Printf("Error saving settings: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	reply := "I'll reply with the checked text even when there are no mistakes."
+	if react {
+		reply = "I'll just react with " + cleanReaction + " to messages without mistakes."
+	}
+	gb.replyWithSettings(message, reply)
+}
+
+// react sets the bot's reaction on a message. The Telegram library predates
+// setMessageReaction, so the request is made directly.
+func (gb *GrammarBot) react(chatID int64, messageID int, emoji string) error {
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", chatID)
+	params.AddNonZero("message_id", messageID)
+	if err := params.AddInterface("reaction", []reactionType{{Type: "emoji", Emoji: emoji}}); err != nil {
+		return fmt.Errorf("failed to encode reaction: %w", err)
+	}
+
+	if _, err := gb.makeRequest("setMessageReaction", params); err != nil {
+		return fmt.Errorf("failed to set reaction: %w", err)
+	}
+	return nil
+}
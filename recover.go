@@ -0,0 +1,29 @@
+package main
+
+import (
+	"log"
+	"runtime/debug"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// safeHandleUpdate handles update, recovering from any panic so one bad
+// message can't take down the whole bot. The update ID identifies the
+// request in logs.
+func (gb *GrammarBot) safeHandleUpdate(update tgbotapi.Update) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		gb.metrics.panics.Add(1)
+		log.Printf("Recovered panic handling update %d: %v\n%s", update.UpdateID, r, debug.Stack())
+
+		if _, chatID, ok := updateOrigin(update); ok {
+			gb.send(tgbotapi.NewMessage(chatID, "Sorry, something went wrong while handling your message. Please try again later."))
+		}
+	}()
+
+	gb.handleUpdate(update)
+}
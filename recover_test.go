@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestWorkerSurvivesPanic injects a panic into the handling of one update
+// and checks that the user gets an error reply and the worker goes on with
+// the next update.
+func TestWorkerSurvivesPanic(t *testing.T) {
+	gb, tg := newTestBot(t, testConfig(t, map[string]string{"WORKERS": "1"}), &fakeEngine{correct: func(ctx context.Context, text string, opts CorrectOptions) (string, error) {
+		if strings.Contains(text, "panic") {
+			panic("injected panic")
+		}
+		return text, nil
+	}})
+
+	tg.addMessage(1, 7, "This one will panic.")
+	tg.addMessage(2, 8, "This one is fine.")
+	run(t, gb, func() {
+		waitFor(t, "both updates to be handled", func() bool { return gb.store.Offset() == 3 })
+	})
+
+	if got := gb.metrics.panics.Load(); got != 1 {
+		t.Errorf("panics = %d, want 1", got)
+	}
+	var replied bool
+	for _, params := range tg.callsTo("sendMessage") {
+		if params.Get("chat_id") == "7" && strings.Contains(params.Get("text"), "something went wrong") {
+			replied = true
+		}
+	}
+	if !replied {
+		t.Error("no error reply to the message whose handler panicked")
+	}
+}
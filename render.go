@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Supported reply formatting styles.
+const (
+	// styleInline strikes through mistakes and bolds corrections in place.
+	styleInline = "inline"
+	// styleArrows shows each change as "wrong → right".
+	styleArrows = "arrows"
+	// styleMinimal shows only the final corrected text.
+	styleMinimal = "minimal"
+)
+
+var replyStyles = []string{styleInline, styleArrows, styleMinimal}
+
+// validStyle reports whether style is one of replyStyles.
+func validStyle(style string) bool {
+	for _, s := range replyStyles {
+		if s == style {
+			return true
+		}
+	}
+	return false
+}
+
+// renderEdits formats edits as MarkdownV2 in the given style.
+func renderEdits(edits []Edit, style string) string {
+	if style == styleMinimal {
+		return escapeMarkdownV2(correctedText(edits))
+	}
+
+	var b strings.Builder
+	for _, e := range edits {
+		original := escapeMarkdownV2(e.Original)
+		corrected := escapeMarkdownV2(e.Corrected)
+
+		switch {
+		case !e.Changed():
+			b.WriteString(original)
+		case e.Corrected == "":
+			b.WriteString("~" + original + "~")
+		case e.Original == "" && style == styleArrows:
+			b.WriteString(escapeMarkdownV2("→ ") + "*" + corrected + "*")
+		case e.Original == "":
+			b.WriteString("*" + corrected + "*")
+		case style == styleArrows:
+			b.WriteString(original + escapeMarkdownV2(" → ") + "*" + corrected + "*")
+		default:
+			b.WriteString("~" + original + "~ *" + corrected + "*")
+		}
+	}
+	return b.String()
+}
+
+// renderCorrection builds the reply text for the model's correction in the
+// user's style, highlighting at most maxHighlights changes (0 for all). At
+// minimal verbosity only the corrected text is sent, and at detailed
+// verbosity every change is highlighted and counted. Flag mode output and
+// output that can't be parsed are shown as returned by the model.
+func renderCorrection(correctedText string, opts CorrectOptions, style string, maxHighlights int) string {
+	if opts.FlagOnly {
+		return fmt.Sprintf("🚩 Issues found in your message:\n\n%s", correctedText)
+	}
+
+	body := correctedText
+	if edits, err := parseInlineEdits(correctedText); err != nil {
+		log.Printf("Error parsing correction markup, sending it as is: %v", err)
+	} else {
+		if opts.Verbosity == verbosityMinimal {
+			return renderEdits(edits, styleMinimal)
+		}
+		hidden := 0
+		if style != styleMinimal && opts.Verbosity != verbosityDetailed {
+			edits, hidden = limitHighlights(edits, maxHighlights)
+		}
+		body = renderEdits(edits, style)
+		switch {
+		case hidden == 1:
+			body += "\n\n_\\+1 more minor fix_"
+		case hidden > 1:
+			body += fmt.Sprintf("\n\n_\\+%d more minor fixes_", hidden)
+		}
+		if counts := correctionCounts(edits); opts.Verbosity == verbosityDetailed && counts != "" {
+			body += "\n\n" + counts
+		}
+	}
+
+	return fmt.Sprintf("📝 Grammar check for your message:\n\n%s", body)
+}
@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func TestRenderEditsStyles(t *testing.T) {
+	edits := []Edit{
+		{Original: "She ", Corrected: "She "},
+		{Original: "go", Corrected: "goes"},
+		{Original: " to ", Corrected: " to "},
+		{Corrected: "the"},
+		{Original: " ", Corrected: " "},
+		{Original: "very ", Corrected: ""},
+		{Original: "park.", Corrected: "park."},
+	}
+	tests := map[string]string{
+		styleInline:  `She ~go~ *goes* to *the* ~very ~park\.`,
+		styleArrows:  `She go → *goes* to → *the* ~very ~park\.`,
+		styleMinimal: `She goes to the park\.`,
+	}
+	for _, style := range replyStyles {
+		if got, want := renderEdits(edits, style), tests[style]; got != want {
+			t.Errorf("renderEdits(%s) = %q, want %q", style, got, want)
+		}
+	}
+}
+
+func TestRenderCorrectionStyles(t *testing.T) {
+	tests := map[string]string{
+		styleInline:  "📝 Grammar check for your message:\n\nShe ~go~ *goes* home\\.",
+		styleArrows:  "📝 Grammar check for your message:\n\nShe go → *goes* home\\.",
+		styleMinimal: "📝 Grammar check for your message:\n\nShe goes home\\.",
+	}
+	for style, want := range tests {
+		if got := renderCorrection("She ~go~ *goes* home.", CorrectOptions{}, style, 0); got != want {
+			t.Errorf("renderCorrection(%s) = %q, want %q", style, got, want)
+		}
+	}
+}
+
+// TestStyleCommand checks that /style saves the style the replies use.
+func TestStyleCommand(t *testing.T) {
+	gb, _ := newTestBot(t, testConfig(t, nil), nil)
+	if got := gb.userStyle(7); got != styleInline {
+		t.Fatalf("default style = %q, want %q", got, styleInline)
+	}
+
+	gb.handleStyleCommand(command(7, "/style Arrows"), "Arrows")
+	if got := gb.userStyle(7); got != styleArrows {
+		t.Errorf("style after /style Arrows = %q, want %q", got, styleArrows)
+	}
+	gb.handleStyleCommand(command(7, "/style fancy"), "fancy")
+	if got := gb.userStyle(7); got != styleArrows {
+		t.Errorf("style after an unknown style = %q, want it unchanged", got)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"time"
+)
+
+// repeatReaction acknowledges a message whose correction was just sent.
+const repeatReaction = "👌"
+
+// isRepeatReply reports whether the same reply was sent to chatID within
+// RepeatWindow, and otherwise records reply as the chat's latest. Replies are
+// remembered by hash only.
+func (gb *GrammarBot) isRepeatReply(chatID int64, reply string) bool {
+	if gb.cfg.RepeatWindow <= 0 {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(reply))
+	hash := hex.EncodeToString(sum[:])
+	now := time.Now().UTC()
+
+	if last, ok := gb.store.LastReply(chatID); ok && last.Hash == hash && now.Sub(last.Time) < gb.cfg.RepeatWindow {
+		return true
+	}
+	if err := gb.store.RecordReply(chatID, ReplyRecord{Hash: hash, Time: now}); err != nil {
+		log.Printf("Error saving last reply: %v", err)
+	}
+	return false
+}
+
+// acknowledgeRepeat reacts to a message instead of repeating a correction.
+func (gb *GrammarBot) acknowledgeRepeat(chatID int64, messageID int) {
+	log.Printf("Suppressing repeated correction in chat %d", chatID)
+	if err := gb.react(chatID, messageID, repeatReaction); err != nil {
+		log.Printf("Error reacting to repeated message: %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// resetModes returns settings with every mode back to its default. Only the
+// timezone, update notifications, daily practice and bookkeeping survive, so
+// modes added later are reset too.
+func resetModes(settings UserSettings) UserSettings {
+	return UserSettings{
+		Timezone:          settings.Timezone,
+		RecentLanguages:   settings.RecentLanguages,
+		StreakDays:        settings.StreakDays,
+		StreakDate:        settings.StreakDate,
+		TipsShown:         settings.TipsShown,
+		DailyPractice:     settings.DailyPractice,
+		DailyPracticeNext: settings.DailyPracticeNext,
+		DailyPracticeSent: settings.DailyPracticeSent,
+		DailyPracticeDone: settings.DailyPracticeDone,
+		NotifyUpdates:     settings.NotifyUpdates,
+		LastSeenVersion:   settings.LastSeenVersion,
+	}
+}
+
+// handleResetCommand resets the sender's modes without touching their
+// history.
+func (gb *GrammarBot) handleResetCommand(message *tgbotapi.Message, args string) {
+	userID := senderID(message)
+	if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) { *stored = resetModes(*stored) }); err != nil {
+		log.Printf("Error saving settings: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	gb.replyWithSettings(message, "Your settings are back to the defaults. Your history, timezone and daily practice are unchanged.")
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// backoffPolicy says how often and how long to wait before retrying a
+// failed AI call.
+type backoffPolicy struct {
+	// Attempts is the most calls made, including the first.
+	Attempts int
+	// BaseDelay is the wait before the first retry, growing by Multiplier
+	// for each further retry up to MaxDelay.
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	Multiplier float64
+	// Jitter shortens each wait by a random fraction of up to Jitter, so
+	// retries of concurrent calls don't arrive together.
+	Jitter float64
+}
+
+// delay returns the wait before retry number retry (0 for the first), given
+// a random number in [0, 1).
+func (p backoffPolicy) delay(retry int, random float64) time.Duration {
+	d := float64(p.BaseDelay) * math.Pow(p.Multiplier, float64(retry))
+	if d > float64(p.MaxDelay) {
+		d = float64(p.MaxDelay)
+	}
+	return time.Duration(d * (1 - p.Jitter*random))
+}
+
+// retryable reports whether a call that failed with err may succeed if made
+// again.
+func retryable(err error) bool {
+	switch classifyError(err) {
+	case errorTimeout, errorNetwork, errorRateLimited, errorServer:
+		return true
+	default:
+		return false
+	}
+}
+
+// retry calls call until it succeeds, fails for good, or cfg.Retry.Attempts
+// calls were made, and returns its last error. It stops early once ctx is
+// cancelled.
+func (gb *GrammarBot) retry(ctx context.Context, call func() error) error {
+	policy := gb.cfg.Retry
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if err == nil || attempt >= policy.Attempts || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		wait := policy.delay(attempt-1, rand.Float64())
+		log.Printf("AI call failed (%s), retrying in %s: %v", classifyError(err), wait.Round(time.Millisecond), err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
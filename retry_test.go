@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+func TestBackoffDelayBounds(t *testing.T) {
+	policy := backoffPolicy{Attempts: 6, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second, Multiplier: 3, Jitter: 0.25}
+
+	// Without jitter the delays grow by Multiplier up to MaxDelay
+	want := []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 900 * time.Millisecond, time.Second, time.Second}
+	for retry, w := range want {
+		if got := policy.delay(retry, 0); got != w {
+			t.Errorf("delay(%d) = %s, want %s", retry, got, w)
+		}
+	}
+
+	for range 1000 {
+		retry, random := rand.IntN(len(want)), rand.Float64()
+		got := policy.delay(retry, random)
+		upper := want[retry]
+		lower := time.Duration(float64(upper) * (1 - policy.Jitter))
+		if got > upper || got < lower || got > policy.MaxDelay {
+			t.Fatalf("delay(%d) with jitter %g = %s, want within [%s, %s]", retry, random, got, lower, upper)
+		}
+	}
+}
+
+func TestRetryConfigValidated(t *testing.T) {
+	invalid := []map[string]string{
+		{"RETRY_MAX_ATTEMPTS": "0"},
+		{"RETRY_BASE_DELAY": "2s", "RETRY_MAX_DELAY": "1s"},
+		{"RETRY_MULTIPLIER": "0.5"},
+		{"RETRY_JITTER": "1.5"},
+		{"RETRY_JITTER": "-0.1"},
+		{"RETRY_BASE_DELAY": "soon"},
+	}
+	for _, env := range invalid {
+		t.Setenv("TELEGRAM_BOT_TOKEN", "test-token")
+		t.Setenv("GEMINI_API_KEY", "test-key")
+		for name, value := range env {
+			t.Setenv(name, value)
+		}
+		if _, err := loadConfig(); err == nil {
+			t.Errorf("loadConfig accepted %v", env)
+		}
+		for name := range env {
+			t.Setenv(name, "")
+		}
+	}
+
+	cfg := testConfig(t, map[string]string{"RETRY_MAX_ATTEMPTS": "5", "RETRY_BASE_DELAY": "1s", "RETRY_MAX_DELAY": "10s", "RETRY_MULTIPLIER": "1.5", "RETRY_JITTER": "0"})
+	if want := (backoffPolicy{Attempts: 5, BaseDelay: time.Second, MaxDelay: 10 * time.Second, Multiplier: 1.5}); cfg.Retry != want {
+		t.Errorf("Retry = %+v, want %+v", cfg.Retry, want)
+	}
+}
+
+// TestRetryStopsAtAttempts checks that retryable errors are retried up to
+// the configured attempts and others not at all.
+func TestRetryStopsAtAttempts(t *testing.T) {
+	gb, _ := newTestBot(t, testConfig(t, map[string]string{"RETRY_MAX_ATTEMPTS": "3", "RETRY_BASE_DELAY": "1ms", "RETRY_MAX_DELAY": "2ms"}), nil)
+
+	calls := 0
+	gb.retry(context.Background(), func() error {
+		calls++
+		return context.DeadlineExceeded
+	})
+	if calls != 3 {
+		t.Errorf("timeouts tried %d times, want 3", calls)
+	}
+
+	calls = 0
+	gb.retry(context.Background(), func() error {
+		calls++
+		return errors.New("bad request")
+	})
+	if calls != 1 {
+		t.Errorf("a non-retryable error was tried %d times, want 1", calls)
+	}
+}
+
+func TestRetryableNetworkErrors(t *testing.T) {
+	post := func(err error) error {
+		return fmt.Errorf("failed to generate content: %w", &url.Error{Op: "Post", URL: "https://generativelanguage.googleapis.com", Err: err})
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection reset", post(&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), true},
+		{"broken pipe", post(&net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.EPIPE)}), true},
+		{"EOF", post(io.EOF), true},
+		{"unexpected EOF", post(io.ErrUnexpectedEOF), true},
+		{"temporary DNS failure", post(&net.DNSError{Err: "server misbehaving", Name: "generativelanguage.googleapis.com", IsTemporary: true}), true},
+		{"DNS timeout", post(&net.DNSError{Err: "i/o timeout", Name: "generativelanguage.googleapis.com", IsTimeout: true}), true},
+		{"dial timeout", post(&net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}), true},
+		{"server error", genai.APIError{Code: 503, Message: "overloaded"}, true},
+		{"unknown host", post(&net.DNSError{Err: "no such host", Name: "generativelanguage.googleapis.com", IsNotFound: true}), false},
+		{"connection refused", post(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), false},
+		{"bad request", genai.APIError{Code: 400, Message: "invalid argument"}, false},
+		{"cancelled", post(context.Canceled), false},
+		{"empty answer", ErrEmptyResponse, false},
+	}
+	for _, tt := range tests {
+		if got := retryable(tt.err); got != tt.want {
+			t.Errorf("retryable(%s) = %v, want %v (classified %s)", tt.name, got, tt.want, classifyError(tt.err))
+		}
+	}
+}
+
+// TestCheckRetriedAfterConnectionClosed has the AI server drop the first
+// connection mid-request and checks that the check is retried and succeeds.
+func TestCheckRetriedAfterConnectionClosed(t *testing.T) {
+	var requests atomic.Int32
+	var body []byte
+	answer := answeringHandler(&body, "She ~go~ **goes** home\\.")
+	engine := newTestGeminiEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			conn, _, err := http.NewResponseController(w).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			conn.Close()
+			return
+		}
+		answer(w, r)
+	}, nil)
+	gb, _ := newTestBot(t, testConfig(t, map[string]string{"RETRY_BASE_DELAY": "1ms", "RETRY_MAX_DELAY": "2ms"}), engine)
+
+	corrected, err := gb.checkGrammar("She go home.", CorrectOptions{Language: defaultLanguage})
+	if err != nil {
+		t.Fatalf("check failed after a dropped connection: %v", err)
+	}
+	if corrected != "She ~go~ **goes** home\\." || requests.Load() != 2 {
+		t.Errorf("got %q after %d requests, want the answer of the second", corrected, requests.Load())
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// scopeQuotes pairs the opening and closing quotes a scope marker's text
+// may be wrapped in.
+var scopeQuotes = map[rune]rune{'"': '"', '“': '”', '«': '»', '„': '“'}
+
+// scopedText returns the parts of text the user asked to have checked by
+// writing marker followed by a quoted part, as in `check: "the part"`, each
+// on its own line. Markers are matched case-insensitively. ok is false when
+// text has no marked part, so all of it is checked.
+func scopedText(text, marker string) (scoped string, ok bool) {
+	if marker == "" {
+		return "", false
+	}
+
+	var parts []string
+	lower := strings.ToLower(text)
+	marker = strings.ToLower(marker)
+	for from := 0; ; {
+		i := strings.Index(lower[from:], marker)
+		if i < 0 {
+			break
+		}
+		rest := text[from+i+len(marker):]
+		trimmed := strings.TrimLeftFunc(rest, unicode.IsSpace)
+		from += i + len(marker)
+
+		open, size := firstRune(trimmed)
+		closing, quoted := scopeQuotes[open]
+		if !quoted {
+			continue
+		}
+		body := trimmed[size:]
+		end := strings.IndexRune(body, closing)
+		if end < 0 {
+			continue
+		}
+		if part := strings.TrimSpace(body[:end]); part != "" {
+			parts = append(parts, part)
+		}
+		from += len(rest) - len(trimmed) + size + end
+	}
+	if len(parts) == 0 {
+		return "", false
+	}
+	return strings.Join(parts, "\n"), true
+}
+
+func firstRune(s string) (rune, int) {
+	for _, r := range s {
+		return r, len(string(r))
+	}
+	return 0, 0
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleSelfTestCommand runs a text through the whole correction pipeline,
+// bypassing the cache, and shows admins each stage: the raw model output,
+// the rendered MarkdownV2 and whether Telegram would accept it. The rendered
+// reply follows as it would be sent, to compare.
+func (gb *GrammarBot) handleSelfTestCommand(message *tgbotapi.Message, text string) {
+	if text == "" && message.ReplyToMessage != nil {
+		text = messageText(message.ReplyToMessage)
+	}
+	if text == "" {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /selftest <text>, or reply to a message with /selftest."))
+		return
+	}
+
+	defer gb.startTyping(message.Chat.ID)()
+
+	opts := gb.correctOptions(message)
+	opts.Model = gb.resolveModel(text, opts)
+	raw, err := gb.correct(gb.ctx, text, opts)
+	if err != nil {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Self-test failed at the model call (%s): %v", classifyError(err), err)))
+		return
+	}
+
+	style := gb.userStyle(senderID(message))
+	rendered := renderCorrection(raw, opts, style, gb.cfg.MaxHighlights)
+
+	validation := "✅ valid MarkdownV2"
+	if _, err := parseMarkdownV2(rendered); err != nil {
+		validation = "❌ invalid MarkdownV2, would be sent as plain text: " + err.Error()
+	}
+	parsing := "❌ not in the inline edit format, shown as is"
+	if edits, err := parseInlineEdits(raw); err == nil {
+		changes := 0
+		for _, e := range edits {
+			if e.Changed() {
+				changes++
+			}
+		}
+		parsing = fmt.Sprintf("✅ %d changes", changes)
+	}
+
+	report := fmt.Sprintf("🔬 *Self\\-test*\nModel: %s\nStyle: %s\n\n*Raw model output*\n```\n%s\n```\n*Rendered*\n```\n%s\n```\n*Inline edits:* %s\n*Rendering:* %s",
+		escapeMarkdownV2(opts.Model), escapeMarkdownV2(style),
+		escapeCodeBlock(raw), escapeCodeBlock(rendered),
+		escapeMarkdownV2(parsing), escapeMarkdownV2(validation))
+	msg := tgbotapi.NewMessage(message.Chat.ID, report)
+	msg.ParseMode = "MarkdownV2"
+	if _, err := gb.send(msg); err != nil {
+		log.Printf("Error sending self-test: %v", err)
+		return
+	}
+
+	reply := tgbotapi.NewMessage(message.Chat.ID, rendered)
+	reply.ParseMode = "MarkdownV2"
+	if _, err := gb.send(reply); err != nil {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Telegram rejected the rendered reply: %v", err)))
+	}
+}
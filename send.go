@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"unicode"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rivo/uniseg"
+)
+
+// maxMessageLength is the longest text Telegram accepts in one message, in
+// UTF-16 code units.
+const maxMessageLength = 4096
+
+// truncationNotice ends text cut to fit maxMessageLength.
+const truncationNotice = "…(truncated)"
+
+// send delivers c through the Telegram API. MarkdownV2 text that wouldn't
+// parse is sent as plain text instead, saving a rejected round trip. Text
+// over maxMessageLength is split into several messages. Everything sent is
+// prefixed with the configured response tag. During a Telegram flood wait
+// the message is held until the wait is over.
+func (gb *GrammarBot) send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	switch msg := c.(type) {
+	case tgbotapi.MessageConfig:
+		if utf16Len(gb.tagText(msg.Text, msg.ParseMode)) > maxMessageLength {
+			return gb.sendChunks(msg)
+		}
+		msg.Text, msg.ParseMode = gb.outgoingText(msg.Text, msg.ParseMode)
+		return gb.sendMessage(msg)
+	case tgbotapi.EditMessageTextConfig:
+		// Edits replace a single message, so they can't be split
+		msg.Text, msg.ParseMode = gb.outgoingText(msg.Text, msg.ParseMode)
+		msg.Text, msg.ParseMode = gb.truncateOversized(msg.Text, msg.ParseMode)
+		c = msg
+	case tgbotapi.DocumentConfig:
+		msg.Caption, msg.ParseMode = gb.outgoingText(msg.Caption, msg.ParseMode)
+		c = msg
+	}
+
+	return gb.botSend(c)
+}
+
+// outgoingText prepares text for any send path: it is prefixed with the
+// response tag and sent as plain text if it isn't valid MarkdownV2. Paths
+// that bypass send, such as business replies and inline results, use it
+// too.
+func (gb *GrammarBot) outgoingText(text, parseMode string) (string, string) {
+	return validateParseMode(gb.tagText(text, parseMode), parseMode)
+}
+
+// answerCallback answers a callback query. Its text is tagged like
+// messages; an empty text, which just stops the button's spinner, stays
+// empty so no notification appears.
+func (gb *GrammarBot) answerCallback(callback tgbotapi.CallbackConfig) {
+	if callback.Text != "" {
+		callback.Text = gb.tagText(callback.Text, "")
+	}
+	if _, err := gb.request(callback); err != nil {
+		log.Printf("Error answering callback query: %v", err)
+	}
+}
+
+// tagText prefixes text with the response tag, escaped to match parseMode.
+func (gb *GrammarBot) tagText(text, parseMode string) string {
+	tag := gb.cfg.ResponseTag
+	if tag == "" {
+		return text
+	}
+	if parseMode == "MarkdownV2" {
+		tag = escapeMarkdownV2(tag)
+	}
+	if text == "" {
+		return tag
+	}
+	return tag + " " + text
+}
+
+// sendChunks sends long text as consecutive messages. Only the first replies
+// to the original message and only the last carries the reply markup. Each
+// starts with the response tag, so every part shows which instance sent it.
+// It returns the last message sent.
+func (gb *GrammarBot) sendChunks(msg tgbotapi.MessageConfig) (tgbotapi.Message, error) {
+	limit := maxMessageLength
+	if gb.cfg.ResponseTag != "" {
+		// The tag and the space after it
+		limit -= utf16Len(gb.tagText("", msg.ParseMode)) + 1
+	}
+	chunks := splitMessage(msg.Text, limit, msg.ParseMode == "MarkdownV2", gb.cfg.ChunkMarkers)
+
+	var sent tgbotapi.Message
+	for i, chunk := range chunks {
+		part := msg
+		part.Text, part.ParseMode = gb.outgoingText(chunk, msg.ParseMode)
+		part.Text, part.ParseMode = gb.truncateOversized(part.Text, part.ParseMode)
+		if i > 0 {
+			part.ReplyToMessageID = 0
+		}
+		if i < len(chunks)-1 {
+			part.ReplyMarkup = nil
+		}
+
+		var err error
+		if sent, err = gb.sendMessage(part); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// sendMessage sends msg, resending it as a plain message when the message it
+// replies to was deleted in the meantime.
+func (gb *GrammarBot) sendMessage(msg tgbotapi.MessageConfig) (tgbotapi.Message, error) {
+	sent, err := gb.botSend(msg)
+	if err != nil && msg.ReplyToMessageID != 0 && isReplyTargetGone(err) {
+		log.Printf("Message %d in chat %d was deleted before the reply, sending without reply", msg.ReplyToMessageID, msg.ChatID)
+		msg.ReplyToMessageID = 0
+		return gb.botSend(msg)
+	}
+	return sent, err
+}
+
+// isReplyTargetGone reports whether err means the message being replied to
+// no longer exists.
+func isReplyTargetGone(err error) bool {
+	var apiErr *tgbotapi.Error
+	return errors.As(err, &apiErr) && strings.Contains(apiErr.Message, "message to be replied not found")
+}
+
+// truncateOversized is the last resort for text still over maxMessageLength,
+// which Telegram would reject outright: it is cut between grapheme clusters
+// and ends with truncationNotice. Markup can't be cut safely, so MarkdownV2
+// is sent as plain text. Every truncation is counted in the metrics.
+func (gb *GrammarBot) truncateOversized(text, parseMode string) (string, string) {
+	if utf16Len(text) <= maxMessageLength {
+		return text, parseMode
+	}
+	gb.metrics.truncations.Add(1)
+	log.Printf("Truncating a message of %d UTF-16 units to %d", utf16Len(text), maxMessageLength)
+
+	if parseMode == "MarkdownV2" {
+		text, parseMode = stripMarkdownV2(text), ""
+	}
+	limit := maxMessageLength - utf16Len(truncationNotice)
+	g := uniseg.NewGraphemes(text)
+	end, units := 0, 0
+	for g.Next() {
+		units += utf16Len(g.Str())
+		if units > limit {
+			break
+		}
+		_, end = g.Positions()
+	}
+	return strings.TrimRightFunc(text[:end], unicode.IsSpace) + truncationNotice, parseMode
+}
+
+// validateParseMode falls back to plain text when text is invalid MarkdownV2.
+func validateParseMode(text, parseMode string) (string, string) {
+	if parseMode != "MarkdownV2" {
+		return text, parseMode
+	}
+	if _, err := parseMarkdownV2(text); err != nil {
+		log.Printf("Invalid MarkdownV2, sending as plain text: %v", err)
+		return stripMarkdownV2(text), ""
+	}
+	return text, parseMode
+}
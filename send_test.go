@@ -0,0 +1,121 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TestSendWithoutDeletedReplyTarget checks that a reply to a message deleted
+// in the meantime is sent as a plain message instead of being dropped.
+func TestSendWithoutDeletedReplyTarget(t *testing.T) {
+	gb, tg := newTestBot(t, testConfig(t, nil), nil)
+	tg.mu.Lock()
+	tg.fail = map[string]func(url.Values) (int, string, int){
+		"sendMessage": func(params url.Values) (int, string, int) {
+			if params.Get("reply_to_message_id") != "" {
+				return 400, "Bad Request: message to be replied not found", 0
+			}
+			return 0, "", 0
+		},
+	}
+	tg.mu.Unlock()
+
+	msg := tgbotapi.NewMessage(7, "She goes home.")
+	msg.ReplyToMessageID = 5
+	if _, err := gb.send(msg); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	calls := tg.callsTo("sendMessage")
+	if len(calls) != 2 {
+		t.Fatalf("sendMessage called %d times, want the reply and the resend", len(calls))
+	}
+	if calls[0].Get("reply_to_message_id") != "5" || calls[1].Get("reply_to_message_id") != "" {
+		t.Errorf("reply_to_message_id = %q then %q, want 5 then none", calls[0].Get("reply_to_message_id"), calls[1].Get("reply_to_message_id"))
+	}
+}
+
+// TestSendKeepsOtherErrors checks that other errors aren't retried without
+// the reply.
+func TestSendKeepsOtherErrors(t *testing.T) {
+	gb, tg := newTestBot(t, testConfig(t, nil), nil)
+	tg.mu.Lock()
+	tg.fail = map[string]func(url.Values) (int, string, int){
+		"sendMessage": func(url.Values) (int, string, int) {
+			return 403, "Forbidden: bot was blocked by the user", 0
+		},
+	}
+	tg.mu.Unlock()
+
+	msg := tgbotapi.NewMessage(7, "She goes home.")
+	msg.ReplyToMessageID = 5
+	if _, err := gb.send(msg); err == nil {
+		t.Fatal("send succeeded, want the API error")
+	}
+	if got := len(tg.callsTo("sendMessage")); got != 1 {
+		t.Errorf("sendMessage called %d times, want 1", got)
+	}
+}
+
+// TestResponseTagOnEveryPath checks that messages, business replies, inline
+// results and callback answers all carry the response tag, escaped for
+// MarkdownV2 where needed.
+func TestResponseTagOnEveryPath(t *testing.T) {
+	gb, tg := newTestBot(t, testConfig(t, map[string]string{"RESPONSE_TAG": "[staging]"}), nil)
+
+	gb.send(tgbotapi.NewMessage(7, "Hello there."))
+	markdown := tgbotapi.NewMessage(7, "She ~go~ **goes**\\.")
+	markdown.ParseMode = "MarkdownV2"
+	gb.sendBusinessMessage("connection", markdown)
+	gb.answerInline("query", 1, tgbotapi.InlineQueryResultArticle{
+		Type: "article", ID: "annotated", Title: "Send",
+		InputMessageContent: tgbotapi.InputTextMessageContent{Text: "She ~go~ **goes**\\.", ParseMode: "MarkdownV2"},
+	})
+	gb.answerCallback(tgbotapi.NewCallback("callback", "Thanks for the feedback!"))
+	gb.answerCallback(tgbotapi.NewCallback("silent", ""))
+
+	messages := tg.callsTo("sendMessage")
+	if len(messages) != 2 {
+		t.Fatalf("sent %d messages, want 2", len(messages))
+	}
+	if got := messages[0].Get("text"); got != "[staging] Hello there." {
+		t.Errorf("message = %q, want it tagged", got)
+	}
+	if got := messages[1].Get("text"); got != "\\[staging\\] She ~go~ **goes**\\." || messages[1].Get("business_connection_id") != "connection" {
+		t.Errorf("business reply = %q, want it tagged and escaped", got)
+	}
+	if got := tg.callsTo("answerInlineQuery")[0].Get("results"); !strings.Contains(got, `"message_text":"\\[staging\\] She ~go~`) {
+		t.Errorf("inline results = %s, want the message tagged and escaped", got)
+	}
+	callbacks := tg.callsTo("answerCallbackQuery")
+	if got := callbacks[0].Get("text"); got != "[staging] Thanks for the feedback!" {
+		t.Errorf("callback answer = %q, want it tagged", got)
+	}
+	if got := callbacks[1].Get("text"); got != "" {
+		t.Errorf("silent callback answer = %q, want no text", got)
+	}
+
+	// Long replies are split, and every part is tagged and still fits
+	long := tgbotapi.NewMessage(7, strings.Repeat("She ~go~ **goes** home today\\. ", 500))
+	long.ParseMode = "MarkdownV2"
+	gb.send(long)
+	chunks := tg.callsTo("sendMessage")[2:]
+	if len(chunks) < 2 {
+		t.Fatalf("a long reply was sent as %d messages, want several", len(chunks))
+	}
+	for i, chunk := range chunks {
+		text := chunk.Get("text")
+		if !strings.HasPrefix(text, "\\[staging\\] ") || chunk.Get("parse_mode") != "MarkdownV2" {
+			t.Errorf("part %d starts %q, want the escaped tag", i+1, text[:min(len(text), 30)])
+		}
+		if utf16Len(text) > maxMessageLength {
+			t.Errorf("part %d is %d UTF-16 units long with the tag, over the limit", i+1, utf16Len(text))
+		}
+	}
+	if got := gb.metrics.truncations.Load(); got != 0 {
+		t.Errorf("%d parts were truncated to fit the tag", got)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// sentenceBatchChars is roughly how many characters of consecutive sentences
+// are corrected together when long input is split.
+const sentenceBatchChars = 500
+
+// abbreviations end in a period that doesn't end a sentence. Single letters,
+// like the initials in "J. R. R. Tolkien", and dotted abbreviations like
+// "e.g." are handled separately.
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "st": true,
+	"jr": true, "sr": true, "vs": true, "etc": true,
+	"no": true, "fig": true, "approx": true, "dept": true, "inc": true, "ltd": true,
+	"jan": true, "feb": true, "mar": true, "apr": true, "jun": true, "jul": true,
+	"aug": true, "sep": true, "sept": true, "oct": true, "nov": true, "dec": true,
+}
+
+// splitSentences splits text into sentences, each keeping the whitespace
+// that follows it, so joining them gives back text exactly. A sentence ends
+// at a line break or CJK full stop, or at ., ! or ? (and any closing quotes or brackets)
+// followed by space and anything but a lowercase letter. Periods of
+// decimals, abbreviations and initials don't end sentences.
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+
+		end := -1
+		switch {
+		case r == '\n' || r == '。' || r == '！' || r == '？':
+			end = i
+		case r == '.' || r == '!' || r == '?':
+			// Take in runs like "?!" or "..." and closing quotes and brackets
+			j := i
+			for j < len(text) {
+				next, size := utf8.DecodeRuneInString(text[j:])
+				if !strings.ContainsRune(".!?\"'”’»)]", next) {
+					break
+				}
+				j += size
+			}
+			if r == '.' && j == i && isAbbreviation(text[start:i-1]) {
+				break
+			}
+			if startsSentence(text[j:]) {
+				end = j
+			}
+			i = j
+		}
+		if end < 0 {
+			continue
+		}
+
+		// Keep the following whitespace with the sentence
+		for end < len(text) {
+			next, size := utf8.DecodeRuneInString(text[end:])
+			if !unicode.IsSpace(next) {
+				break
+			}
+			end += size
+		}
+		sentences = append(sentences, text[start:end])
+		start, i = end, end
+	}
+	if start < len(text) {
+		sentences = append(sentences, text[start:])
+	}
+	return sentences
+}
+
+// isAbbreviation reports whether the last word of text is an abbreviation or
+// initial, given that a period follows it.
+func isAbbreviation(text string) bool {
+	word := text[strings.LastIndexFunc(text, func(r rune) bool { return unicode.IsSpace(r) || r == '(' })+1:]
+	if utf8.RuneCountInString(word) == 1 {
+		r, _ := utf8.DecodeRuneInString(word)
+		return unicode.IsLetter(r)
+	}
+	return strings.Contains(word, ".") || abbreviations[strings.ToLower(word)]
+}
+
+// startsSentence reports whether text, which follows sentence-ending
+// punctuation, begins a new sentence.
+func startsSentence(text string) bool {
+	trimmed := strings.TrimLeftFunc(text, unicode.IsSpace)
+	if trimmed == "" {
+		return true
+	}
+	if len(trimmed) == len(text) {
+		// No space after the punctuation, as in "3.14" or "example.com"
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(trimmed)
+	return !unicode.IsLower(r)
+}
+
+// sentenceBatches groups consecutive sentences into batches of about
+// sentenceBatchChars characters.
+func sentenceBatches(sentences []string) []string {
+	var batches []string
+	var current strings.Builder
+	for _, sentence := range sentences {
+		if current.Len() > 0 && utf8.RuneCountInString(current.String())+utf8.RuneCountInString(sentence) > sentenceBatchChars {
+			batches = append(batches, current.String())
+			current.Reset()
+		}
+		current.WriteString(sentence)
+	}
+	if current.Len() > 0 {
+		batches = append(batches, current.String())
+	}
+	return batches
+}
+
+// correctInBatches corrects long text in batches of sentences, at most
+// cfg.SentenceConcurrency at a time, and joins the corrections back in
+// order with the original spacing between them.
+func (gb *GrammarBot) correctInBatches(ctx context.Context, text string, opts CorrectOptions) (string, error) {
+	batches := sentenceBatches(splitSentences(text))
+	corrected := make([]string, len(batches))
+	errs := make([]error, len(batches))
+
+	slots := make(chan struct{}, gb.cfg.SentenceConcurrency)
+	var wg sync.WaitGroup
+	for i, batch := range batches {
+		body := strings.TrimSpace(batch)
+		if body == "" {
+			corrected[i] = batch
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			leading := batch[:strings.Index(batch, body)]
+			trailing := batch[len(leading)+len(body):]
+			correctedBody, err := gb.correctOnce(ctx, body, opts)
+			corrected[i], errs[i] = leading+correctedBody+trailing, err
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(corrected, ""), nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// renderSettings summarizes the stored settings of userID as they take
+// effect, one per line. user is the Telegram user, for a native explanation
+// language.
+func (gb *GrammarBot) renderSettings(userID int64, user *tgbotapi.User) string {
+	settings := gb.store.GetUserSettings(userID)
+
+	language := effectiveLanguage(settings)
+	if settings.AutoLanguage {
+		language = "detected for each message"
+	}
+	explanations := "off"
+	if settings.Explain {
+		explanations = "in " + explanationLanguage(settings, user)
+	}
+	categories := "all"
+	if len(settings.Categories) > 0 {
+		categories = strings.Join(settings.Categories, ", ")
+	}
+	model := "the bot's default"
+	if settings.Model == modelStandard || settings.Model == modelPro && gb.entitledToPro(userID) {
+		model = settings.Model
+	}
+	cleanReply := "text reply"
+	if settings.ReactWhenClean {
+		cleanReply = "reaction"
+	}
+	ratings := "👍/👎"
+	if settings.StarRatings {
+		ratings = "1–5 stars"
+	}
+	dailyPractice := "off"
+	if settings.DailyPractice != "" {
+		dailyPractice = "at " + settings.DailyPractice
+	}
+
+	return fmt.Sprintf(`Language: %s
+Style: %s
+Flag mode: %s
+Explanations: %s
+Mixed-language mode: %s
+Strictness: %s
+Verbosity: %s
+Categories: %s
+Formal reports: %s
+Version changes: %s
+Model: %s
+Messages without mistakes: %s
+Rating buttons: %s
+Streak notes: %s
+Learning tips: %s
+Digest mode: %s
+Daily practice: %s
+Timezone: %s`,
+		language, gb.userStyle(userID), onOff(settings.FlagOnly), explanations, onOff(settings.Mixed),
+		effectiveStrictness(settings), effectiveVerbosity(settings), categories, onOff(settings.Report),
+		onOff(settings.VersionDiff), model, cleanReply, ratings, onOff(!settings.StreakNotesOff),
+		onOff(settings.Tips), onOff(settings.Digest), dailyPractice, gb.userLocation(userID))
+}
+
+// replyWithSettings answers a settings command with reply followed by the
+// sender's settings, so they can see the change took and how it combines
+// with the rest.
+func (gb *GrammarBot) replyWithSettings(message *tgbotapi.Message, reply string) {
+	gb.send(tgbotapi.NewMessage(message.Chat.ID, reply+"\n\n"+gb.renderSettings(senderID(message), message.From)))
+}
+
+// handleSettingsCommand shows the sender's settings.
+func (gb *GrammarBot) handleSettingsCommand(message *tgbotapi.Message, args string) {
+	reply := "Your settings:"
+	if locked := gb.chatLanguage(message.Chat); locked != "" {
+		reply = fmt.Sprintf("Your settings. This chat's admins have set all corrections here to %s.", locked)
+	}
+	gb.replyWithSettings(message, reply)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TestSettingCommandsKeepOtherSettings runs one settings command after
+// another and checks that each leaves the settings before it in place.
+func TestSettingCommandsKeepOtherSettings(t *testing.T) {
+	gb, tg := newTestBot(t, testConfig(t, nil), nil)
+
+	commands := []string{
+		"/language German",
+		"/style arrows",
+		"/strictness high",
+		"/verbosity detailed",
+		"/categories spelling",
+		"/explain on",
+		"/flag on",
+		"/mixed on",
+		"/cleanreply reaction",
+		"/feedback stars",
+		"/tips on",
+		"/timezone Europe/Berlin",
+	}
+	for _, text := range commands {
+		gb.handleCommand(command(7, text))
+	}
+
+	got := gb.store.GetUserSettings(7)
+	want := UserSettings{
+		Language:       "German",
+		Style:          styleArrows,
+		Strictness:     strictnessHigh,
+		Verbosity:      verbosityDetailed,
+		Categories:     []string{"spelling"},
+		Explain:        true,
+		FlagOnly:       true,
+		Mixed:          true,
+		ReactWhenClean: true,
+		StarRatings:    true,
+		Tips:           true,
+		Timezone:       "Europe/Berlin",
+	}
+	// Only compare what the commands set
+	got = UserSettings{
+		Language: got.Language, Style: got.Style, Strictness: got.Strictness, Verbosity: got.Verbosity,
+		Categories: got.Categories, Explain: got.Explain, FlagOnly: got.FlagOnly, Mixed: got.Mixed,
+		ReactWhenClean: got.ReactWhenClean, StarRatings: got.StarRatings, Tips: got.Tips, Timezone: got.Timezone,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("settings after every command = %+v, want %+v", got, want)
+	}
+
+	// Repeating a command changes nothing and shows the same settings
+	replies := tg.callsTo("sendMessage")
+	last := replies[len(replies)-1].Get("text")
+	gb.handleCommand(command(7, "/timezone Europe/Berlin"))
+	replies = tg.callsTo("sendMessage")
+	if again := replies[len(replies)-1].Get("text"); settingsPart(again) != settingsPart(last) {
+		t.Errorf("repeated command showed\n%s\nwant\n%s", settingsPart(again), settingsPart(last))
+	}
+}
+
+// settingsPart returns the settings summary closing a settings reply.
+func settingsPart(reply string) string {
+	_, summary, _ := strings.Cut(reply, "\n\n")
+	return summary
+}
+
+// TestSettingCommandsEchoSettings checks that settings commands and
+// /settings all end their reply with the same summary of the result.
+func TestSettingCommandsEchoSettings(t *testing.T) {
+	gb, tg := newTestBot(t, testConfig(t, nil), nil)
+
+	gb.handleCommand(command(7, "/style minimal"))
+	gb.handleCommand(command(7, "/settings"))
+
+	replies := tg.callsTo("sendMessage")
+	if len(replies) != 2 {
+		t.Fatalf("got %d replies, want 2", len(replies))
+	}
+	summary := gb.renderSettings(7, &tgbotapi.User{ID: 7, FirstName: "Ann"})
+	for i, reply := range replies {
+		if text := reply.Get("text"); !strings.HasSuffix(text, "\n\n"+summary) {
+			t.Errorf("reply %d = %q, want it to end with the settings summary", i, text)
+		}
+	}
+	if !strings.Contains(summary, "Style: minimal\n") {
+		t.Errorf("summary doesn't show the new style:\n%s", summary)
+	}
+}
+
+// TestTogglesConcurrent flips settings from many goroutines at once, with
+// the handlers called directly so no flip is debounced, and checks that an
+// even number of flips leaves each setting as it was. Run with -race.
+func TestTogglesConcurrent(t *testing.T) {
+	gb, _ := newTestBot(t, testConfig(t, nil), nil)
+	toggles := map[string]func(*tgbotapi.Message, string){
+		"/flag": gb.handleFlagCommand, "/digest": gb.handleDigestCommand, "/mixed": gb.handleMixedCommand,
+		"/tips": gb.handleTipsCommand, "/versions": gb.handleVersionsCommand, "/report": gb.handleReportCommand,
+	}
+
+	var wg sync.WaitGroup
+	for name, handle := range toggles {
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				handle(command(7, name), "")
+			}()
+		}
+	}
+	wg.Wait()
+
+	got := gb.store.GetUserSettings(7)
+	if got.FlagOnly || got.Digest || got.Mixed || got.Tips || got.VersionDiff || got.Report {
+		t.Errorf("settings after an even number of flips = %+v, want every toggle off again", got)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sort"
+	"syscall"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// Error types counted in Stats.ErrorsByType.
+const (
+	errorTimeout     = "timeout"
+	errorNetwork     = "network"
+	errorRateLimited = "rate_limited"
+	errorServer      = "server"
+	errorClient      = "client"
+	errorEmpty       = "empty"
+	errorSafety      = "safety"
+	errorOther       = "other"
+)
+
+// Stats is a point-in-time copy of the bot's counters, the single source for
+// admin commands and exporters.
+type Stats struct {
+	Checks        int64            `json:"checks"`
+	CheckErrors   int64            `json:"check_errors"`
+	ErrorsByType  map[string]int64 `json:"errors_by_type"`
+	InFlight      int64            `json:"in_flight"`
+	ActiveWorkers int64            `json:"active_workers"`
+	Panics        int64            `json:"panics"`
+	Reconnects    int64            `json:"reconnects"`
+	Truncations   int64            `json:"truncations"`
+	FloodWaits    int64            `json:"flood_waits"`
+
+	// StructuredCorrections counts corrections asked for in the JSON
+	// format, and StructuredReprompts those asked for again because the
+	// answer couldn't be used.
+	StructuredCorrections  int64   `json:"structured_corrections"`
+	StructuredReprompts    int64   `json:"structured_reprompts"`
+	StructuredRepromptRate float64 `json:"structured_reprompt_rate"`
+
+	RecentErrorRate float64 `json:"recent_error_rate"`
+	RecentSamples   int     `json:"recent_samples"`
+
+	Models  map[string]int64 `json:"models"`
+	Latency LatencyStats     `json:"latency"`
+	Cache   CacheStats       `json:"cache"`
+}
+
+// LatencyStats are percentiles of recent AI call durations.
+type LatencyStats struct {
+	P50     time.Duration `json:"p50_ns"`
+	P90     time.Duration `json:"p90_ns"`
+	P99     time.Duration `json:"p99_ns"`
+	Samples int           `json:"samples"`
+}
+
+// CacheStats describes the correction cache.
+type CacheStats struct {
+	Size     int     `json:"size"`
+	Capacity int     `json:"capacity"`
+	Hits     int64   `json:"hits"`
+	Misses   int64   `json:"misses"`
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+// Snapshot returns a copy of the current counters. It is safe to call from
+// any goroutine.
+func (gb *GrammarBot) Snapshot() Stats {
+	m := gb.metrics
+	s := Stats{
+		Checks:        m.checks.Load(),
+		CheckErrors:   m.checkErrors.Load(),
+		InFlight:      m.inFlight.Load(),
+		ActiveWorkers: m.activeWorkers.Load(),
+		Panics:        m.panics.Load(),
+		Reconnects:    m.reconnects.Load(),
+		Truncations:   m.truncations.Load(),
+		FloodWaits:    m.floodWaits.Load(),
+
+		StructuredCorrections: m.structuredAttempts.Load(),
+		StructuredReprompts:   m.structuredReprompts.Load(),
+		Models:                m.modelSplit(),
+		ErrorsByType:          m.errorTypes(),
+		Latency:               m.latencyPercentiles(),
+	}
+	s.RecentErrorRate, s.RecentSamples = m.recentErrorRate()
+	if s.StructuredCorrections > 0 {
+		s.StructuredRepromptRate = float64(s.StructuredReprompts) / float64(s.StructuredCorrections)
+	}
+
+	s.Cache = CacheStats{
+		Size:     gb.cache.size(),
+		Capacity: gb.cache.capacity,
+		Hits:     gb.cache.hits.Load(),
+		Misses:   gb.cache.misses.Load(),
+	}
+	if lookups := s.Cache.Hits + s.Cache.Misses; lookups > 0 {
+		s.Cache.HitRatio = float64(s.Cache.Hits) / float64(lookups)
+	}
+	return s
+}
+
+// classifyError names the kind of failure err is, for ErrorsByType.
+func classifyError(err error) string {
+	var (
+		apiErr    genai.APIError
+		statusErr *httpStatusError
+		netErr    net.Error
+		code      int
+	)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return errorTimeout
+	case isTransientNetworkError(err):
+		return errorNetwork
+	case errors.Is(err, ErrEmptyResponse):
+		return errorEmpty
+	case errors.Is(err, ErrSafetyBlocked):
+		return errorSafety
+	case errors.As(err, &apiErr):
+		code = apiErr.Code
+	case errors.As(err, &statusErr):
+		code = statusErr.StatusCode
+	}
+
+	switch {
+	case code == 429:
+		return errorRateLimited
+	case code >= 500:
+		return errorServer
+	case code >= 400:
+		return errorClient
+	default:
+		return errorOther
+	}
+}
+
+// isTransientNetworkError reports whether err is a connection failure that
+// may not recur, such as a reset connection, a connection closed mid-answer
+// or a temporary DNS failure.
+func isTransientNetworkError(err error) bool {
+	var dnsErr *net.DNSError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return false
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNABORTED), errors.Is(err, syscall.EPIPE):
+		return true
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	case errors.As(err, &dnsErr):
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+	var temporary interface{ Temporary() bool }
+	return errors.As(err, &temporary) && temporary.Temporary()
+}
+
+// percentile returns the p-th percentile of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p * float64(len(sorted)-1))
+	return sorted[i]
+}
+
+// latencyPercentiles summarizes the durations of recent calls.
+func (m *Metrics) latencyPercentiles() LatencyStats {
+	m.mu.Lock()
+	durations := append([]time.Duration(nil), m.latency[:m.filled]...)
+	m.mu.Unlock()
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	return LatencyStats{
+		P50:     percentile(durations, 0.50),
+		P90:     percentile(durations, 0.90),
+		P99:     percentile(durations, 0.99),
+		Samples: len(durations),
+	}
+}
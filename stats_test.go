@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// TestSnapshotConcurrent records AI calls from many goroutines while others
+// take snapshots, and checks the final counters. Run with -race.
+func TestSnapshotConcurrent(t *testing.T) {
+	gb, _ := newTestBot(t, testConfig(t, nil), nil)
+	const workers, rounds = 8, 50
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := range rounds {
+				var err error
+				switch {
+				case i%5 == 0:
+					err = ErrEmptyResponse
+				case i%5 == 1:
+					err = context.DeadlineExceeded
+				}
+				gb.metrics.beginCall()(err)
+				gb.metrics.recordModel([]string{"flash", "pro"}[w%2])
+				gb.metrics.panics.Add(1)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				s := gb.Snapshot()
+				if s.CheckErrors > s.Checks {
+					t.Errorf("snapshot has %d errors in %d checks", s.CheckErrors, s.Checks)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	s := gb.Snapshot()
+	const calls = workers * rounds
+	if s.Checks != calls || s.CheckErrors != 2*calls/5 || s.Panics != calls || s.InFlight != 0 {
+		t.Errorf("checks %d, errors %d, panics %d, in flight %d, want %d, %d, %d, 0", s.Checks, s.CheckErrors, s.Panics, s.InFlight, calls, 2*calls/5, calls)
+	}
+	if s.ErrorsByType[errorEmpty] != calls/5 || s.ErrorsByType[errorTimeout] != calls/5 {
+		t.Errorf("errors by type = %v, want %d empty and %d timeouts", s.ErrorsByType, calls/5, calls/5)
+	}
+	if s.Models["flash"] != calls/2 || s.Models["pro"] != calls/2 {
+		t.Errorf("models = %v, want %d each", s.Models, calls/2)
+	}
+	if s.RecentSamples != errorWindowSize {
+		t.Errorf("recent error rate is over %d calls, want %d", s.RecentSamples, errorWindowSize)
+	}
+	if s.Latency.Samples != errorWindowSize {
+		t.Errorf("latency samples = %d, want %d", s.Latency.Samples, errorWindowSize)
+	}
+}
+
+func TestSnapshotIsCopy(t *testing.T) {
+	gb, _ := newTestBot(t, testConfig(t, nil), nil)
+	gb.metrics.recordModel("flash")
+
+	s := gb.Snapshot()
+	s.Models["flash"] = 100
+	if got := gb.Snapshot().Models["flash"]; got != 1 {
+		t.Errorf("model count changed to %d through a snapshot", got)
+	}
+}
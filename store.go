@@ -0,0 +1,598 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxHistoryEntries is how many recent checks are kept per user.
+const maxHistoryEntries = 50
+
+// UserSettings holds a user's preferences.
+type UserSettings struct {
+	// FlagOnly marks mistakes without rewriting them.
+	FlagOnly bool `json:"flag_only,omitempty"`
+	// Timezone is the IANA zone timestamps are shown in. Empty means UTC.
+	Timezone string `json:"timezone,omitempty"`
+	// Language is the correction language. Empty means defaultLanguage.
+	Language string `json:"language,omitempty"`
+	// AutoLanguage detects the language of each message, falling back to
+	// Language when detection fails.
+	AutoLanguage bool `json:"auto_language,omitempty"`
+	// RecentLanguages lists recently used correction languages, newest first.
+	RecentLanguages []string `json:"recent_languages,omitempty"`
+	// Style is the reply formatting style. Empty means styleInline.
+	Style string `json:"style,omitempty"`
+	// Explain adds a short explanation of each correction.
+	Explain bool `json:"explain,omitempty"`
+	// ExplainLanguage is the language of explanations: a language name,
+	// explainNative, or empty/explainSame for the correction language.
+	ExplainLanguage string `json:"explain_language,omitempty"`
+	// Mixed corrects each language of a mixed-language message on its own.
+	Mixed bool `json:"mixed,omitempty"`
+	// Model is the preferred model tier, modelStandard or modelPro. Empty
+	// follows MODEL_SELECTION.
+	Model string `json:"model,omitempty"`
+	// Strictness is how much of the text corrections may change, one of the
+	// strictness levels. Empty means strictnessMedium.
+	Strictness string `json:"strictness,omitempty"`
+	// Verbosity is how much correction replies say, one of the verbosity
+	// levels. Empty means verbosityNormal.
+	Verbosity string `json:"verbosity,omitempty"`
+	// Categories limits corrections to some correctionCategories. Empty
+	// corrects every category.
+	Categories []string `json:"categories,omitempty"`
+	// StarRatings offers 1–5 star rating buttons under corrections instead
+	// of 👍 and 👎.
+	StarRatings bool `json:"star_ratings,omitempty"`
+	// VersionDiff shows what changed from the user's previous version of a
+	// text they check again.
+	VersionDiff bool `json:"version_diff,omitempty"`
+	// Report makes /check answer with a formal writing report.
+	Report bool `json:"report,omitempty"`
+	// ReactWhenClean answers messages without mistakes with a reaction
+	// instead of a reply.
+	ReactWhenClean bool `json:"react_when_clean,omitempty"`
+	// StreakDays counts the days in a row, up to StreakDate, the user's
+	// local date of their last check, with at least one check.
+	StreakDays int    `json:"streak_days,omitempty"`
+	StreakDate string `json:"streak_date,omitempty"`
+	// StreakNotesOff hides the streak note on the day's first correction.
+	StreakNotesOff bool `json:"streak_notes_off,omitempty"`
+	// Digest collects corrections into a periodic digest instead of
+	// replying to each message.
+	Digest bool `json:"digest,omitempty"`
+	// Tips adds a learning tip about common mistakes the user keeps making.
+	Tips bool `json:"tips,omitempty"`
+	// TipsShown is when each tip, by key, was last shown to the user.
+	TipsShown map[string]time.Time `json:"tips_shown,omitempty"`
+	// DailyPractice is the "HH:MM" local time a daily exercise is sent to
+	// the user's private chat. Empty means off.
+	DailyPractice string `json:"daily_practice,omitempty"`
+	// DailyPracticeNext is when the next daily exercise is due.
+	DailyPracticeNext time.Time `json:"daily_practice_next,omitzero"`
+	// DailyPracticeSent and DailyPracticeDone count the daily exercises
+	// sent and answered.
+	DailyPracticeSent int `json:"daily_practice_sent,omitempty"`
+	DailyPracticeDone int `json:"daily_practice_done,omitempty"`
+	// NotifyUpdates opts into a changelog notice after new versions.
+	NotifyUpdates bool `json:"notify_updates,omitempty"`
+	// LastSeenVersion is the newest changelog version shown to the user.
+	LastSeenVersion string `json:"last_seen_version,omitempty"`
+}
+
+// ChatSettings holds a chat's preferences, shared by all its members.
+type ChatSettings struct {
+	// AutoDeleteSeconds deletes corrections this long after sending them.
+	// Zero keeps them.
+	AutoDeleteSeconds int `json:"auto_delete_seconds,omitempty"`
+	// TypingOff hides the typing indicator while checks run.
+	TypingOff bool `json:"typing_off,omitempty"`
+	// CheckPolls checks the question and options of polls.
+	CheckPolls bool `json:"check_polls,omitempty"`
+	// Greet introduces the bot to new members.
+	Greet bool `json:"greet,omitempty"`
+	// Language, when set, is the language all corrections in the chat are
+	// made in, whatever its members chose with /language.
+	Language string `json:"language,omitempty"`
+	// MentionOnly checks only messages that mention the bot.
+	MentionOnly bool `json:"mention_only,omitempty"`
+	// Preview, when set, shows the corrections of /check to their sender
+	// first: previewAlert or previewDM.
+	Preview string `json:"preview,omitempty"`
+}
+
+// AutoDelete returns the auto-delete delay, or zero when it is off.
+func (c ChatSettings) AutoDelete() time.Duration {
+	return time.Duration(c.AutoDeleteSeconds) * time.Second
+}
+
+// ReplyRecord remembers the latest correction sent to a chat.
+type ReplyRecord struct {
+	Hash string    `json:"hash"`
+	Time time.Time `json:"time"`
+}
+
+// FeedbackTally counts how users rated the corrections of one model and
+// prompt version.
+type FeedbackTally struct {
+	Sent     int `json:"sent"`
+	Accepted int `json:"accepted"`
+	Rejected int `json:"rejected"`
+	// StarRatings counts the 1–5 star ratings, which add up to Stars.
+	StarRatings int       `json:"star_ratings,omitempty"`
+	Stars       int       `json:"stars,omitempty"`
+	Since       time.Time `json:"since"`
+}
+
+// HistoryEntry records a single grammar check.
+type HistoryEntry struct {
+	Time      time.Time `json:"time"`
+	Original  string    `json:"original"`
+	Corrected string    `json:"corrected"`
+}
+
+// storeData is the persisted form of the Store.
+type storeData struct {
+	Users   map[int64]UserSettings   `json:"users"`
+	History map[int64][]HistoryEntry `json:"history,omitempty"`
+	Blocked map[int64]bool           `json:"blocked,omitempty"`
+	Chats   map[int64]ChatSettings   `json:"chats,omitempty"`
+	Replies map[int64]ReplyRecord    `json:"replies,omitempty"`
+	// Pro lists users granted the pro model with /grantpro.
+	Pro map[int64]bool `json:"pro,omitempty"`
+	// Deferred holds checks queued while the backend was rate-limited,
+	// oldest first.
+	Deferred []DeferredCheck `json:"deferred,omitempty"`
+	// Feedback tallies correction ratings by model and prompt version.
+	Feedback map[string]FeedbackTally `json:"feedback,omitempty"`
+	// Maintenance pauses grammar checks for everyone but admins.
+	Maintenance bool `json:"maintenance,omitempty"`
+	// Offset is the ID of the next update to fetch from Telegram.
+	Offset int `json:"offset,omitempty"`
+}
+
+// Store keeps bot state in memory and, when a path is configured, persists it
+// to a JSON file after every change.
+type Store struct {
+	mu   sync.RWMutex
+	path string
+	data storeData
+}
+
+// NewStore loads the store from path. An empty path keeps state in memory only.
+func NewStore(path string) (*Store, error) {
+	s := &Store{
+		path: path,
+		data: storeData{
+			Users:    make(map[int64]UserSettings),
+			History:  make(map[int64][]HistoryEntry),
+			Blocked:  make(map[int64]bool),
+			Chats:    make(map[int64]ChatSettings),
+			Replies:  make(map[int64]ReplyRecord),
+			Pro:      make(map[int64]bool),
+			Feedback: make(map[string]FeedbackTally),
+		},
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store: %w", err)
+	}
+	if err := json.Unmarshal(raw, &s.data); err != nil {
+		return nil, fmt.Errorf("failed to decode store: %w", err)
+	}
+	if s.data.Users == nil {
+		s.data.Users = make(map[int64]UserSettings)
+	}
+	if s.data.History == nil {
+		s.data.History = make(map[int64][]HistoryEntry)
+	}
+	if s.data.Blocked == nil {
+		s.data.Blocked = make(map[int64]bool)
+	}
+	if s.data.Chats == nil {
+		s.data.Chats = make(map[int64]ChatSettings)
+	}
+	if s.data.Replies == nil {
+		s.data.Replies = make(map[int64]ReplyRecord)
+	}
+	if s.data.Pro == nil {
+		s.data.Pro = make(map[int64]bool)
+	}
+	if s.data.Feedback == nil {
+		s.data.Feedback = make(map[string]FeedbackTally)
+	}
+
+	return s, nil
+}
+
+// GetUserSettings returns the settings of userID, or defaults if none are stored.
+func (s *Store) GetUserSettings(userID int64) UserSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.data.Users[userID].clone()
+}
+
+// LookupUserSettings returns the settings of userID and whether any are stored.
+func (s *Store) LookupUserSettings(userID int64) (UserSettings, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	settings, ok := s.data.Users[userID]
+	return settings.clone(), ok
+}
+
+// HasUser reports whether userID has stored settings or history, that is,
+// whether they have used the bot before. A nil store knows no users.
+func (s *Store) HasUser(userID int64) bool {
+	if s == nil {
+		return false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.data.Users[userID]
+	return ok || len(s.data.History[userID]) > 0
+}
+
+// SaveUserSettings stores the settings of userID.
+func (s *Store) SaveUserSettings(userID int64, settings UserSettings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Users[userID] = settings.clone()
+	return s.persistLocked()
+}
+
+// UpdateUserSettings applies update to the settings of userID atomically, so
+// concurrent updates of different fields don't overwrite each other.
+func (s *Store) UpdateUserSettings(userID int64, update func(*UserSettings)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings := s.data.Users[userID].clone()
+	update(&settings)
+	s.data.Users[userID] = settings
+	return s.persistLocked()
+}
+
+// clone returns a copy of settings that shares no memory with it, so callers
+// can't modify stored settings without saving them.
+func (settings UserSettings) clone() UserSettings {
+	settings.RecentLanguages = append([]string(nil), settings.RecentLanguages...)
+	settings.Categories = append([]string(nil), settings.Categories...)
+	settings.TipsShown = maps.Clone(settings.TipsShown)
+	return settings
+}
+
+// GetChatSettings returns the settings of chatID, or defaults if none are stored.
+func (s *Store) GetChatSettings(chatID int64) ChatSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.data.Chats[chatID]
+}
+
+// SaveChatSettings stores the settings of chatID.
+func (s *Store) SaveChatSettings(chatID int64, settings ChatSettings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Chats[chatID] = settings
+	return s.persistLocked()
+}
+
+// UpdateChatSettings applies update to the settings of chatID atomically,
+// so concurrent commands in one chat don't overwrite each other.
+func (s *Store) UpdateChatSettings(chatID int64, update func(*ChatSettings)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings := s.data.Chats[chatID]
+	update(&settings)
+	s.data.Chats[chatID] = settings
+	return s.persistLocked()
+}
+
+// LastReply returns the latest correction recorded for chatID.
+func (s *Store) LastReply(chatID int64) (ReplyRecord, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.data.Replies[chatID]
+	return record, ok
+}
+
+// RecordReply stores the latest correction sent to chatID.
+func (s *Store) RecordReply(chatID int64, record ReplyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Replies[chatID] = record
+	return s.persistLocked()
+}
+
+// AppendHistory records a check for userID, keeping only the most recent
+// maxHistoryEntries entries.
+func (s *Store) AppendHistory(userID int64, entry HistoryEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := append(s.data.History[userID], entry)
+	if len(history) > maxHistoryEntries {
+		history = history[len(history)-maxHistoryEntries:]
+	}
+	s.data.History[userID] = history
+	return s.persistLocked()
+}
+
+// DueDailyPractice returns the users whose daily exercise is due at now,
+// longest due first.
+func (s *Store) DueDailyPractice(now time.Time) []int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var due []int64
+	for userID, settings := range s.data.Users {
+		if settings.DailyPractice != "" && !settings.DailyPracticeNext.IsZero() && !settings.DailyPracticeNext.After(now) {
+			due = append(due, userID)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return s.data.Users[due[i]].DailyPracticeNext.Before(s.data.Users[due[j]].DailyPracticeNext)
+	})
+	return due
+}
+
+// History returns the recorded checks of userID, oldest first.
+func (s *Store) History(userID int64) []HistoryEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]HistoryEntry(nil), s.data.History[userID]...)
+}
+
+// SetBlocked adds userID to or removes it from the runtime blocklist.
+func (s *Store) SetBlocked(userID int64, blocked bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if blocked {
+		s.data.Blocked[userID] = true
+	} else {
+		delete(s.data.Blocked, userID)
+	}
+	return s.persistLocked()
+}
+
+// IsBlocked reports whether userID is on the runtime blocklist.
+func (s *Store) IsBlocked(userID int64) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.data.Blocked[userID]
+}
+
+// SetPro grants or revokes userID's pro model entitlement.
+func (s *Store) SetPro(userID int64, pro bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if pro {
+		s.data.Pro[userID] = true
+	} else {
+		delete(s.data.Pro, userID)
+	}
+	return s.persistLocked()
+}
+
+// IsPro reports whether userID was granted the pro model.
+func (s *Store) IsPro(userID int64) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.data.Pro[userID]
+}
+
+// AddDeferredCheck queues check unless max checks are already queued, and
+// reports whether it was queued.
+func (s *Store) AddDeferredCheck(check DeferredCheck, max int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.data.Deferred) >= max {
+		return false, nil
+	}
+	s.data.Deferred = append(s.data.Deferred, check)
+	return true, s.persistLocked()
+}
+
+// DeferredChecks returns a copy of the queued checks, oldest first.
+func (s *Store) DeferredChecks() []DeferredCheck {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]DeferredCheck(nil), s.data.Deferred...)
+}
+
+// RemoveDeferredCheck drops the queued check of a message.
+func (s *Store) RemoveDeferredCheck(chatID int64, messageID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.data.Deferred[:0]
+	for _, check := range s.data.Deferred {
+		if check.Message.Chat.ID != chatID || check.Message.MessageID != messageID {
+			kept = append(kept, check)
+		}
+	}
+	s.data.Deferred = kept
+	return s.persistLocked()
+}
+
+// RecordSent counts a correction offered for rating under variant.
+func (s *Store) RecordSent(variant string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tally := s.data.Feedback[variant]
+	if tally.Since.IsZero() {
+		tally.Since = time.Now().UTC()
+	}
+	tally.Sent++
+	s.data.Feedback[variant] = tally
+	return s.persistLocked()
+}
+
+// RecordFeedback counts a rating of a correction sent under variant.
+func (s *Store) RecordFeedback(variant string, accepted bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tally := s.data.Feedback[variant]
+	if tally.Since.IsZero() {
+		tally.Since = time.Now().UTC()
+	}
+	if accepted {
+		tally.Accepted++
+	} else {
+		tally.Rejected++
+	}
+	s.data.Feedback[variant] = tally
+	return s.persistLocked()
+}
+
+// RecordStars counts a rating of 1 to 5 stars of a correction sent under
+// variant.
+func (s *Store) RecordStars(variant string, stars int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tally := s.data.Feedback[variant]
+	if tally.Since.IsZero() {
+		tally.Since = time.Now().UTC()
+	}
+	tally.StarRatings++
+	tally.Stars += stars
+	s.data.Feedback[variant] = tally
+	return s.persistLocked()
+}
+
+// Feedback returns a copy of the correction ratings by variant.
+func (s *Store) Feedback() map[string]FeedbackTally {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	feedback := make(map[string]FeedbackTally, len(s.data.Feedback))
+	for variant, tally := range s.data.Feedback {
+		feedback[variant] = tally
+	}
+	return feedback
+}
+
+// SetMaintenance turns maintenance mode on or off.
+func (s *Store) SetMaintenance(on bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Maintenance = on
+	return s.persistLocked()
+}
+
+// Maintenance reports whether maintenance mode is on.
+func (s *Store) Maintenance() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.data.Maintenance
+}
+
+// Offset returns the committed update offset.
+func (s *Store) Offset() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.data.Offset
+}
+
+// CommitOffset records that every update before offset was processed. The
+// committed offset never moves backwards.
+func (s *Store) CommitOffset(offset int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if offset <= s.data.Offset {
+		return nil
+	}
+	s.data.Offset = offset
+	return s.persistLocked()
+}
+
+// storeStats summarizes what the Store holds.
+type storeStats struct {
+	Users          int  `json:"users"`
+	HistoryUsers   int  `json:"history_users"`
+	HistoryEntries int  `json:"history_entries"`
+	Blocked        int  `json:"blocked"`
+	Persistent     bool `json:"persistent"`
+}
+
+// Stats returns counts of the stored data.
+func (s *Store) Stats() storeStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := storeStats{
+		Users:        len(s.data.Users),
+		HistoryUsers: len(s.data.History),
+		Blocked:      len(s.data.Blocked),
+		Persistent:   s.path != "",
+	}
+	for _, entries := range s.data.History {
+		stats.HistoryEntries += len(entries)
+	}
+	return stats
+}
+
+// persistLocked writes the store to disk. The caller must hold s.mu.
+func (s *Store) persistLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	raw, err := json.Marshal(s.data)
+	if err != nil {
+		return fmt.Errorf("failed to encode store: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create store directory: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated store
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return fmt.Errorf("failed to write store: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace store: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TestUpdateUserSettingsConcurrent hammers one user's settings from many
+// goroutines, each updating its own field, while others read and save
+// other users. Run with -race.
+func TestUpdateUserSettingsConcurrent(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const userID, workers, rounds = 1, 8, 25
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				if err := store.UpdateUserSettings(userID, func(settings *UserSettings) { settings.StreakDays++ }); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				if err := store.UpdateUserSettings(userID, func(settings *UserSettings) { settings.DailyPracticeSent++ }); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			other := int64(100 + w)
+			for i := range rounds {
+				_ = store.GetUserSettings(userID)
+				settings := store.GetUserSettings(other)
+				settings.StreakDays = i
+				settings.RecentLanguages = append(settings.RecentLanguages, "German")
+				if err := store.SaveUserSettings(other, settings); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	got := store.GetUserSettings(userID)
+	if want := workers * rounds; got.StreakDays != want || got.DailyPracticeSent != want {
+		t.Errorf("StreakDays = %d, DailyPracticeSent = %d, want %d each", got.StreakDays, got.DailyPracticeSent, want)
+	}
+
+	reloaded, err := NewStore(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved := reloaded.GetUserSettings(userID); saved.StreakDays != got.StreakDays || saved.DailyPracticeSent != got.DailyPracticeSent {
+		t.Errorf("persisted %+v, want %+v", saved, got)
+	}
+}
+
+// TestUpdateUserSettingsKeepsOtherFields checks that a settings command
+// saving one field doesn't undo a concurrent update of another.
+func TestUpdateUserSettingsKeepsOtherFields(t *testing.T) {
+	store, err := NewStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A handler reads the settings, then a check records a language before
+	// the handler saves
+	settings := store.GetUserSettings(1)
+	settings.Style = styleArrows
+	if err := store.UpdateUserSettings(1, func(stored *UserSettings) { stored.RecentLanguages = []string{"German"} }); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateUserSettings(1, func(stored *UserSettings) { stored.Style = settings.Style }); err != nil {
+		t.Fatal(err)
+	}
+
+	got := store.GetUserSettings(1)
+	if got.Style != styleArrows || len(got.RecentLanguages) != 1 {
+		t.Errorf("got %+v, want style %q and the recorded language", got, styleArrows)
+	}
+}
+
+func TestGetUserSettingsReturnsCopy(t *testing.T) {
+	store, err := NewStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveUserSettings(1, UserSettings{RecentLanguages: []string{"German"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	settings := store.GetUserSettings(1)
+	settings.RecentLanguages[0] = "French"
+	if got := store.GetUserSettings(1).RecentLanguages[0]; got != "German" {
+		t.Errorf("stored language changed to %q through a returned copy", got)
+	}
+}
+
+// TestChatCommandsConcurrent runs every chat settings command at once in
+// one group, plus pairs of toggles, and checks that none undid another.
+// Run with -race.
+func TestChatCommandsConcurrent(t *testing.T) {
+	var admins []string
+	for id := 1; id <= 30; id++ {
+		admins = append(admins, strconv.Itoa(id))
+	}
+	gb, _ := newTestBot(t, testConfig(t, map[string]string{"ADMIN_USER_IDS": strings.Join(admins, ",")}), nil)
+	group := &tgbotapi.Chat{ID: -100, Type: "supergroup"}
+
+	commands := []string{
+		"/autodelete 60", "/chatlanguage German", "/preview dm", "/mentiononly on", "/polls on",
+	}
+	// An even number of flips of a setting leaves it as it was. Each admin
+	// sends one command, so none is debounced
+	for range 10 {
+		commands = append(commands, "/greeting", "/typing")
+	}
+
+	var wg sync.WaitGroup
+	for i, text := range commands {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			message := command(int64(i+1), text)
+			message.Chat = group
+			gb.handleCommand(message)
+		}()
+	}
+	wg.Wait()
+
+	want := ChatSettings{AutoDeleteSeconds: 60, Language: "German", Preview: previewDM, MentionOnly: true, CheckPolls: true}
+	if got := gb.store.GetChatSettings(group.ID); got != want {
+		t.Errorf("chat settings = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// streakDateLayout formats the local dates streaks are counted in.
+const streakDateLayout = "2006-01-02"
+
+// recordStreak counts a check made at now towards the user's streak of days
+// in a row with at least one check, in their time zone. It returns the
+// streak and whether this was the day's first check.
+func (gb *GrammarBot) recordStreak(userID int64, now time.Time) (int, bool) {
+	local := now.In(gb.userLocation(userID))
+	today := local.Format(streakDateLayout)
+	yesterday := local.AddDate(0, 0, -1).Format(streakDateLayout)
+
+	var days int
+	var first bool
+	err := gb.store.UpdateUserSettings(userID, func(settings *UserSettings) {
+		switch settings.StreakDate {
+		case today:
+		case yesterday:
+			settings.StreakDays++
+			first = true
+		default:
+			settings.StreakDays = 1
+			first = true
+		}
+		settings.StreakDate = today
+		days = settings.StreakDays
+	})
+	if err != nil {
+		log.Printf("Error saving streak: %v", err)
+	}
+	return days, first
+}
+
+// currentStreak returns the user's streak as of now: zero once a whole day
+// has passed without a check.
+func (gb *GrammarBot) currentStreak(userID int64, now time.Time) int {
+	settings := gb.store.GetUserSettings(userID)
+	local := now.In(gb.userLocation(userID))
+	switch settings.StreakDate {
+	case local.Format(streakDateLayout), local.AddDate(0, 0, -1).Format(streakDateLayout):
+		return settings.StreakDays
+	default:
+		return 0
+	}
+}
+
+// streakNote records a check towards the user's streak and returns the
+// MarkdownV2 note for the day's first check of a streak of two days or
+// more, or "" when there is nothing to say.
+func (gb *GrammarBot) streakNote(userID int64) string {
+	if !gb.features.get().Streaks {
+		return ""
+	}
+	days, first := gb.recordStreak(userID, time.Now())
+	if !first || days < 2 || gb.store.GetUserSettings(userID).StreakNotesOff {
+		return ""
+	}
+	return escapeMarkdownV2(fmt.Sprintf("🔥 %d-day streak!", days))
+}
+
+// handleStreakCommand shows the user's streak, or turns the daily streak
+// note on or off with "/streak on|off".
+func (gb *GrammarBot) handleStreakCommand(message *tgbotapi.Message, args string) {
+	userID := senderID(message)
+	settings := gb.store.GetUserSettings(userID)
+
+	if args == "" {
+		days := gb.currentStreak(userID, time.Now())
+		reply := "You have no streak yet. Check a message every day to build one."
+		switch {
+		case days == 1:
+			reply = "🔥 Your streak is 1 day. Check a message tomorrow to keep it going."
+		case days > 1:
+			reply = fmt.Sprintf("🔥 Your streak is %d days in a row.", days)
+		}
+		if settings.StreakNotesOff {
+			reply += " Streak notes are off; use /streak on to see them with your first check of the day."
+		}
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, reply))
+		return
+	}
+
+	if _, ok := parseToggle(args, false); !ok {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /streak [on|off]"))
+		return
+	}
+
+	// No argument flips the stored value
+	if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) {
+		enabled, _ := parseToggle(args, !stored.StreakNotesOff)
+		stored.StreakNotesOff = !enabled
+		settings = stored.clone()
+	}); err != nil {
+		log.Printf("Error saving settings: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	reply := "Streak notes are on. Your first correction of the day will show your streak."
+	if settings.StreakNotesOff {
+		reply = "Streak notes are off. Your streak is still counted; use /streak to see it."
+	}
+	gb.replyWithSettings(message, reply)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Strictness levels, chosen with /strictness.
+const (
+	// strictnessHigh fixes outright errors only, leaving informal or
+	// awkward but correct wording alone.
+	strictnessHigh = "high"
+	// strictnessMedium, the default, also fixes clearly wrong word choice,
+	// as the correction prompts always have.
+	strictnessMedium = "medium"
+	// strictnessLow also lightly rewrites correct text to improve its
+	// clarity and flow.
+	strictnessLow = "low"
+)
+
+// strictnessPrompts are appended to the correction prompts for each level
+// other than the default.
+var strictnessPrompts = map[string]string{
+	strictnessHigh: `
+
+Be strict about what you change: fix only outright errors of grammar, spelling and punctuation. Leave word choice, informal language and awkward but correct phrasing exactly as written.`,
+	strictnessLow: `
+
+Beyond fixing mistakes, you may lightly rewrite correct parts to improve clarity and flow, such as wordy or clumsy phrasing, while keeping the author's meaning and voice. Mark these rewrites like corrections.`,
+}
+
+// effectiveStrictness returns the strictness level of settings.
+func effectiveStrictness(settings UserSettings) string {
+	if settings.Strictness == "" {
+		return strictnessMedium
+	}
+	return settings.Strictness
+}
+
+// handleStrictnessCommand shows or sets how much of the user's text
+// corrections may change.
+func (gb *GrammarBot) handleStrictnessCommand(message *tgbotapi.Message, args string) {
+	userID := senderID(message)
+	settings := gb.store.GetUserSettings(userID)
+	level := strings.ToLower(args)
+
+	switch level {
+	case "":
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf(`Your strictness is %s. Use /strictness <level> to change it:
+
+high: fix outright errors only
+medium: also fix wrong word choice
+low: also improve clarity and flow`, effectiveStrictness(settings))))
+		return
+	case strictnessHigh, strictnessMedium, strictnessLow:
+	default:
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Unknown strictness. Choose low, medium or high."))
+		return
+	}
+
+	// The default level is stored as none
+	storedStrictness := level
+	if level == strictnessMedium {
+		storedStrictness = ""
+	}
+	if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) { stored.Strictness = storedStrictness }); err != nil {
+		log.Printf("Error saving settings: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	reply := "Strictness set to medium. I'll fix mistakes, including wrong word choice, and leave the rest of your text alone."
+	switch level {
+	case strictnessHigh:
+		reply = "Strictness set to high. I'll fix only outright grammar, spelling and punctuation errors."
+	case strictnessLow:
+		reply = "Strictness set to low. Besides fixing mistakes, I'll suggest clearer and smoother wording."
+	}
+	gb.replyWithSettings(message, reply)
+}
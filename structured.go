@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// Correction formats, selected with CORRECTION_FORMAT.
+const (
+	// formatText has the model write the MarkdownV2 correction itself.
+	formatText = "text"
+	// formatJSON has the model list its edits as JSON, which the bot turns
+	// into MarkdownV2, so escaping and markup are never left to the model.
+	formatJSON = "json"
+)
+
+const structuredPrompt = `You are a world-class %s language assistant specializing in grammar and vocabulary correction. When given a user's text, you must:
+
+1. Identify all grammar, spelling, punctuation or word-choice mistakes.
+2. Preserve the original meaning, tone and style, and leave everything that is correct as it is.
+3. Answer with JSON only, in this form: {"edits": [{"original": "the words as written", "corrected": "the corrected words", "type": "a short name of the issue, such as verb tense or spelling"}]}
+4. List the edits in the order they appear in the text. Copy each original exactly as written, never leave it empty, and for a missing word include the word next to it, for example {"original": "to store", "corrected": "to the store"}.
+5. Answer with {"edits": []} when there are no mistakes.`
+
+// structuredExplainPrompt adds explanations to structuredPrompt.
+const structuredExplainPrompt = `
+
+Also give each edit an "explanation" field: one short sentence explaining the mistake in %s.`
+
+// structuredReminderPrompt is added when a structured correction is asked for
+// again because the first answer couldn't be used.
+const structuredReminderPrompt = `
+
+Your previous answer could not be used (%s). Answer with a single JSON object exactly in the form given above and nothing else: no code fences, comments or extra fields. Every "original" must be copied character for character from the text, in the order the edits appear.`
+
+// structuredSchema is the shape of a structured correction, for backends
+// that can enforce it.
+var structuredSchema = &genai.Schema{
+	Type: genai.TypeObject,
+	Properties: map[string]*genai.Schema{
+		"edits": {
+			Type: genai.TypeArray,
+			Items: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"original":    {Type: genai.TypeString},
+					"corrected":   {Type: genai.TypeString},
+					"type":        {Type: genai.TypeString},
+					"category":    {Type: genai.TypeString},
+					"explanation": {Type: genai.TypeString},
+				},
+				Required: []string{"original", "corrected", "type"},
+			},
+		},
+	},
+	Required: []string{"edits"},
+}
+
+// structuredEdit is one edit of a structured correction.
+type structuredEdit struct {
+	Original    string `json:"original"`
+	Corrected   string `json:"corrected"`
+	Type        string `json:"type"`
+	Category    string `json:"category"`
+	Explanation string `json:"explanation"`
+}
+
+var errEditNotFound = errors.New("edit not found in the text")
+
+// correctStructured corrects text in the JSON format. An answer that can't
+// be parsed or doesn't match text is asked for once more with a reminder of
+// the format, and if that fails too, text is corrected in the text format.
+// Both are counted in the metrics ctx carries.
+func correctStructured(ctx context.Context, e GrammarEngine, text string, opts CorrectOptions) (string, error) {
+	metrics := metricsFromContext(ctx)
+	attempts := metrics.structuredAttempts.Add(1)
+	instructions := structuredInstructions(opts)
+	raw, err := e.Complete(ctx, Prompt{Instructions: instructions + inputGuardPrompt, Model: opts.Model, Schema: structuredSchema}, guardInput(text))
+	if err != nil {
+		return "", err
+	}
+	markup, err := parseStructured(text, raw, opts)
+	if err == nil {
+		return markup, nil
+	}
+
+	reprompts := metrics.structuredReprompts.Add(1)
+	log.Printf("Structured correction malformed, asking again (%d of %d structured corrections so far): %v", reprompts, attempts, err)
+	reminder := fmt.Sprintf(structuredReminderPrompt, err)
+	raw, err = e.Complete(ctx, Prompt{Instructions: instructions + reminder + inputGuardPrompt, Model: opts.Model, Schema: structuredSchema}, guardInput(text))
+	if err != nil {
+		return "", err
+	}
+	markup, err = parseStructured(text, raw, opts)
+	if err != nil {
+		log.Printf("Error parsing structured correction, falling back to text: %v", err)
+		opts.Format = formatText
+		return correctWith(ctx, e, text, opts)
+	}
+	return markup, nil
+}
+
+func structuredInstructions(opts CorrectOptions) string {
+	language := opts.Language
+	if language == "" {
+		language = defaultLanguage
+	}
+
+	prompt := fmt.Sprintf(structuredPrompt, language) + strictnessPrompts[opts.Strictness]
+	if opts.Categories != "" {
+		prompt += categoriesInstructions(opts) + structuredCategoryPrompt
+	}
+	if opts.Mixed {
+		prompt += mixedPrompt
+	}
+	if opts.Explain {
+		explainIn := opts.ExplainLanguage
+		if explainIn == "" {
+			explainIn = language
+		}
+		prompt += fmt.Sprintf(structuredExplainPrompt, explainIn)
+	}
+	return prompt
+}
+
+// parseStructured decodes a structured correction of text and renders it as
+// the MarkdownV2 markup the text format produces: mistakes in
+// ~strikethrough~ followed by their correction in bold, or by the issue's
+// name in flag mode, then one line per explanation in explain mode. Edits
+// outside the categories corrections are limited to are left out.
+func parseStructured(text, raw string, opts CorrectOptions) (string, error) {
+	var answer struct {
+		Edits []structuredEdit `json:"edits"`
+	}
+	if err := json.Unmarshal([]byte(trimCodeFence(raw)), &answer); err != nil {
+		return "", fmt.Errorf("failed to decode structured correction: %w", err)
+	}
+
+	var b strings.Builder
+	var explanations []string
+	pos := 0
+	for _, edit := range answer.Edits {
+		if edit.Original == "" {
+			return "", fmt.Errorf("edit with an empty original: %+v", edit)
+		}
+		if edit.Original == edit.Corrected || !allowsCategory(opts, edit.Category) {
+			continue
+		}
+		i := strings.Index(text[pos:], edit.Original)
+		if i < 0 {
+			return "", fmt.Errorf("%w: %q", errEditNotFound, edit.Original)
+		}
+
+		b.WriteString(escapeMarkdownV2(text[pos : pos+i]))
+		b.WriteString("~" + escapeMarkdownV2(edit.Original) + "~")
+		switch {
+		case opts.FlagOnly:
+			issue := strings.TrimSpace(edit.Type)
+			if issue == "" {
+				issue = "mistake"
+			}
+			b.WriteString(" _\\(" + escapeMarkdownV2(issue) + "\\)_")
+		case edit.Corrected != "":
+			b.WriteString(" **" + escapeMarkdownV2(edit.Corrected) + "**")
+		}
+		pos += i + len(edit.Original)
+
+		if explanation := strings.TrimSpace(edit.Explanation); opts.Explain && !opts.FlagOnly && explanation != "" {
+			explanations = append(explanations, "• "+escapeMarkdownV2(explanation))
+		}
+	}
+	b.WriteString(escapeMarkdownV2(text[pos:]))
+
+	if len(explanations) > 0 {
+		b.WriteString("\n\n" + strings.Join(explanations, "\n"))
+	}
+	return b.String(), nil
+}
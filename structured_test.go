@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// sequenceEngine answers Complete calls with answers in turn, recording the
+// prompts it was given.
+func sequenceEngine(answers ...string) (*fakeEngine, *[]Prompt) {
+	var prompts []Prompt
+	return &fakeEngine{complete: func(ctx context.Context, prompt Prompt, text string) (string, error) {
+		prompts = append(prompts, prompt)
+		answer := answers[0]
+		answers = answers[1:]
+		return answer, nil
+	}}, &prompts
+}
+
+const validStructured = `{"edits": [{"original": "go", "corrected": "goes", "type": "verb tense"}]}`
+
+func TestCorrectStructuredReprompts(t *testing.T) {
+	opts := CorrectOptions{Language: defaultLanguage, Format: formatJSON}
+	engine, prompts := sequenceEngine(`{"edits": [{"original": "went"`, validStructured)
+	engine.correct = func(ctx context.Context, text string, opts CorrectOptions) (string, error) {
+		return correctWith(ctx, engine, text, opts)
+	}
+	gb, _ := newTestBot(t, testConfig(t, nil), engine)
+
+	markup, err := gb.checkGrammar("She go home.", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if markup != "She ~go~ **goes** home\\." {
+		t.Errorf("markup = %q, want the second answer rendered", markup)
+	}
+	if len(*prompts) != 2 {
+		t.Fatalf("asked %d times, want 2", len(*prompts))
+	}
+	if second := (*prompts)[1]; second.Schema != structuredSchema || !strings.Contains(second.Instructions, "Your previous answer could not be used") {
+		t.Error("the second request wasn't a structured one with the format reminder")
+	}
+	if s := gb.Snapshot(); s.StructuredCorrections != 1 || s.StructuredReprompts != 1 || s.StructuredRepromptRate != 1 {
+		t.Errorf("snapshot counted %d reprompts of %d structured corrections, rate %g, want 1 of 1", s.StructuredReprompts, s.StructuredCorrections, s.StructuredRepromptRate)
+	}
+}
+
+// TestCorrectStructuredFallsBackToText checks that two malformed answers
+// fall back to a correction in the text format.
+func TestCorrectStructuredFallsBackToText(t *testing.T) {
+	opts := CorrectOptions{Language: defaultLanguage, Format: formatJSON}
+	engine, prompts := sequenceEngine("not JSON", `{"edits": [{"original": "stay", "corrected": "stays"}]}`, "She ~go~ **goes** home\\.")
+
+	markup, err := correctStructured(context.Background(), engine, "She go home.", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if markup != "She ~go~ **goes** home\\." {
+		t.Errorf("markup = %q, want the text format answer", markup)
+	}
+	if len(*prompts) != 3 || (*prompts)[2].Schema != nil {
+		t.Errorf("asked %d times, want two structured requests and one in the text format", len(*prompts))
+	}
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// telemetryInterval is how often finished spans and the metrics are
+// exported.
+const telemetryInterval = 10 * time.Second
+
+// maxPendingSpans bounds the spans held for the next export; while the
+// collector is unreachable, newer spans are dropped.
+const maxPendingSpans = 2048
+
+// Span kinds of the OTLP trace format.
+const (
+	spanKindInternal = 1
+	spanKindServer   = 2
+	spanKindClient   = 3
+)
+
+// telemetry exports traces of each update's handling and the bot's metrics
+// to an OpenTelemetry collector, as OTLP over HTTP with JSON encoding. A nil
+// *telemetry, used when OTEL_EXPORTER_OTLP_ENDPOINT is unset, records
+// nothing.
+type telemetry struct {
+	endpoint string
+	service  string
+	started  time.Time
+	client   *http.Client
+
+	mu       sync.Mutex
+	pending  []otlpSpan
+	received map[int]time.Time
+	active   map[messageKey]*span
+}
+
+func newTelemetry(endpoint, service string) *telemetry {
+	return &telemetry{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		service:  service,
+		started:  time.Now(),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		received: make(map[int]time.Time),
+		active:   make(map[messageKey]*span),
+	}
+}
+
+// span is an operation being traced. A nil *span records nothing.
+type span struct {
+	t        *telemetry
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     int
+	start    time.Time
+	attrs    []otlpAttribute
+}
+
+// startSpan starts a span at start, as a child of parent or, without one, as
+// the root of a new trace.
+func (t *telemetry) startSpan(parent *span, name string, kind int, start time.Time) *span {
+	if t == nil {
+		return nil
+	}
+	s := &span{t: t, name: name, kind: kind, start: start}
+	rand.Read(s.spanID[:])
+	if parent != nil {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	} else {
+		rand.Read(s.traceID[:])
+	}
+	return s
+}
+
+// child starts a span within s, now.
+func (s *span) child(name string, kind int) *span {
+	if s == nil {
+		return nil
+	}
+	return s.t.startSpan(s, name, kind, time.Now())
+}
+
+// set adds an attribute to s. Values are strings, bools or integers.
+func (s *span) set(key string, value any) {
+	if s == nil {
+		return
+	}
+	attr := otlpAttribute{Key: key}
+	switch v := value.(type) {
+	case string:
+		attr.Value.StringValue = &v
+	case bool:
+		attr.Value.BoolValue = &v
+	case int:
+		n := strconv.Itoa(v)
+		attr.Value.IntValue = &n
+	case int64:
+		n := strconv.FormatInt(v, 10)
+		attr.Value.IntValue = &n
+	default:
+		text := fmt.Sprint(v)
+		attr.Value.StringValue = &text
+	}
+	s.attrs = append(s.attrs, attr)
+}
+
+// end finishes s now, marking it failed with err.
+func (s *span) end(err error) {
+	s.endAt(time.Now(), err)
+}
+
+// endAt finishes s at end, marking it failed with err, and holds it for the
+// next export.
+func (s *span) endAt(end time.Time, err error) {
+	if s == nil {
+		return
+	}
+	out := otlpSpan{
+		TraceID:    hex.EncodeToString(s.traceID[:]),
+		SpanID:     hex.EncodeToString(s.spanID[:]),
+		Name:       s.name,
+		Kind:       s.kind,
+		Start:      strconv.FormatInt(s.start.UnixNano(), 10),
+		End:        strconv.FormatInt(end.UnixNano(), 10),
+		Attributes: s.attrs,
+		Status:     otlpStatus{Code: 1},
+	}
+	if s.parentID != [8]byte{} {
+		out.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+	if err != nil {
+		out.Status = otlpStatus{Code: 2, Message: err.Error()}
+	}
+
+	s.t.mu.Lock()
+	defer s.t.mu.Unlock()
+	if len(s.t.pending) < maxPendingSpans {
+		s.t.pending = append(s.t.pending, out)
+	}
+}
+
+type spanContextKey struct{}
+
+// contextWithSpan returns ctx carrying s, so the AI calls made under ctx
+// are traced within it.
+func contextWithSpan(ctx context.Context, s *span) context.Context {
+	if s == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanContextKey{}, s)
+}
+
+// spanFromContext returns the span ctx carries, if any.
+func spanFromContext(ctx context.Context) *span {
+	s, _ := ctx.Value(spanContextKey{}).(*span)
+	return s
+}
+
+// receive records when the update with ID updateID was fetched, the start
+// of its trace.
+func (t *telemetry) receive(updateID int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.received[updateID] = time.Now()
+}
+
+// beginUpdate starts the trace of update as a worker picks it up: its root
+// span starts when the update was fetched, with the wait in the queue as
+// its first child. Replies to the update's message are traced within it
+// until endUpdate.
+func (t *telemetry) beginUpdate(update tgbotapi.Update) *span {
+	if t == nil {
+		return nil
+	}
+	now := time.Now()
+	t.mu.Lock()
+	start, ok := t.received[update.UpdateID]
+	delete(t.received, update.UpdateID)
+	t.mu.Unlock()
+	if !ok {
+		start = now
+	}
+
+	root := t.startSpan(nil, "telegram.update", spanKindServer, start)
+	root.set("telegram.update_id", update.UpdateID)
+	if user, chatID, ok := updateOrigin(update); ok {
+		root.set("telegram.chat_id", chatID)
+		root.set("telegram.user_id", user)
+	}
+	if update.Message != nil {
+		root.set("telegram.chat_type", update.Message.Chat.Type)
+		t.mu.Lock()
+		t.active[messageKey{update.Message.Chat.ID, update.Message.MessageID}] = root
+		t.mu.Unlock()
+	}
+	t.startSpan(root, "queue.wait", spanKindInternal, start).endAt(now, nil)
+	return root
+}
+
+// endUpdate finishes the trace of update begun with root.
+func (t *telemetry) endUpdate(update tgbotapi.Update, root *span) {
+	if t == nil {
+		return
+	}
+	if update.Message != nil {
+		t.mu.Lock()
+		delete(t.active, messageKey{update.Message.Chat.ID, update.Message.MessageID})
+		t.mu.Unlock()
+	}
+	root.end(nil)
+}
+
+// messageSpan returns the root span of the update of a message still being
+// handled.
+func (t *telemetry) messageSpan(chatID int64, messageID int) *span {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.active[messageKey{chatID, messageID}]
+}
+
+// messageContext returns gb.ctx carrying the trace of message, for checking
+// it.
+func (gb *GrammarBot) messageContext(message *tgbotapi.Message) context.Context {
+	return contextWithSpan(gb.ctx, gb.telemetry.messageSpan(message.Chat.ID, message.MessageID))
+}
+
+// sendSpan starts the span of a send, within the trace of the message c
+// replies to.
+func (gb *GrammarBot) sendSpan(c tgbotapi.Chattable) *span {
+	msg, ok := c.(tgbotapi.MessageConfig)
+	if !ok || msg.ReplyToMessageID == 0 {
+		return nil
+	}
+	s := gb.telemetry.messageSpan(msg.ChatID, msg.ReplyToMessageID).child("telegram.send", spanKindClient)
+	s.set("telegram.chat_id", msg.ChatID)
+	return s
+}
+
+// runTelemetry exports spans and metrics every telemetryInterval until ctx
+// is cancelled.
+func (gb *GrammarBot) runTelemetry(ctx context.Context) {
+	ticker := time.NewTicker(telemetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			gb.exportTelemetry()
+		}
+	}
+}
+
+// exportTelemetry sends the finished spans and the current metrics to the
+// collector.
+func (gb *GrammarBot) exportTelemetry() {
+	t := gb.telemetry
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	spans := t.pending
+	t.pending = nil
+	t.mu.Unlock()
+
+	resource := otlpResource{Attributes: []otlpAttribute{stringAttribute("service.name", t.service)}}
+	scope := otlpScope{Name: "smart-grammar-bot"}
+	if len(spans) > 0 {
+		err := t.post("/v1/traces", map[string]any{
+			"resourceSpans": []any{map[string]any{
+				"resource":   resource,
+				"scopeSpans": []any{map[string]any{"scope": scope, "spans": spans}},
+			}},
+		})
+		if err != nil {
+			log.Printf("Error exporting %d spans: %v", len(spans), err)
+		}
+	}
+
+	err := t.post("/v1/metrics", map[string]any{
+		"resourceMetrics": []any{map[string]any{
+			"resource":     resource,
+			"scopeMetrics": []any{map[string]any{"scope": scope, "metrics": gb.otlpMetrics()}},
+		}},
+	})
+	if err != nil {
+		log.Printf("Error exporting metrics: %v", err)
+	}
+}
+
+// otlpMetrics returns the bot's counters as OTLP metrics: cumulative sums
+// for counts since the start and gauges for current levels.
+func (gb *GrammarBot) otlpMetrics() []otlpMetric {
+	stats := gb.Snapshot()
+	now := strconv.FormatInt(time.Now().UnixNano(), 10)
+	start := strconv.FormatInt(gb.telemetry.started.UnixNano(), 10)
+
+	sum := func(name, description string, value int64) otlpMetric {
+		return otlpMetric{Name: name, Description: description, Unit: "1", Sum: &otlpSum{
+			AggregationTemporality: 2,
+			IsMonotonic:            true,
+			DataPoints:             []otlpDataPoint{{AsInt: strconv.FormatInt(value, 10), StartTime: start, Time: now}},
+		}}
+	}
+	gauge := func(name, description string, value int64) otlpMetric {
+		return otlpMetric{Name: name, Description: description, Unit: "1", Gauge: &otlpGauge{
+			DataPoints: []otlpDataPoint{{AsInt: strconv.FormatInt(value, 10), Time: now}},
+		}}
+	}
+	return []otlpMetric{
+		sum("grammarbot.checks", "Grammar checks made", stats.Checks),
+		sum("grammarbot.check_errors", "Grammar checks that failed", stats.CheckErrors),
+		sum("grammarbot.panics", "Panics recovered while handling updates", stats.Panics),
+		sum("grammarbot.reconnects", "Reconnects of update polling", stats.Reconnects),
+		sum("grammarbot.truncations", "Replies truncated to fit Telegram's limit", stats.Truncations),
+		sum("grammarbot.flood_waits", "Telegram flood waits", stats.FloodWaits),
+		sum("grammarbot.structured.corrections", "Corrections asked for in the JSON format", stats.StructuredCorrections),
+		sum("grammarbot.structured.reprompts", "Structured corrections asked for again", stats.StructuredReprompts),
+		sum("grammarbot.cache.hits", "Correction cache hits", stats.Cache.Hits),
+		sum("grammarbot.cache.misses", "Correction cache misses", stats.Cache.Misses),
+		gauge("grammarbot.in_flight", "AI calls in flight", stats.InFlight),
+		gauge("grammarbot.active_workers", "Workers handling an update", stats.ActiveWorkers),
+		gauge("grammarbot.queue.depth", "Updates waiting for a worker", int64(len(gb.queue))),
+	}
+}
+
+// post sends body as JSON to path under the collector's endpoint.
+func (t *telemetry) post(path string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode telemetry: %w", err)
+	}
+	resp, err := t.client.Post(t.endpoint+path, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to send telemetry: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &httpStatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return nil
+}
+
+// The OTLP JSON encoding of the parts of traces and metrics exported.
+type (
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpAttribute struct {
+		Key   string `json:"key"`
+		Value struct {
+			StringValue *string `json:"stringValue,omitempty"`
+			BoolValue   *bool   `json:"boolValue,omitempty"`
+			IntValue    *string `json:"intValue,omitempty"`
+		} `json:"value"`
+	}
+	otlpSpan struct {
+		TraceID      string          `json:"traceId"`
+		SpanID       string          `json:"spanId"`
+		ParentSpanID string          `json:"parentSpanId,omitempty"`
+		Name         string          `json:"name"`
+		Kind         int             `json:"kind"`
+		Start        string          `json:"startTimeUnixNano"`
+		End          string          `json:"endTimeUnixNano"`
+		Attributes   []otlpAttribute `json:"attributes,omitempty"`
+		Status       otlpStatus      `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpMetric struct {
+		Name        string     `json:"name"`
+		Description string     `json:"description"`
+		Unit        string     `json:"unit"`
+		Sum         *otlpSum   `json:"sum,omitempty"`
+		Gauge       *otlpGauge `json:"gauge,omitempty"`
+	}
+	otlpSum struct {
+		AggregationTemporality int             `json:"aggregationTemporality"`
+		IsMonotonic            bool            `json:"isMonotonic"`
+		DataPoints             []otlpDataPoint `json:"dataPoints"`
+	}
+	otlpGauge struct {
+		DataPoints []otlpDataPoint `json:"dataPoints"`
+	}
+	otlpDataPoint struct {
+		AsInt     string `json:"asInt"`
+		StartTime string `json:"startTimeUnixNano,omitempty"`
+		Time      string `json:"timeUnixNano"`
+	}
+)
+
+func stringAttribute(key, value string) otlpAttribute {
+	var attr otlpAttribute
+	attr.Key = key
+	attr.Value.StringValue = &value
+	return attr
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// usageTracker counts each user's checks over a sliding window.
+type usageTracker struct {
+	mu     sync.Mutex
+	checks map[int64][]time.Time
+}
+
+// record adds a check for userID and returns how many checks the user made
+// within window, including this one.
+func (u *usageTracker) record(userID int64, now time.Time, window time.Duration) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.checks == nil {
+		u.checks = make(map[int64][]time.Time)
+	}
+
+	recent := u.checks[userID][:0]
+	for _, t := range u.checks[userID] {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, now)
+	u.checks[userID] = recent
+
+	return len(recent)
+}
+
+// size returns how many users have recorded checks.
+func (u *usageTracker) size() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return len(u.checks)
+}
+
+// throttleDelay returns how long to hold back the reply to userID. Users
+// over the soft usage threshold get a small delay instead of a hard limit, so
+// a single power user can't monopolize a free-tier quota.
+func (gb *GrammarBot) throttleDelay(userID int64) time.Duration {
+	if gb.cfg.SoftLimitChecks == 0 {
+		return 0
+	}
+
+	count := gb.usage.record(userID, time.Now(), gb.cfg.SoftLimitWindow)
+	if count <= gb.cfg.SoftLimitChecks {
+		return 0
+	}
+
+	log.Printf("User %d made %d checks in %s, delaying reply by %s", userID, count, gb.cfg.SoftLimitWindow, gb.cfg.SoftLimitDelay)
+	return gb.cfg.SoftLimitDelay
+}
+
+// deliverAfter runs deliver once delay has passed, keeping the typing
+// indicator visible meanwhile. It returns immediately so the worker can serve
+// other users.
+func (gb *GrammarBot) deliverAfter(chatID int64, delay time.Duration, deliver func()) {
+	if delay <= 0 {
+		deliver()
+		return
+	}
+
+	stopTyping := gb.startTyping(chatID)
+	time.AfterFunc(delay, func() {
+		stopTyping()
+		deliver()
+	})
+}
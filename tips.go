@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// tipRepeats is how many checks, the current one included, must have
+	// corrected a mistake before its tip is shown.
+	tipRepeats = 3
+	// tipInterval is the least time between two tips for a user.
+	tipInterval = 24 * time.Hour
+	// tipRepeatInterval is the least time before the same tip is shown
+	// again.
+	tipRepeatInterval = 7 * 24 * time.Hour
+)
+
+// mistakeTip explains a common English mistake: writing one of Mistakes
+// where one of Fixes belongs. Key identifies the tip in
+// UserSettings.TipsShown.
+type mistakeTip struct {
+	Key      string
+	Mistakes []string
+	Fixes    []string
+	Tip      string
+}
+
+var mistakeTips = []mistakeTip{
+	{Key: "alot", Mistakes: []string{"alot"}, Fixes: []string{"a lot"}, Tip: "“a lot” is always two words."},
+	{Key: "of-have", Mistakes: []string{"could of", "should of", "would of", "must of"}, Fixes: []string{"could have", "should have", "would have", "must have", "could've", "should've", "would've", "must've"}, Tip: "after could, should, would and must, write “have” (or “'ve”), not “of”: “could have”, “should've”."},
+	{Key: "definitely", Mistakes: []string{"definately", "definatly", "defiantly"}, Fixes: []string{"definitely"}, Tip: "“definitely” has “finite” in it: de-finite-ly."},
+	{Key: "separate", Mistakes: []string{"seperate", "seperately"}, Fixes: []string{"separate", "separately"}, Tip: "there's “a rat” in “separate”."},
+	{Key: "receive", Mistakes: []string{"recieve", "recieved", "reciept"}, Fixes: []string{"receive", "received", "receipt"}, Tip: "“i” before “e” except after “c”: receive, receipt."},
+	{Key: "until", Mistakes: []string{"untill"}, Fixes: []string{"until"}, Tip: "“until” ends in a single “l”, unlike “till”."},
+	{Key: "its", Mistakes: []string{"its", "it's"}, Fixes: []string{"its", "it's"}, Tip: "“it's” means “it is” or “it has”, while “its” is possessive, like “his”."},
+	{Key: "your", Mistakes: []string{"your", "you're"}, Fixes: []string{"your", "you're"}, Tip: "“you're” means “you are”, while “your” is possessive: “you're right about your plan”."},
+	{Key: "than", Mistakes: []string{"then"}, Fixes: []string{"than"}, Tip: "use “than” to compare and “then” for time: “better than”, “and then”."},
+	{Key: "lose", Mistakes: []string{"loose"}, Fixes: []string{"lose"}, Tip: "“lose” is the opposite of win or find; “loose” is the opposite of tight."},
+	{Key: "every-day", Mistakes: []string{"everyday"}, Fixes: []string{"every day"}, Tip: "“every day” means each day, while “everyday” is an adjective meaning ordinary: “everyday clothes”."},
+	{Key: "regardless", Mistakes: []string{"irregardless"}, Fixes: []string{"regardless"}, Tip: "the word is “regardless”; “irregardless” is widely seen as a mistake."},
+}
+
+// normalizeWords lowercases text and reduces it to its words separated by
+// single spaces, with a space at each end so whole words can be matched.
+func normalizeWords(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\'' && r != '’'
+	})
+	return " " + strings.ReplaceAll(strings.Join(words, " "), "’", "'") + " "
+}
+
+// matches reports whether edit corrects the mistake the tip is about.
+func (t mistakeTip) matches(edit Edit) bool {
+	if !edit.Changed() {
+		return false
+	}
+	original, corrected := normalizeWords(edit.Original), normalizeWords(edit.Corrected)
+	return containsPhrase(corrected, t.Fixes) && slices.ContainsFunc(t.Mistakes, func(mistake string) bool {
+		return containsPhrase(original, []string{mistake}) && !containsPhrase(corrected, []string{mistake})
+	})
+}
+
+// containsPhrase reports whether normalized text, as normalizeWords returns
+// it, contains any of phrases as whole words.
+func containsPhrase(text string, phrases []string) bool {
+	for _, phrase := range phrases {
+		if strings.Contains(text, " "+phrase+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// correctedTips returns the tips for the mistakes a correction fixed.
+func correctedTips(correctedText string) []mistakeTip {
+	edits, err := parseInlineEdits(correctedText)
+	if err != nil {
+		return nil
+	}
+	var tips []mistakeTip
+	for _, tip := range mistakeTips {
+		for _, edit := range edits {
+			if tip.matches(edit) {
+				tips = append(tips, tip)
+				break
+			}
+		}
+	}
+	return tips
+}
+
+// mistakeTip returns the tip for a mistake the user keeps making, if the
+// correction fixes one they also made in tipRepeats-1 earlier checks and
+// no tip was shown to them recently. It must be called before the check is
+// added to the history.
+func (gb *GrammarBot) mistakeTip(userID int64, opts CorrectOptions, correctedText string) (mistakeTip, bool) {
+	settings := gb.store.GetUserSettings(userID)
+	if !settings.Tips || opts.FlagOnly || opts.Language != defaultLanguage {
+		return mistakeTip{}, false
+	}
+	now := time.Now()
+	for _, shown := range settings.TipsShown {
+		if now.Sub(shown) < tipInterval {
+			return mistakeTip{}, false
+		}
+	}
+
+	tips := correctedTips(correctedText)
+	if len(tips) == 0 {
+		return mistakeTip{}, false
+	}
+	repeats := make(map[string]int)
+	for _, entry := range gb.store.History(userID) {
+		for _, tip := range correctedTips(entry.Corrected) {
+			repeats[tip.Key]++
+		}
+	}
+	for _, tip := range tips {
+		if repeats[tip.Key]+1 >= tipRepeats && now.Sub(settings.TipsShown[tip.Key]) >= tipRepeatInterval {
+			return tip, true
+		}
+	}
+	return mistakeTip{}, false
+}
+
+// tipNote remembers that tip is shown to the user and returns it as
+// MarkdownV2.
+func (gb *GrammarBot) tipNote(userID int64, tip mistakeTip) string {
+	err := gb.store.UpdateUserSettings(userID, func(settings *UserSettings) {
+		if settings.TipsShown == nil {
+			settings.TipsShown = make(map[string]time.Time)
+		}
+		settings.TipsShown[tip.Key] = time.Now().UTC()
+	})
+	if err != nil {
+		log.Printf("Error saving tip: %v", err)
+	}
+	return escapeMarkdownV2(fmt.Sprintf("💡 Tip: %s", tip.Tip))
+}
+
+// handleTipsCommand toggles learning tips, or sets them with "/tips on|off".
+func (gb *GrammarBot) handleTipsCommand(message *tgbotapi.Message, args string) {
+	userID := senderID(message)
+	if _, ok := parseToggle(args, false); !ok {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /tips [on|off]"))
+		return
+	}
+
+	// No argument flips the stored value
+	var settings UserSettings
+	if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) {
+		stored.Tips, _ = parseToggle(args, stored.Tips)
+		settings = stored.clone()
+	}); err != nil {
+		log.Printf("Error saving settings: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	reply := "Learning tips are off."
+	if settings.Tips {
+		reply = fmt.Sprintf("Learning tips are on. When I've fixed the same common mistake %d times, I'll add a short tip about it, at most once a day.", tipRepeats)
+	}
+	gb.replyWithSettings(message, reply)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// sendTyping shows the typing indicator in chatID, unless the operator or
+// the chat turned it off or a flood wait is in effect.
+func (gb *GrammarBot) sendTyping(chatID int64) {
+	if !gb.cfg.TypingAction || gb.store.GetChatSettings(chatID).TypingOff || gb.flood.remaining() > 0 {
+		return
+	}
+	gb.request(tgbotapi.NewChatAction(chatID, tgbotapi.ChatTyping))
+}
+
+// typingRefreshInterval keeps the typing indicator visible; Telegram clears
+// it after about five seconds.
+const typingRefreshInterval = 4 * time.Second
+
+// typingCoalescer keeps at most one typing indicator loop per chat, shared
+// by every request in flight for that chat.
+type typingCoalescer struct {
+	mu    sync.Mutex
+	chats map[int64]*typingLoop
+}
+
+type typingLoop struct {
+	refs int
+	stop chan struct{}
+}
+
+// startTyping shows the typing indicator in chatID until the returned func is
+// called, refreshing it while the work lasts. Concurrent requests for the
+// same chat share one loop, which stops when the last of them finishes.
+func (gb *GrammarBot) startTyping(chatID int64) (stop func()) {
+	t := &gb.typing
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.chats == nil {
+		t.chats = make(map[int64]*typingLoop)
+	}
+	loop, running := t.chats[chatID]
+	if !running {
+		loop = &typingLoop{stop: make(chan struct{})}
+		t.chats[chatID] = loop
+		go gb.refreshTyping(chatID, loop.stop)
+	}
+	loop.refs++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+
+			loop.refs--
+			if loop.refs == 0 {
+				close(loop.stop)
+				delete(t.chats, chatID)
+			}
+		})
+	}
+}
+
+// refreshTyping sends the typing indicator now and every
+// typingRefreshInterval until stop is closed.
+func (gb *GrammarBot) refreshTyping(chatID int64, stop <-chan struct{}) {
+	gb.sendTyping(chatID)
+
+	ticker := time.NewTicker(typingRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			gb.sendTyping(chatID)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// handleTypingCommand shows or sets whether the chat sees the typing
+// indicator while a check runs.
+func (gb *GrammarBot) handleTypingCommand(message *tgbotapi.Message, args string) {
+	chatID := message.Chat.ID
+	if !gb.canManageChat(message) {
+		gb.send(tgbotapi.NewMessage(chatID, "Only chat admins can change the typing indicator."))
+		return
+	}
+
+	if _, ok := parseToggle(args, false); !ok {
+		gb.send(tgbotapi.NewMessage(chatID, "Usage: /typing [on|off]"))
+		return
+	}
+	// No argument flips the stored value
+	var settings ChatSettings
+	if err := gb.store.UpdateChatSettings(chatID, func(stored *ChatSettings) {
+		enabled, _ := parseToggle(args, !stored.TypingOff)
+		stored.TypingOff = !enabled
+		settings = *stored
+	}); err != nil {
+		log.Printf("Error saving chat settings: %v", err)
+		gb.send(tgbotapi.NewMessage(chatID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	reply := "The typing indicator is on while I check messages in this chat."
+	switch {
+	case !gb.cfg.TypingAction:
+		reply = "The typing indicator is turned off for all chats by the bot operator."
+	case settings.TypingOff:
+		reply = "The typing indicator is off in this chat."
+	}
+	gb.send(tgbotapi.NewMessage(chatID, reply))
+}
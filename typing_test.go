@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+// TestTypingCoalesced starts two concurrent requests in one chat and one in
+// another, and checks that each chat gets one typing loop that stops with
+// its last request.
+func TestTypingCoalesced(t *testing.T) {
+	gb, tg := newTestBot(t, testConfig(t, nil), nil)
+	running := func(chatID int64) bool {
+		gb.typing.mu.Lock()
+		defer gb.typing.mu.Unlock()
+
+		_, ok := gb.typing.chats[chatID]
+		return ok
+	}
+
+	stopFirst := gb.startTyping(7)
+	stopSecond := gb.startTyping(7)
+	stopOther := gb.startTyping(8)
+	waitFor(t, "the typing indicators", func() bool { return len(tg.callsTo("sendChatAction")) >= 2 })
+
+	perChat := map[string]int{}
+	for _, params := range tg.callsTo("sendChatAction") {
+		perChat[params.Get("chat_id")]++
+	}
+	if perChat["7"] != 1 || perChat["8"] != 1 {
+		t.Errorf("typing actions per chat = %v, want one each", perChat)
+	}
+
+	stopFirst()
+	stopFirst()
+	if !running(7) {
+		t.Error("typing stopped while a request was still in flight")
+	}
+	stopSecond()
+	if running(7) {
+		t.Error("typing kept running after the last request finished")
+	}
+	stopOther()
+	if running(8) {
+		t.Error("typing in the other chat kept running")
+	}
+}
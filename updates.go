@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// textQuote is the part of the replied-to message a reply quotes. Position
+// is in UTF-16 code units.
+type textQuote struct {
+	Text     string `json:"text"`
+	Position int    `json:"position"`
+}
+
+// rawUpdate holds the fields of an update the Telegram library drops.
+type rawUpdate struct {
+	BusinessConnection *businessConnection `json:"business_connection"`
+	BusinessMessage    json.RawMessage     `json:"business_message"`
+	Message            *struct {
+		Quote *textQuote `json:"quote"`
+	} `json:"message"`
+}
+
+// messageKey identifies a message across chats.
+type messageKey struct {
+	chatID    int64
+	messageID int
+}
+
+// updateExtras carries what rawUpdate decoded from polling to the workers:
+// business messages by update ID, and quotes by the message quoting.
+type updateExtras struct {
+	mu       sync.Mutex
+	business map[int]businessMessage
+	quotes   map[messageKey]textQuote
+}
+
+func (e *updateExtras) putBusiness(updateID int, message businessMessage) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.business == nil {
+		e.business = make(map[int]businessMessage)
+	}
+	e.business[updateID] = message
+}
+
+// takeBusiness removes and returns the business message of an update, if it
+// has one.
+func (e *updateExtras) takeBusiness(updateID int) (businessMessage, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	message, ok := e.business[updateID]
+	delete(e.business, updateID)
+	return message, ok
+}
+
+func (e *updateExtras) putQuote(key messageKey, quote textQuote) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.quotes == nil {
+		e.quotes = make(map[messageKey]textQuote)
+	}
+	e.quotes[key] = quote
+}
+
+// quote returns what message quotes from the message it replies to.
+func (e *updateExtras) quote(message *tgbotapi.Message) (textQuote, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	quote, ok := e.quotes[messageKey{message.Chat.ID, message.MessageID}]
+	return quote, ok
+}
+
+// forgetQuote drops the quote of message once its update is handled.
+func (e *updateExtras) forgetQuote(message *tgbotapi.Message) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.quotes, messageKey{message.Chat.ID, message.MessageID})
+}
+
+// pendingPollInterval spaces out polls that only return updates already
+// passed on, which Telegram keeps returning until the workers finish them.
+const pendingPollInterval = time.Second
+
+// updatesChan returns the channel updates are received on until ctx is
+// cancelled. Updates are fetched here rather than by the library, so the
+// fields it doesn't know about survive.
+//
+// Each poll asks for updates from the oldest one still queued or being
+// handled, because polling from an offset confirms every update before it
+// to Telegram. An update dropped at shutdown is therefore never confirmed
+// and Telegram delivers it again after a restart. Updates already passed on
+// are left out of the channel.
+func (gb *GrammarBot) updatesChan(ctx context.Context, config tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel {
+	ch := make(chan tgbotapi.Update, gb.bot.Buffer)
+	go func() {
+		defer close(ch)
+		next := config.Offset
+		for ctx.Err() == nil {
+			poll := config
+			poll.Offset = next
+			if unfinished := gb.offsets.committable(); unfinished > 0 && unfinished < next {
+				poll.Offset = unfinished
+			}
+
+			updates, fetched, err := gb.fetchUpdates(poll, next)
+			if err != nil {
+				gb.logError("Error fetching updates: %v", err)
+				// Polling again before a flood wait ends would extend it
+				wait := 3 * time.Second
+				if gb.noteFloodWait(err) {
+					wait = gb.flood.remaining()
+				}
+				select {
+				case <-ctx.Done():
+				case <-time.After(wait):
+				}
+				continue
+			}
+
+			if len(updates) == 0 && fetched > 0 {
+				// Only unfinished updates came back, and would again at once
+				select {
+				case <-ctx.Done():
+				case <-time.After(pendingPollInterval):
+				}
+				continue
+			}
+			for _, update := range updates {
+				next = max(next, update.UpdateID+1)
+				// After a reconnect, updates still in the queue come again
+				if gb.offsets.unfinished(update.UpdateID) {
+					continue
+				}
+				// Tracked from here, so the next poll can't confirm it
+				gb.offsets.start(update.UpdateID)
+				select {
+				case ch <- update:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ch
+}
+
+// fetchUpdates calls getUpdates and returns the updates from offset from on,
+// along with how many it fetched in all. It sets aside business messages and
+// quotes of the returned updates for handleUpdate and remembers business
+// connections.
+func (gb *GrammarBot) fetchUpdates(config tgbotapi.UpdateConfig, from int) ([]tgbotapi.Update, int, error) {
+	params := tgbotapi.Params{}
+	params.AddNonZero("offset", config.Offset)
+	params.AddNonZero("limit", config.Limit)
+	params.AddNonZero("timeout", config.Timeout)
+
+	resp, err := gb.bot.MakeRequest("getUpdates", params)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get updates: %w", err)
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(resp.Result, &raw); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode updates: %w", err)
+	}
+
+	updates := make([]tgbotapi.Update, 0, len(raw))
+	for _, data := range raw {
+		var update tgbotapi.Update
+		var extra rawUpdate
+		if err := json.Unmarshal(data, &update); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode update: %w", err)
+		}
+		if update.UpdateID < from {
+			continue
+		}
+		if err := json.Unmarshal(data, &extra); err != nil {
+			log.Printf("Error decoding update %d: %v", update.UpdateID, err)
+		} else {
+			gb.keepExtras(update, extra)
+		}
+		updates = append(updates, update)
+	}
+	return updates, len(raw), nil
+}
+
+// keepExtras sets aside the parts of update that only extra decoded.
+func (gb *GrammarBot) keepExtras(update tgbotapi.Update, extra rawUpdate) {
+	if update.Message != nil && extra.Message != nil && extra.Message.Quote != nil {
+		gb.extras.putQuote(messageKey{update.Message.Chat.ID, update.Message.MessageID}, *extra.Message.Quote)
+	}
+
+	if !gb.cfg.BusinessMode {
+		return
+	}
+	if extra.BusinessConnection != nil {
+		gb.business.set(extra.BusinessConnection)
+	}
+	if len(extra.BusinessMessage) > 0 {
+		var message tgbotapi.Message
+		var meta struct {
+			BusinessConnectionID string `json:"business_connection_id"`
+		}
+		if err := json.Unmarshal(extra.BusinessMessage, &message); err != nil {
+			log.Printf("Error decoding business message: %v", err)
+		} else if err := json.Unmarshal(extra.BusinessMessage, &meta); err == nil {
+			gb.extras.putBusiness(update.UpdateID, businessMessage{message: &message, connectionID: meta.BusinessConnectionID})
+		}
+	}
+}
+
+// quotedText returns the part of text a reply quotes, located by the quote's
+// offset. If the quote isn't found there, as when the message was edited
+// since, the quote's own text is used, and without a quote all of text.
+func quotedText(text string, quote textQuote, ok bool) string {
+	switch {
+	case !ok || quote.Text == "":
+		return text
+	case quote.Position < 0:
+		return quote.Text
+	}
+
+	start := limitOffset(text, quote.Position)
+	if utf16Len(text[:start]) == quote.Position && strings.HasPrefix(text[start:], quote.Text) {
+		return text[start : start+len(quote.Text)]
+	}
+	return quote.Text
+}
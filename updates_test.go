@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestQuotedText(t *testing.T) {
+	const text = "I has a cat. 😀 She go home. Its fine."
+	tests := []struct {
+		name  string
+		quote textQuote
+		ok    bool
+		want  string
+	}{
+		{"no quote", textQuote{}, false, text},
+		{"empty quote", textQuote{Position: 3}, true, text},
+		{"at the start", textQuote{Text: "I has a cat.", Position: 0}, true, "I has a cat."},
+		// The emoji takes two UTF-16 code units
+		{"after an emoji", textQuote{Text: "She go home.", Position: 16}, true, "She go home."},
+		{"byte offset instead of UTF-16", textQuote{Text: "She go home.", Position: 18}, true, "She go home."},
+		{"edited since", textQuote{Text: "He go home.", Position: 16}, true, "He go home."},
+		{"negative position", textQuote{Text: "Its fine.", Position: -1}, true, "Its fine."},
+		{"position past the end", textQuote{Text: "Its fine.", Position: 500}, true, "Its fine."},
+	}
+	for _, tt := range tests {
+		if got := quotedText(text, tt.quote, tt.ok); got != tt.want {
+			t.Errorf("%s: quotedText() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestFetchUpdatesKeepsQuotes checks that the quote of a reply, which the
+// Telegram library drops, is set aside for handling the update.
+func TestFetchUpdatesKeepsQuotes(t *testing.T) {
+	gb, tg := newTestBot(t, testConfig(t, nil), nil)
+	raw, _ := json.Marshal(map[string]any{
+		"update_id": 1,
+		"message": map[string]any{
+			"message_id": 20,
+			"date":       0,
+			"chat":       map[string]any{"id": 7, "type": "private"},
+			"text":       "/check",
+			"quote":      map[string]any{"text": "She go home.", "position": 16},
+		},
+	})
+	tg.mu.Lock()
+	tg.updates = append(tg.updates, raw)
+	tg.mu.Unlock()
+	tg.addMessage(2, 7, "This one has no quote.")
+
+	updates, _, err := gb.fetchUpdates(tgbotapi.NewUpdate(0), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 2 {
+		t.Fatalf("got %d updates, want 2", len(updates))
+	}
+	if quote, ok := gb.extras.quote(updates[0].Message); !ok || quote != (textQuote{Text: "She go home.", Position: 16}) {
+		t.Errorf("quote = %+v, %v, want the reply's quote", quote, ok)
+	}
+	if _, ok := gb.extras.quote(updates[1].Message); ok {
+		t.Error("a message without a quote got one")
+	}
+	gb.extras.forgetQuote(updates[0].Message)
+	if _, ok := gb.extras.quote(updates[0].Message); ok {
+		t.Error("quote kept after the update was handled")
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// entitledToPro reports whether userID may choose the pro model: listed in
+// PRO_USER_IDS, granted with /grantpro, or an admin.
+func (gb *GrammarBot) entitledToPro(userID int64) bool {
+	return gb.isAdmin(userID) || containsID(gb.cfg.ProUserIDs, userID) || gb.store.IsPro(userID)
+}
+
+// preferredModel returns the model the user chose with /usemodel, or "" to
+// follow the operator's MODEL_SELECTION. A pro choice only applies while the
+// user is entitled to it.
+func (gb *GrammarBot) preferredModel(userID int64, settings UserSettings) string {
+	switch {
+	case settings.Model == modelPro && gb.entitledToPro(userID):
+		return gb.modelName(modelPro)
+	case settings.Model == modelStandard:
+		return gb.modelName(modelStandard)
+	default:
+		return ""
+	}
+}
+
+// handleUseModelCommand shows or sets the user's preferred model tier.
+func (gb *GrammarBot) handleUseModelCommand(message *tgbotapi.Message, args string) {
+	userID := senderID(message)
+	settings := gb.store.GetUserSettings(userID)
+	tier := strings.ToLower(args)
+
+	switch tier {
+	case "":
+		current := settings.Model
+		if current == "" || (current == modelPro && !gb.entitledToPro(userID)) {
+			current = modelStandard
+		}
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("You're using the %s model. Use /usemodel standard or /usemodel pro to change it.", current)))
+		return
+	case modelStandard, modelPro:
+	default:
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /usemodel <standard|pro>"))
+		return
+	}
+
+	if tier == modelPro && !gb.entitledToPro(userID) {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "The pro model is a premium feature that isn't enabled for your account."))
+		return
+	}
+	if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) { stored.Model = tier }); err != nil {
+		log.Printf("Error saving settings: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+	gb.replyWithSettings(message, fmt.Sprintf("Your messages are now checked with the %s model.", tier))
+}
+
+// handleGrantProCommand grants or revokes a user's pro model entitlement.
+func (gb *GrammarBot) handleGrantProCommand(message *tgbotapi.Message, args string, granted bool) {
+	userID, ok := targetUserID(message, args)
+	if !ok {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Usage: /%s <user ID>, or reply to one of their messages.", message.Command())))
+		return
+	}
+
+	if err := gb.store.SetPro(userID, granted); err != nil {
+		log.Printf("Error saving pro entitlements: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save the entitlement. Please try again later."))
+		return
+	}
+
+	reply := fmt.Sprintf("User %d can now use the pro model.", userID)
+	if !granted {
+		reply = fmt.Sprintf("User %d can no longer use the pro model.", userID)
+		if containsID(gb.cfg.ProUserIDs, userID) {
+			reply += " They are still listed in PRO_USER_IDS, which takes precedence."
+		}
+	}
+	log.Printf("Admin %d: %s", message.From.ID, reply)
+	gb.send(tgbotapi.NewMessage(message.Chat.ID, reply))
+}
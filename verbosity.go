@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Verbosity levels of correction replies, chosen with /verbosity.
+const (
+	// verbosityMinimal replies with the corrected text alone.
+	verbosityMinimal = "minimal"
+	// verbosityNormal, the default, marks the corrections in the user's
+	// style.
+	verbosityNormal = "normal"
+	// verbosityDetailed also explains each correction, shows every one of
+	// them, and counts them by kind.
+	verbosityDetailed = "detailed"
+)
+
+// effectiveVerbosity returns the verbosity level of settings.
+func effectiveVerbosity(settings UserSettings) string {
+	if settings.Verbosity == "" {
+		return verbosityNormal
+	}
+	return settings.Verbosity
+}
+
+// correctionCounts describes how many corrections edits make of each kind,
+// as MarkdownV2, or returns "" when they make none.
+func correctionCounts(edits []Edit) string {
+	var replaced, added, removed int
+	for _, e := range edits {
+		switch {
+		case !e.Changed():
+		case e.Original == "":
+			added++
+		case e.Corrected == "":
+			removed++
+		default:
+			replaced++
+		}
+	}
+	total := replaced + added + removed
+	if total == 0 {
+		return ""
+	}
+
+	var kinds []string
+	for _, kind := range []struct {
+		n    int
+		name string
+	}{{replaced, "replaced"}, {added, "added"}, {removed, "removed"}} {
+		if kind.n > 0 {
+			kinds = append(kinds, fmt.Sprintf("%d %s", kind.n, kind.name))
+		}
+	}
+	noun := "corrections"
+	if total == 1 {
+		noun = "correction"
+	}
+	return escapeMarkdownV2(fmt.Sprintf("✏️ %d %s: %s", total, noun, strings.Join(kinds, ", ")))
+}
+
+// handleVerbosityCommand shows or sets how much correction replies say.
+func (gb *GrammarBot) handleVerbosityCommand(message *tgbotapi.Message, args string) {
+	userID := senderID(message)
+	settings := gb.store.GetUserSettings(userID)
+	level := strings.ToLower(args)
+
+	switch level {
+	case "":
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf(`Your verbosity is %s. Use /verbosity <level> to change it:
+
+minimal: just the corrected text
+normal: the corrections marked in your /style
+detailed: every correction marked and explained, with counts`, effectiveVerbosity(settings))))
+		return
+	case verbosityMinimal, verbosityNormal, verbosityDetailed:
+	default:
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Unknown verbosity. Choose minimal, normal or detailed."))
+		return
+	}
+
+	// The default level is stored as none
+	storedVerbosity := level
+	if level == verbosityNormal {
+		storedVerbosity = ""
+	}
+	if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) { stored.Verbosity = storedVerbosity }); err != nil {
+		log.Printf("Error saving settings: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	reply := "Verbosity set to normal. I'll mark the corrections in your message."
+	switch level {
+	case verbosityMinimal:
+		reply = "Verbosity set to minimal. I'll reply with just the corrected text."
+	case verbosityDetailed:
+		reply = "Verbosity set to detailed. I'll mark and explain every correction and count them."
+	}
+	gb.replyWithSettings(message, reply)
+}
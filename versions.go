@@ -0,0 +1,150 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Limits of version comparison: texts of more than maxVersionTokens words
+// and spaces aren't compared, and a text is taken for a new version of an
+// earlier one when at least minVersionSimilarity of their words match.
+const (
+	maxVersionTokens     = 600
+	minVersionSimilarity = 0.5
+)
+
+// versionTokens splits text into runs of whitespace and the words between
+// them, so joining the tokens gives back text.
+func versionTokens(text string) []string {
+	var tokens []string
+	start := 0
+	for i, r := range text {
+		if i > start {
+			prev, _ := utf8.DecodeLastRuneInString(text[:i])
+			if unicode.IsSpace(prev) != unicode.IsSpace(r) {
+				tokens = append(tokens, text[start:i])
+				start = i
+			}
+		}
+	}
+	if start < len(text) {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
+
+// wordDiff returns the word-level edits that turn old into new, and the
+// share of their words the two have in common.
+func wordDiff(old, new string) ([]Edit, float64) {
+	a, b := versionTokens(old), versionTokens(new)
+
+	// lcs[i][j] is the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var edits []Edit
+	add := func(original, corrected string) {
+		if n := len(edits); n > 0 && edits[n-1].Changed() == (original != corrected) {
+			edits[n-1].Original += original
+			edits[n-1].Corrected += corrected
+			return
+		}
+		edits = append(edits, Edit{Original: original, Corrected: corrected})
+	}
+	common, words := 0, 0
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			add(a[i], b[j])
+			if strings.TrimSpace(a[i]) != "" {
+				common += 2
+			}
+			i, j = i+1, j+1
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			add("", b[j])
+			j++
+		default:
+			add(a[i], "")
+			i++
+		}
+	}
+
+	for _, token := range append(a, b...) {
+		if strings.TrimSpace(token) != "" {
+			words++
+		}
+	}
+	if words == 0 {
+		return edits, 1
+	}
+	return edits, float64(common) / float64(words)
+}
+
+// versionDiff returns a MarkdownV2 section showing how text changed from the
+// user's earlier version of it among their last cfg.VersionDiffDepth checks,
+// or "" when text isn't a new version of any of them.
+func (gb *GrammarBot) versionDiff(userID int64, text string) string {
+	if !gb.store.GetUserSettings(userID).VersionDiff || len(versionTokens(text)) > maxVersionTokens {
+		return ""
+	}
+
+	history := gb.store.History(userID)
+	var best []Edit
+	bestSimilarity := minVersionSimilarity
+	for k := len(history) - 1; k >= 0 && k >= len(history)-gb.cfg.VersionDiffDepth; k-- {
+		previous := history[k].Original
+		if previous == text || len(versionTokens(previous)) > maxVersionTokens {
+			continue
+		}
+		if edits, similarity := wordDiff(previous, text); similarity >= bestSimilarity {
+			best, bestSimilarity = edits, similarity
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return "*Your changes since the last version*\n" + renderEdits(best, styleInline)
+}
+
+// handleVersionsCommand toggles showing the changes between versions of a
+// text, or sets it with "/versions on|off".
+func (gb *GrammarBot) handleVersionsCommand(message *tgbotapi.Message, args string) {
+	userID := senderID(message)
+	if _, ok := parseToggle(args, false); !ok {
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /versions [on|off]"))
+		return
+	}
+
+	// No argument flips the stored value
+	var settings UserSettings
+	if err := gb.store.UpdateUserSettings(userID, func(stored *UserSettings) {
+		stored.VersionDiff, _ = parseToggle(args, stored.VersionDiff)
+		settings = stored.clone()
+	}); err != nil {
+		log.Printf("Error saving settings: %v", err)
+		gb.send(tgbotapi.NewMessage(message.Chat.ID, "Sorry, I couldn't save your settings. Please try again later."))
+		return
+	}
+
+	reply := "Version changes are off."
+	if settings.VersionDiff {
+		reply = "Version changes are on. When you send a new version of a text you recently checked, I'll also show what you changed."
+	}
+	gb.replyWithSettings(message, reply)
+}